@@ -20,6 +20,7 @@ limitations under the License.
 package v1
 
 import (
+	dhapi "github.com/open-ness/openshift-operator/common/pkg/drainhelper/api"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -44,12 +45,19 @@ type N3000Fpga struct {
 	// MD5 checksum verified against calculated one from downloaded user image. Optional.
 	// +kubebuilder:validation:Pattern=`^[a-fA-F0-9]{32}$`
 	CheckSum string `json:"checksum,omitempty"`
+	// Bitstream ID expected after the flash. If the programmed FPGA reports a different one,
+	// the last known-good image is restored. Optional.
+	// +kubebuilder:validation:Pattern=`^0x[a-fA-F0-9]+$`
+	BitstreamID string `json:"bitstreamId,omitempty"`
 }
 
 type N3000Fortville struct {
 	// +kubebuilder:validation:Pattern=[a-zA-Z0-9\.\-\/]+
-	FirmwareURL string         `json:"firmwareURL"`
-	MACs        []FortvilleMAC `json:"MACs"`
+	FirmwareURL string `json:"firmwareURL"`
+	// NICs to be updated selected by MAC address
+	MACs []FortvilleMAC `json:"MACs,omitempty"`
+	// NICs to be updated selected by PCI address of the NIC or of the N3000 card
+	Selectors []FortvilleSelector `json:"selectors,omitempty"`
 	// MD5 checksum verified against calculated one from downloaded nvmupdate package. Optional.
 	// +kubebuilder:validation:Pattern=`^[a-fA-F0-9]{32}$`
 	CheckSum string `json:"checksum,omitempty"`
@@ -60,6 +68,16 @@ type FortvilleMAC struct {
 	MAC string `json:"MAC"`
 }
 
+// FortvilleSelector selects NICs by PCI address. If only N3000PCIAddr is set, all NICs on the card are selected.
+type FortvilleSelector struct {
+	// PCI address of the N3000 card (BMC)
+	// +kubebuilder:validation:Pattern=`^[a-fA-F0-9]{4}:[a-fA-F0-9]{2}:[01][a-fA-F0-9]\.[0-7]$`
+	N3000PCIAddr string `json:"N3000PCIAddr,omitempty"`
+	// PCI address of the NIC
+	// +kubebuilder:validation:Pattern=`^[a-fA-F0-9]{4}:[a-fA-F0-9]{2}:[01][a-fA-F0-9]\.[0-7]$`
+	NICPCIAddr string `json:"NICPCIAddr,omitempty"`
+}
+
 type N3000ClusterNode struct {
 	// +kubebuilder:validation:Pattern=[a-z0-9\.\-]+
 	NodeName  string          `json:"nodeName"`
@@ -74,6 +92,10 @@ type N3000ClusterSpec struct {
 	Nodes     []N3000ClusterNode `json:"nodes"`
 	DryRun    bool               `json:"dryrun,omitempty"`
 	DrainSkip bool               `json:"drainSkip,omitempty"`
+	// Applies the declared state again when devices were changed outside of the operator
+	ReapplyOnDrift bool `json:"reapplyOnDrift,omitempty"`
+	// Drain settings, used unless drainSkip is set
+	DrainPolicy *dhapi.DrainPolicySpec `json:"drainPolicy,omitempty"`
 }
 
 // N3000ClusterStatus defines the observed state of N3000Cluster
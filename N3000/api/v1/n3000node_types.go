@@ -20,6 +20,7 @@ limitations under the License.
 package v1
 
 import (
+	dhapi "github.com/open-ness/openshift-operator/common/pkg/drainhelper/api"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -34,6 +35,10 @@ type N3000NodeSpec struct {
 	DryRun    bool            `json:"dryRun,omitempty"`
 	// Allows for updating devices without draining the node
 	DrainSkip bool `json:"drainSkip,omitempty"`
+	// Applies the declared state again when a drift of the devices is detected
+	ReapplyOnDrift bool `json:"reapplyOnDrift,omitempty"`
+	// Drain settings, used unless drainSkip is set
+	DrainPolicy *dhapi.DrainPolicySpec `json:"drainPolicy,omitempty"`
 }
 
 // N3000NodeStatus defines the observed state of N3000Node
@@ -46,6 +51,8 @@ type N3000NodeStatus struct {
 	// Provides information about N3000 Fortville invetory on the node
 	// +operator-sdk:csv:customresourcedefinitions:type=status
 	Fortville []N3000FortvilleStatus `json:"fortville,omitempty"`
+	// Result of the last drain simulation
+	DrainSimulation *dhapi.DrainSimulationStatus `json:"drainSimulation,omitempty"`
 }
 
 type N3000FpgaStatus struct {
@@ -55,6 +62,18 @@ type N3000FpgaStatus struct {
 	BitstreamVersion string `json:"bitstreamVersion,omitempty"`
 	BootPage         string `json:"bootPage,omitempty"`
 	NumaNode         int    `json:"numaNode,omitempty"`
+	// Result of the last flash of the FPGA
+	LastFlash *FlashResult `json:"lastFlash,omitempty"`
+}
+
+// FlashResult is the outcome of the flash of a single card
+type FlashResult struct {
+	// Time the flash finished
+	Time metav1.Time `json:"time"`
+	// Reason of the outcome, the same as of the Flashed condition, e.g. Succeeded, Failed or RolledBack
+	Reason string `json:"reason"`
+	// Error of the failed flash
+	Message string `json:"message,omitempty"`
 }
 
 type N3000FortvilleStatus struct {
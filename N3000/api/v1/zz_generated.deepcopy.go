@@ -1,3 +1,4 @@
+//go:build !ignore_autogenerated
 // +build !ignore_autogenerated
 
 // Code generated by controller-gen. DO NOT EDIT.
@@ -5,10 +6,27 @@
 package v1
 
 import (
+	dhapi "github.com/open-ness/openshift-operator/common/pkg/drainhelper/api"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FlashResult) DeepCopyInto(out *FlashResult) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FlashResult.
+func (in *FlashResult) DeepCopy() *FlashResult {
+	if in == nil {
+		return nil
+	}
+	out := new(FlashResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FortvilleMAC) DeepCopyInto(out *FortvilleMAC) {
 	*out = *in
@@ -24,6 +42,21 @@ func (in *FortvilleMAC) DeepCopy() *FortvilleMAC {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FortvilleSelector) DeepCopyInto(out *FortvilleSelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FortvilleSelector.
+func (in *FortvilleSelector) DeepCopy() *FortvilleSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(FortvilleSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FortvilleStatus) DeepCopyInto(out *FortvilleStatus) {
 	*out = *in
@@ -133,6 +166,11 @@ func (in *N3000ClusterSpec) DeepCopyInto(out *N3000ClusterSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.DrainPolicy != nil {
+		in, out := &in.DrainPolicy, &out.DrainPolicy
+		*out = new(dhapi.DrainPolicySpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new N3000ClusterSpec.
@@ -168,6 +206,11 @@ func (in *N3000Fortville) DeepCopyInto(out *N3000Fortville) {
 		*out = make([]FortvilleMAC, len(*in))
 		copy(*out, *in)
 	}
+	if in.Selectors != nil {
+		in, out := &in.Selectors, &out.Selectors
+		*out = make([]FortvilleSelector, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new N3000Fortville.
@@ -233,6 +276,11 @@ func (in *N3000Fpga) DeepCopy() *N3000Fpga {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *N3000FpgaStatus) DeepCopyInto(out *N3000FpgaStatus) {
 	*out = *in
+	if in.LastFlash != nil {
+		in, out := &in.LastFlash, &out.LastFlash
+		*out = new(FlashResult)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new N3000FpgaStatus.
@@ -317,6 +365,11 @@ func (in *N3000NodeSpec) DeepCopyInto(out *N3000NodeSpec) {
 		*out = new(N3000Fortville)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.DrainPolicy != nil {
+		in, out := &in.DrainPolicy, &out.DrainPolicy
+		*out = new(dhapi.DrainPolicySpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new N3000NodeSpec.
@@ -342,7 +395,9 @@ func (in *N3000NodeStatus) DeepCopyInto(out *N3000NodeStatus) {
 	if in.FPGA != nil {
 		in, out := &in.FPGA, &out.FPGA
 		*out = make([]N3000FpgaStatus, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.Fortville != nil {
 		in, out := &in.Fortville, &out.Fortville
@@ -351,6 +406,11 @@ func (in *N3000NodeStatus) DeepCopyInto(out *N3000NodeStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.DrainSimulation != nil {
+		in, out := &in.DrainSimulation, &out.DrainSimulation
+		*out = new(dhapi.DrainSimulationStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new N3000NodeStatus.
@@ -9,6 +9,7 @@ import (
 
 	fpgav1 "github.com/open-ness/openshift-operator/N3000/api/v1"
 	"github.com/open-ness/openshift-operator/N3000/pkg/daemon"
+	"github.com/open-ness/openshift-operator/common/pkg/drainhelper"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -30,6 +31,8 @@ func init() {
 }
 
 func main() {
+	var metricsAddr string
+	flag.StringVar(&metricsAddr, "metrics-bind-address", ":42223", "The address the metric endpoint binds to.")
 	opts := zap.Options{}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
@@ -48,6 +51,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := drainhelper.RegisterMetrics(); err != nil {
+		setupLog.Error(err, "failed to register drain metrics")
+		os.Exit(1)
+	}
+
 	config := ctrl.GetConfigOrDie()
 
 	cset, err := clientset.NewForConfig(config)
@@ -64,7 +72,7 @@ func main() {
 
 	mgr, err := ctrl.NewManager(config, ctrl.Options{
 		Scheme:             scheme,
-		MetricsBindAddress: "0",
+		MetricsBindAddress: metricsAddr,
 		LeaderElection:     false,
 		Namespace:          namespace,
 	})
@@ -32,15 +32,26 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/open-ness/openshift-operator/common/pkg/events"
+	"github.com/open-ness/openshift-operator/common/pkg/statuswriter"
+
 	fpgav1 "github.com/open-ness/openshift-operator/N3000/api/v1"
 )
 
 const (
 	DEFAULT_N3000_CONFIG_NAME = "n3000"
+
+	// Reasons of the events recorded on the N3000Cluster and the Nodes
+	EventNodeConfigCreated = "NodeConfigCreated"
+	EventNodeConfigUpdated = "NodeConfigUpdated"
+	EventNodeConfigDeleted = "NodeConfigDeleted"
+	EventSyncFailed        = "SyncFailed"
+	EventSyncIgnored       = "SyncIgnored"
 )
 
 var log = ctrl.Log.WithName("N3000ClusterController")
@@ -48,9 +59,11 @@ var namespace = os.Getenv("N3000_NAMESPACE")
 
 func (r *N3000ClusterReconciler) updateStatus(n3000cluster *fpgav1.N3000Cluster,
 	status fpgav1.SyncStatus, reason string) {
-	n3000cluster.Status.SyncStatus = status
-	n3000cluster.Status.LastSyncError = reason
-	if err := r.Status().Update(context.Background(), n3000cluster, &client.UpdateOptions{}); err != nil {
+	err := statuswriter.NewStatusWriter(r.Client).Update(context.Background(), n3000cluster, func(obj client.Object) {
+		obj.(*fpgav1.N3000Cluster).Status.SyncStatus = status
+		obj.(*fpgav1.N3000Cluster).Status.LastSyncError = reason
+	})
+	if err != nil {
 		log.Error(err, "failed to update cluster config's status")
 	}
 }
@@ -58,14 +71,29 @@ func (r *N3000ClusterReconciler) updateStatus(n3000cluster *fpgav1.N3000Cluster,
 // N3000ClusterReconciler reconciles a N3000Cluster object
 type N3000ClusterReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// recordEvent records the event on the N3000Cluster and on the Node, if nodeName is set
+func (r *N3000ClusterReconciler) recordEvent(n3000cluster *fpgav1.N3000Cluster, nodeName string,
+	eventType, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder == nil {
+		return
+	}
+	if nodeName == "" {
+		r.Recorder.Eventf(n3000cluster, eventType, reason, messageFmt, args...)
+		return
+	}
+	events.NewNodeRecorder(r.Recorder, nodeName).Eventf(n3000cluster, eventType, reason, messageFmt, args...)
 }
 
 // +kubebuilder:rbac:groups=fpga.intel.com,resources=n3000clusters,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=fpga.intel.com,resources=n3000clusters/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=fpga.intel.com,resources=n3000nodes,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=nodes,verbs=list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups="",resources=namespaces,verbs=*
 // +kubebuilder:rbac:groups="",resources=services;serviceaccounts,verbs=*
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=serviceaccounts;roles;rolebindings;clusterroles;clusterrolebindings,verbs=*
@@ -92,9 +120,10 @@ func (r *N3000ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		log.V(2).Info("received N3000Cluster, but it not an expected one - it'll be ignored",
 			"expectedNamespace", namespace, "expectedName", DEFAULT_N3000_CONFIG_NAME)
 
-		r.updateStatus(clusterConfig, fpgav1.IgnoredSync, fmt.Sprintf(
-			"Only N3000Cluster with name '%s' and namespace '%s' are handled",
-			DEFAULT_N3000_CONFIG_NAME, namespace))
+		msg := fmt.Sprintf("Only N3000Cluster with name '%s' and namespace '%s' are handled",
+			DEFAULT_N3000_CONFIG_NAME, namespace)
+		r.updateStatus(clusterConfig, fpgav1.IgnoredSync, msg)
+		r.recordEvent(clusterConfig, "", corev1.EventTypeWarning, EventSyncIgnored, "%s", msg)
 
 		return ctrl.Result{}, nil
 	}
@@ -102,18 +131,22 @@ func (r *N3000ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	n3000nodes, err := r.splitClusterIntoNodes(ctx, clusterConfig)
 	if err != nil {
 		log.Error(err, "cluster into nodes split failed")
+		r.recordEvent(clusterConfig, "", corev1.EventTypeWarning, EventSyncFailed, "Unable to list the nodes: %v", err)
 		return ctrl.Result{RequeueAfter: time.Second * 5}, err
 	}
 
-	if err = r.removeOldNodes(n3000nodes); err != nil {
+	if err = r.removeOldNodes(clusterConfig, n3000nodes); err != nil {
 		log.Error(err, "removing old nodes failed")
+		r.recordEvent(clusterConfig, "", corev1.EventTypeWarning, EventSyncFailed, "Removing old nodes failed: %v", err)
 		return ctrl.Result{RequeueAfter: time.Second * 5}, err
 	}
 
 	for _, node := range n3000nodes {
-		err := r.updateOrCreateNodeConfig(node)
+		err := r.updateOrCreateNodeConfig(clusterConfig, node)
 		if err != nil {
 			log.Error(err, "create or update failed")
+			r.recordEvent(clusterConfig, node.Name, corev1.EventTypeWarning, EventSyncFailed,
+				"Failed to create or update N3000Node %s: %v", node.Name, err)
 			return reconcile.Result{}, err
 		}
 	}
@@ -127,7 +160,8 @@ func (r *N3000ClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&fpgav1.N3000Cluster{}).
 		Complete(r)
 }
-func (r *N3000ClusterReconciler) updateOrCreateNodeConfig(nodeCfg *fpgav1.N3000Node) error {
+func (r *N3000ClusterReconciler) updateOrCreateNodeConfig(n3000cluster *fpgav1.N3000Cluster,
+	nodeCfg *fpgav1.N3000Node) error {
 	log := r.Log.WithName("updateOrCreateNodeConfig")
 	log.V(2).Info("syncing node config", "name", nodeCfg.Name)
 
@@ -143,6 +177,8 @@ func (r *N3000ClusterReconciler) updateOrCreateNodeConfig(nodeCfg *fpgav1.N3000N
 				log.Error(err, "failed to create NodeConfig", "name", nodeCfg.Name)
 				return err
 			}
+			r.recordEvent(n3000cluster, nodeCfg.Name, corev1.EventTypeNormal, EventNodeConfigCreated,
+				"N3000Node %s created", nodeCfg.Name)
 		} else {
 			log.Error(err, "previous NodeConfig Get failed", "name", nodeCfg.Name)
 			return err
@@ -151,10 +187,15 @@ func (r *N3000ClusterReconciler) updateOrCreateNodeConfig(nodeCfg *fpgav1.N3000N
 		log.V(4).Info("previous NodeConfig found - updating", "name", nodeCfg.Name)
 
 		prev.Spec = nodeCfg.Spec
+		generation := prev.GetGeneration()
 		if err := r.Update(context.TODO(), prev); err != nil {
 			log.Error(err, "failed to update NodeConfig", "name", nodeCfg.Name)
 			return err
 		}
+		if prev.GetGeneration() != generation {
+			r.recordEvent(n3000cluster, nodeCfg.Name, corev1.EventTypeNormal, EventNodeConfigUpdated,
+				"N3000Node %s updated", nodeCfg.Name)
+		}
 	}
 
 	return nil
@@ -183,6 +224,8 @@ func (r *N3000ClusterReconciler) splitClusterIntoNodes(ctx context.Context,
 				nodeRes.Spec.Fortville = res.Fortville
 				nodeRes.Spec.DryRun = n3000cluster.Spec.DryRun
 				nodeRes.Spec.DrainSkip = n3000cluster.Spec.DrainSkip
+				nodeRes.Spec.ReapplyOnDrift = n3000cluster.Spec.ReapplyOnDrift
+				nodeRes.Spec.DrainPolicy = n3000cluster.Spec.DrainPolicy
 				n3000Nodes = append(n3000Nodes, nodeRes)
 				break
 			}
@@ -192,7 +235,8 @@ func (r *N3000ClusterReconciler) splitClusterIntoNodes(ctx context.Context,
 	return n3000Nodes, nil
 }
 
-func (r *N3000ClusterReconciler) removeOldNodes(newNodeCfgs []*fpgav1.N3000Node) error {
+func (r *N3000ClusterReconciler) removeOldNodes(n3000cluster *fpgav1.N3000Cluster,
+	newNodeCfgs []*fpgav1.N3000Node) error {
 	log := r.Log.WithName("removeOldNodes")
 
 	// existing NodeConfigs which are not part of the new ClusterConfig are removed
@@ -219,6 +263,8 @@ func (r *N3000ClusterReconciler) removeOldNodes(newNodeCfgs []*fpgav1.N3000Node)
 				log.Error(err, "failed to delete existing N3000Node", "name", node.GetName())
 				return err
 			}
+			r.recordEvent(n3000cluster, node.GetName(), corev1.EventTypeNormal, EventNodeConfigDeleted,
+				"N3000Node %s deleted", node.GetName())
 		}
 	}
 
@@ -10,6 +10,7 @@ require (
 	github.com/open-ness/openshift-operator/common v0.0.0-20210331133825-661f430b5d9f
 	github.com/pkg/errors v0.9.1
 	github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring v0.45.0
+	github.com/prometheus/client_golang v1.7.1
 	k8s.io/api v0.20.4
 	k8s.io/apimachinery v0.20.4
 	k8s.io/client-go v0.20.4
@@ -18,3 +19,5 @@ require (
 	k8s.io/kubectl v0.20.4 // indirect
 	sigs.k8s.io/controller-runtime v0.7.0
 )
+
+replace github.com/open-ness/openshift-operator/common => ../common
@@ -13,6 +13,7 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 )
@@ -52,7 +53,10 @@ func downloadImage(path, url, checksum string) error {
 	}
 	defer r.Body.Close()
 
-	_, err = io.Copy(f, r.Body)
+	start := time.Now()
+	n, err := io.Copy(f, r.Body)
+	imageDownloadBytes.Add(float64(n))
+	imageDownloadSeconds.Observe(time.Since(start).Seconds())
 	if err != nil {
 		return err
 	}
@@ -6,11 +6,19 @@ package daemon
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	dh "github.com/open-ness/openshift-operator/common/pkg/drainhelper"
+	"github.com/open-ness/openshift-operator/common/pkg/events"
+	"github.com/open-ness/openshift-operator/common/pkg/statuswriter"
 
 	"github.com/go-logr/logr"
 	fpgav1 "github.com/open-ness/openshift-operator/N3000/api/v1"
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	meta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -37,6 +45,24 @@ const (
 	FlashNotRequested FlashConditionReason = "NotRequested"
 	// FlashSucceeded indicates that the flashing process succeeded
 	FlashSucceeded FlashConditionReason = "Succeeded"
+	// FlashPreconditionFailed indicates that the device is not healthy enough to be flashed
+	FlashPreconditionFailed FlashConditionReason = "PreconditionFailed"
+	// FlashInterrupted indicates that the flashing process was interrupted by a restart of the daemon
+	FlashInterrupted FlashConditionReason = "Interrupted"
+	// FlashWaiting indicates that the flash waits until other nodes finish their maintenance
+	FlashWaiting FlashConditionReason = "WaitingForMaintenanceSlot"
+	// FlashRolledBack indicates that the flashed FPGA image failed the verification and the previous one was restored
+	FlashRolledBack FlashConditionReason = "RolledBack"
+
+	// Reasons of the events recorded on the N3000Node and the Node, in addition to the flash condition reasons
+	EventImagesDownloaded = "ImagesDownloaded"
+	EventFPGAProgrammed   = "FPGAProgrammed"
+	EventNICsUpdated      = "NICsUpdated"
+	EventPowerCycled      = "PowerCycled"
+	EventDriftDetected    = "DriftDetected"
+
+	resyncPeriodEnvVarName = "RESYNC_PERIOD_SECONDS"
+	resyncPeriodDefault    = int64(300)
 )
 
 type N3000NodeReconciler struct {
@@ -49,45 +75,76 @@ type N3000NodeReconciler struct {
 	fpga      FPGAManager
 
 	drainHelper *dh.DrainHelper
+
+	// resyncPeriod is an interval of the inventory refresh, 0 disables it
+	resyncPeriod time.Duration
+
+	recorder *events.NodeRecorder
+
+	journal *flashJournal
+	// interrupted is the flash left unfinished by the previous run of the daemon
+	interrupted *journalState
+}
+
+func getResyncPeriod(log logr.Logger) time.Duration {
+	resyncPeriod := resyncPeriodDefault
+	resyncPeriodStr := os.Getenv(resyncPeriodEnvVarName)
+	if resyncPeriodStr != "" {
+		val, err := strconv.ParseInt(resyncPeriodStr, 10, 64)
+		if err != nil || val < 0 {
+			log.Error(err, "invalid env variable value - using default value",
+				"variable", resyncPeriodEnvVarName, "value", resyncPeriodStr)
+		} else {
+			resyncPeriod = val
+		}
+	}
+	log.V(2).Info("resync settings", "period seconds", resyncPeriod)
+	return time.Duration(resyncPeriod) * time.Second
 }
 
 func NewN3000NodeReconciler(c client.Client, clientSet *clientset.Clientset, log logr.Logger,
 	nodename, namespace string) *N3000NodeReconciler {
 
+	journal := newFlashJournal(journalPath)
+	interrupted, err := journal.load()
+	if err != nil {
+		log.Error(err, "failed to read flash journal")
+	} else if interrupted != nil {
+		log.V(2).Info("interrupted flash found in journal", "journal", interrupted.String())
+	}
+
 	return &N3000NodeReconciler{
 		Client:    c,
 		log:       log,
 		nodeName:  nodename,
 		namespace: namespace,
 		fortville: FortvilleManager{
-			Log: log.WithName("fortvilleManager"),
+			Log:     log.WithName("fortvilleManager"),
+			journal: journal,
 		},
 		fpga: FPGAManager{
-			Log: log.WithName("fpgaManager"),
+			Log:       log.WithName("fpgaManager"),
+			journal:   journal,
+			knownGood: newKnownGoodStore(knownGoodPath),
 		},
-		drainHelper: dh.NewDrainHelper(log, clientSet, nodename, namespace),
+		drainHelper:  dh.NewDrainHelper(log, clientSet, nodename, namespace),
+		resyncPeriod: getResyncPeriod(log),
+		journal:      journal,
+		interrupted:  interrupted,
 	}
 }
 
 func (r *N3000NodeReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.recorder = events.NewNodeRecorder(mgr.GetEventRecorderFor("n3000-daemon"), r.nodeName)
+	if r.drainHelper != nil {
+		r.drainHelper.SetEventRecorder(r.recorder)
+	}
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&fpgav1.N3000Node{}).
 		WithEventFilter(predicate.Funcs{
-			CreateFunc: func(e event.CreateEvent) bool {
-				n3000node, ok := e.Object.(*fpgav1.N3000Node)
-				if !ok {
-					r.log.V(2).Info("Failed to convert e.Object to fpgav1.N3000Node", "e.Object", e.Object)
-					return false
-				}
-				cond := meta.FindStatusCondition(n3000node.Status.Conditions, FlashCondition)
-				if cond != nil && cond.ObservedGeneration == e.Object.GetGeneration() {
-					r.log.V(4).Info("Created object was handled previously, ignoring")
-					return false
-				}
-				return true
-
-			},
+			// Objects handled previously are not flashed again (see Reconcile),
+			// but they still need to be reconciled to start the periodic resync
 			UpdateFunc: func(e event.UpdateEvent) bool {
 				if e.ObjectOld.GetGeneration() == e.ObjectNew.GetGeneration() {
 					r.log.V(4).Info("Update ignored, generation unchanged")
@@ -148,6 +205,14 @@ func (r *N3000NodeReconciler) getNodeStatus(n *fpgav1.N3000Node) (fpgav1.N3000No
 		log.Error(err, "Failed to get FPGA inventory")
 		return fpgav1.N3000NodeStatus{}, err
 	}
+	// results of the last flash are not part of the inventory
+	for i := range fpgaStatus {
+		for _, prev := range n.Status.FPGA {
+			if prev.PciAddr == fpgaStatus[i].PciAddr {
+				fpgaStatus[i].LastFlash = prev.LastFlash
+			}
+		}
+	}
 
 	return fpgav1.N3000NodeStatus{
 		Fortville: fortvilleStatus,
@@ -164,11 +229,21 @@ func (r *N3000NodeReconciler) updateStatus(n *fpgav1.N3000Node, c []metav1.Condi
 		return err
 	}
 
-	for _, condition := range c {
-		meta.SetStatusCondition(&nodeStatus.Conditions, condition)
-	}
-	n.Status = nodeStatus
-	if err := r.Status().Update(context.Background(), n); err != nil {
+	return r.writeStatus(n, nodeStatus, c)
+}
+
+// writeStatus sets the given inventory and conditions. Other existing conditions are preserved.
+func (r *N3000NodeReconciler) writeStatus(n *fpgav1.N3000Node, nodeStatus fpgav1.N3000NodeStatus,
+	c []metav1.Condition) error {
+	log := r.log.WithName("writeStatus")
+
+	err := statuswriter.NewStatusWriter(r.Client).Update(context.Background(), n, func(obj client.Object) {
+		node := obj.(*fpgav1.N3000Node)
+		node.Status.Fortville = nodeStatus.Fortville
+		node.Status.FPGA = nodeStatus.FPGA
+		statuswriter.SetConditions(&node.Status.Conditions, c...)
+	})
+	if err != nil {
 		log.Error(err, "failed to update N3000Node status")
 		return err
 	}
@@ -176,8 +251,28 @@ func (r *N3000NodeReconciler) updateStatus(n *fpgav1.N3000Node, c []metav1.Condi
 	return nil
 }
 
+// updateProgressCondition updates the flash condition without marking the generation as observed,
+// so the spec is handled again if the daemon is restarted before the flash finishes
+func (r *N3000NodeReconciler) updateProgressCondition(n *fpgav1.N3000Node, reason FlashConditionReason,
+	msg string) error {
+	return r.updateStatus(n, []metav1.Condition{
+		statuswriter.ProgressCondition(n.Status.Conditions, FlashCondition, string(reason), msg)})
+}
+
+// updateDrainSimulation stores the result of the drain simulation in the status
+func (r *N3000NodeReconciler) updateDrainSimulation(n *fpgav1.N3000Node, sim *dh.DrainSimulation) {
+	simulation := dh.NewDrainSimulationStatus(sim)
+	err := statuswriter.NewStatusWriter(r.Client).Update(context.Background(), n, func(obj client.Object) {
+		obj.(*fpgav1.N3000Node).Status.DrainSimulation = simulation
+	})
+	if err != nil {
+		r.log.WithName("updateDrainSimulation").Error(err, "failed to update N3000Node drain simulation")
+	}
+}
+
+// updateFlashCondition writes the flash condition together with the given conditions and records the event
 func (r *N3000NodeReconciler) updateFlashCondition(n *fpgav1.N3000Node, status metav1.ConditionStatus,
-	reason FlashConditionReason, msg string) {
+	reason FlashConditionReason, msg string, conditions ...metav1.Condition) {
 	log := r.log.WithName("updateFlashCondition")
 	fc := metav1.Condition{
 		Type:               FlashCondition,
@@ -186,19 +281,88 @@ func (r *N3000NodeReconciler) updateFlashCondition(n *fpgav1.N3000Node, status m
 		Message:            msg,
 		ObservedGeneration: n.GetGeneration(),
 	}
-	if err := r.updateStatus(n, []metav1.Condition{fc}); err != nil {
+	if err := r.updateStatus(n, append([]metav1.Condition{fc}, conditions...)); err != nil {
 		log.Error(err, "failed to update N3000Node flash condition")
 	}
+
+	switch reason {
+	case FlashNotRequested:
+	case FlashSucceeded:
+		r.recorder.Eventf(n, corev1.EventTypeNormal, "Flash"+string(reason), "%s", msg)
+	default:
+		r.recorder.Eventf(n, corev1.EventTypeWarning, "Flash"+string(reason), "%s", msg)
+	}
+}
+
+// resync refreshes the inventory and updates the drift condition.
+// Returns true if the declared state should be applied again.
+func (r *N3000NodeReconciler) resync(n *fpgav1.N3000Node) (bool, error) {
+	log := r.log.WithName("resync")
+
+	nodeStatus, err := r.getNodeStatus(n)
+	if err != nil {
+		log.Error(err, "failed to get N3000Node status")
+		return false, err
+	}
+
+	var conditions []metav1.Condition
+	drift := detectDrift(&n.Status, &nodeStatus)
+	if len(drift) > 0 {
+		log.V(2).Info("drift detected", "changes", drift)
+		r.recorder.Eventf(n, corev1.EventTypeWarning, EventDriftDetected, "%s", strings.Join(drift, "; "))
+		conditions = append(conditions, metav1.Condition{
+			Type:               DriftCondition,
+			Status:             metav1.ConditionTrue,
+			Reason:             string(DriftDetected),
+			Message:            strings.Join(drift, "; "),
+			ObservedGeneration: n.GetGeneration(),
+		})
+	} else if meta.FindStatusCondition(n.Status.Conditions, DriftCondition) == nil {
+		conditions = append(conditions, metav1.Condition{
+			Type:               DriftCondition,
+			Status:             metav1.ConditionFalse,
+			Reason:             string(DriftNotDetected),
+			Message:            "Inventory unchanged",
+			ObservedGeneration: n.GetGeneration(),
+		})
+	}
+
+	if err := r.writeStatus(n, nodeStatus, conditions); err != nil {
+		return false, err
+	}
+
+	// Only a successfully applied state is applied again
+	reapply := len(drift) > 0 && n.Spec.ReapplyOnDrift &&
+		(n.Spec.FPGA != nil || n.Spec.Fortville != nil) &&
+		meta.IsStatusConditionTrue(n.Status.Conditions, FlashCondition)
+	return reapply, nil
+}
+
+// flashFailureReason returns the reason of the flash condition for the given error
+func flashFailureReason(err error) FlashConditionReason {
+	if isPreconditionError(err) {
+		return FlashPreconditionFailed
+	}
+	if isRollbackError(err) {
+		return FlashRolledBack
+	}
+	return FlashFailed
 }
 
 func (r *N3000NodeReconciler) verifySpec(n *fpgav1.N3000Node) error {
+	pcis := map[string]bool{}
 	for _, f := range n.Spec.FPGA {
 		if f.UserImageURL == "" {
 			return errors.New("Missing UserImageURL for PCI: " + f.PCIAddr)
 		}
+		// the same card would be programmed concurrently
+		if pcis[f.PCIAddr] {
+			return errors.New("Duplicate FPGA entry for PCI: " + f.PCIAddr)
+		}
+		pcis[f.PCIAddr] = true
 	}
 
-	if n.Spec.Fortville != nil && len(n.Spec.Fortville.MACs) > 0 {
+	if n.Spec.Fortville != nil && (len(n.Spec.Fortville.MACs) > 0 || len(n.Spec.Fortville.Selectors) > 0) {
 		if n.Spec.Fortville.FirmwareURL == "" {
 			return errors.New("Missing Fortville FirmwareURL")
 		}
@@ -206,6 +370,181 @@ func (r *N3000NodeReconciler) verifySpec(n *fpgav1.N3000Node) error {
 	return nil
 }
 
+// flash programs the FPGAs and updates the Fortville NICs.
+// All the updated cards are power cycled once, at the end.
+func (r *N3000NodeReconciler) flash(n *fpgav1.N3000Node) error {
+	log := r.log.WithName("flash")
+
+	var cards, programmed []string
+	var flashErr error
+	// results of the flashed FPGAs, by PCI address
+	fpgaResults := map[string]error{}
+	defer func() { setFlashResults(n, fpgaResults) }()
+	if n.Spec.FPGA != nil {
+		var failures map[string]error
+		programmed, failures = r.fpga.ProgramFPGAs(n)
+		cards = append(cards, programmed...)
+		for _, f := range n.Spec.FPGA {
+			for _, pci := range programmed {
+				if f.PCIAddr == pci {
+					fpgaResults[pci] = nil
+					r.recorder.Eventf(n, corev1.EventTypeNormal, EventFPGAProgrammed,
+						"FPGA %s programmed with %s", pci, f.UserImageURL)
+				}
+			}
+		}
+		for pci, err := range failures {
+			fpgaResults[pci] = err
+		}
+		if err := joinDeviceErrors(failures); err != nil {
+			log.Error(err, "Unable to flash FPGA")
+			flashErr = err
+		}
+	}
+
+	if flashErr == nil && n.Spec.Fortville != nil {
+		updated, err := r.fortville.flash(n)
+		for _, c := range updated {
+			cards = appendBMC(cards, c)
+		}
+		if len(updated) != 0 {
+			r.recorder.Eventf(n, corev1.EventTypeNormal, EventNICsUpdated, "NICs of N3000 %s updated with %s",
+				strings.Join(updated, ", "), n.Spec.Fortville.FirmwareURL)
+		}
+		if err != nil {
+			log.Error(err, "Unable to flash Fortville")
+			flashErr = err
+		}
+	}
+
+	if len(cards) != 0 {
+		device := strings.Join(cards, ",")
+		r.journal.mark(log, journalStepPowerCycle, device, "", false)
+		if err := powerCycle(cards, n.Spec.DryRun, r.log); err != nil {
+			for _, pci := range programmed {
+				fpgaResults[pci] = err
+			}
+			if flashErr == nil {
+				flashErr = err
+			}
+		} else {
+			r.journal.mark(log, journalStepPowerCycle, device, "", true)
+			r.recorder.Eventf(n, corev1.EventTypeNormal, EventPowerCycled, "N3000 %s power cycled",
+				strings.Join(cards, ", "))
+
+			// new images are only loaded by the power cycle
+			if len(programmed) != 0 && !n.Spec.DryRun {
+				failures := r.fpga.verifyProgrammedFPGAs(n, programmed)
+				for pci, err := range failures {
+					fpgaResults[pci] = err
+				}
+				if err := joinDeviceErrors(failures); err != nil && flashErr == nil {
+					log.Error(err, "FPGA verification failed")
+					flashErr = err
+				}
+			}
+		}
+	}
+	return flashErr
+}
+
+// setFlashResults stores the results of the flashed FPGAs in their status entries, the results are
+// reported until the next flash of the card (see getNodeStatus)
+func setFlashResults(n *fpgav1.N3000Node, results map[string]error) {
+	now := metav1.Now()
+	for pci, err := range results {
+		result := &fpgav1.FlashResult{Time: now, Reason: string(FlashSucceeded)}
+		if err != nil {
+			result.Reason = string(flashFailureReason(err))
+			result.Message = err.Error()
+		}
+
+		found := false
+		for i := range n.Status.FPGA {
+			if n.Status.FPGA[i].PciAddr == pci {
+				n.Status.FPGA[i].LastFlash = result
+				found = true
+			}
+		}
+		if !found {
+			n.Status.FPGA = append(n.Status.FPGA, fpgav1.N3000FpgaStatus{PciAddr: pci, LastFlash: result})
+		}
+	}
+}
+
+// rollBackInterruptedSteps restores the known-good (or factory) images of the FPGAs left partially programmed by
+// the interrupted flash, unless they are programmed again (the flash is resumed or the card is in the new spec).
+// The firmware of the NICs is not rolled back - no copy of the previous NVM is kept.
+// Returns the outcome of each interrupted step.
+func (r *N3000NodeReconciler) rollBackInterruptedSteps(n *fpgav1.N3000Node, interrupted *journalState,
+	resume bool) []string {
+	var msgs []string
+	for _, e := range interrupted.pending() {
+		switch e.Step {
+		case journalStepProgramFPGA:
+			reprogrammed := resume
+			for _, f := range n.Spec.FPGA {
+				reprogrammed = reprogrammed || f.PCIAddr == e.Device
+			}
+			if reprogrammed {
+				continue
+			}
+			known, err := r.fpga.restoreKnownGoodFPGA(e.Device, n.Spec.DryRun)
+			if err != nil {
+				msgs = append(msgs, fmt.Sprintf("FPGA %s rollback to %s failed: %v", e.Device, known, err))
+			} else {
+				msgs = append(msgs, fmt.Sprintf("FPGA %s rolled back to %s", e.Device, known))
+			}
+		case journalStepUpdateNIC:
+			if !resume {
+				msgs = append(msgs, fmt.Sprintf("NIC %s not rolled back: previous NVM not kept", e.Device))
+			}
+		}
+	}
+	return msgs
+}
+
+// recoverInterruptedFlash handles the flash left unfinished by the previous run of the daemon.
+// The flash is resumed if the spec didn't change in the meantime, otherwise it's abandoned in favor of
+// the new spec and the interrupted steps are rolled back. The node is uncordoned if it was drained for the flash.
+// Returns true if the flash should be resumed.
+func (r *N3000NodeReconciler) recoverInterruptedFlash(ctx context.Context, n *fpgav1.N3000Node) bool {
+	log := r.log.WithName("recoverInterruptedFlash")
+	interrupted := r.interrupted
+	r.interrupted = nil
+
+	log.V(2).Info("recovering interrupted flash", "journal", interrupted.String())
+	resume := interrupted.Generation == n.GetGeneration()
+	// the node is still drained while the FPGAs are rolled back
+	rollbacks := r.rollBackInterruptedSteps(n, interrupted, resume)
+
+	if interrupted.Drain && r.drainHelper != nil {
+		if err := r.drainHelper.Uncordon(ctx); err != nil {
+			log.Error(err, "failed to uncordon the node after interrupted flash")
+		}
+	}
+
+	if err := r.journal.finish(); err != nil {
+		log.Error(err, "failed to remove flash journal")
+	}
+
+	msg := "Flash interrupted by daemon restart (" + interrupted.String() + ")"
+	if resume {
+		msg += ", resuming"
+	} else {
+		msg += ", not resumed because the spec changed"
+	}
+	if len(rollbacks) != 0 {
+		msg += "; " + strings.Join(rollbacks, "; ")
+	}
+	// the generation is not marked as observed, so the new spec is still applied
+	if err := r.updateProgressCondition(n, FlashInterrupted, msg); err != nil {
+		log.Error(err, "failed to update N3000Node flash condition")
+	}
+	r.recorder.Eventf(n, corev1.EventTypeWarning, "Flash"+string(FlashInterrupted), "%s", msg)
+	return resume
+}
+
 func (r *N3000NodeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.log.WithName("Reconcile").WithValues("namespace", req.Namespace, "name", req.Name)
 
@@ -232,81 +571,150 @@ func (r *N3000NodeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, err
 	}
 
+	result := ctrl.Result{RequeueAfter: r.resyncPeriod}
+
+	resume := false
+	if r.interrupted != nil {
+		resume = r.recoverInterruptedFlash(ctx, n3000node)
+	}
+
+	reapply := false
+	flashCondition := meta.FindStatusCondition(n3000node.Status.Conditions, FlashCondition)
+	if !resume && flashCondition != nil && flashCondition.ObservedGeneration == n3000node.GetGeneration() {
+		// Spec was handled previously - only the inventory is refreshed
+		var err error
+		reapply, err = r.resync(n3000node)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !reapply {
+			log.V(4).Info("Resynced")
+			return result, nil
+		}
+		log.V(2).Info("Reapplying declared state")
+	}
+
 	err := r.verifySpec(n3000node)
 	if err != nil {
 		log.Error(err, "verifySpec error")
 		r.updateFlashCondition(n3000node, metav1.ConditionFalse, FlashFailed, err.Error())
-		return ctrl.Result{}, nil
+		return result, nil
+	}
+
+	drainPolicy, err := dh.NewDrainPolicy(n3000node.Spec.DrainPolicy)
+	if err != nil {
+		log.Error(err, "drain policy error")
+		r.updateFlashCondition(n3000node, metav1.ConditionFalse, FlashFailed, err.Error())
+		return result, nil
 	}
 
 	if n3000node.Spec.FPGA == nil && n3000node.Spec.Fortville == nil {
 		log.V(4).Info("Nothing to do")
 		r.updateFlashCondition(n3000node, metav1.ConditionFalse, FlashNotRequested, "Inventory up to date")
-		return ctrl.Result{}, nil
+		return result, nil
 	}
 
 	// Update current condition to reflect that the flash started
-	currentCondition := meta.FindStatusCondition(n3000node.Status.Conditions, FlashCondition)
-	if currentCondition != nil {
-		currentCondition.Status = metav1.ConditionFalse
-		currentCondition.Reason = string(FlashInProgress)
-		currentCondition.Message = "Flash started"
-		if err := r.updateStatus(n3000node, []metav1.Condition{*currentCondition}); err != nil {
-			log.Error(err, "failed to update current N3000Node flash condition")
-			return ctrl.Result{}, err
-		}
+	startedMsg := "Flash started"
+	if resume {
+		startedMsg = "Flash resumed after daemon restart"
+	}
+	if err := r.updateProgressCondition(n3000node, FlashInProgress, startedMsg); err != nil {
+		log.Error(err, "failed to update current N3000Node flash condition")
+		return ctrl.Result{}, err
 	}
 
 	if n3000node.Spec.FPGA != nil {
 		err := r.fpga.verifyPreconditions(n3000node)
 		if err != nil {
-			r.updateFlashCondition(n3000node, metav1.ConditionFalse, FlashFailed, err.Error())
-			return ctrl.Result{}, nil
+			r.updateFlashCondition(n3000node, metav1.ConditionFalse, flashFailureReason(err), err.Error())
+			return result, nil
 		}
 	}
 
 	if n3000node.Spec.Fortville != nil {
 		err = r.fortville.verifyPreconditions(n3000node)
 		if err != nil {
-			r.updateFlashCondition(n3000node, metav1.ConditionFalse, FlashFailed, err.Error())
-			return ctrl.Result{}, nil
+			r.updateFlashCondition(n3000node, metav1.ConditionFalse, flashFailureReason(err), err.Error())
+			return result, nil
 		}
 	}
+	r.recorder.Eventf(n3000node, corev1.EventTypeNormal, EventImagesDownloaded, "Images downloaded and verified")
 
-	var flashErr error
-	err = r.drainHelper.Run(func(c context.Context) bool {
-		if n3000node.Spec.FPGA != nil {
-			err := r.fpga.ProgramFPGAs(n3000node)
-			if err != nil {
-				log.Error(err, "Unable to flash FPGA")
-				flashErr = err
-				return true
-			}
+	if err := r.journal.begin(n3000node.GetGeneration(), !n3000node.Spec.DrainSkip); err != nil {
+		log.Error(err, "failed to write flash journal")
+		r.updateFlashCondition(n3000node, metav1.ConditionFalse, FlashFailed, err.Error())
+		return result, nil
+	}
+
+	// waiting for the maintenance slot is abandoned if the spec is changed in the meantime
+	runCtx, cancelRun := context.WithCancel(context.Background())
+	defer cancelRun()
+	stopWatching := r.drainHelper.WatchWaiting(cancelRun, func(holders []string) {
+		msg := "Waiting for maintenance slot, held by: " + strings.Join(holders, ", ")
+		if err := r.updateProgressCondition(n3000node, FlashWaiting, msg); err != nil {
+			log.Error(err, "failed to update N3000Node flash condition")
 		}
+		r.recorder.Eventf(n3000node, corev1.EventTypeNormal, "Flash"+string(FlashWaiting), "%s", msg)
+	}, func() bool {
+		return statuswriter.GenerationChanged(context.Background(), r.Client, n3000node)
+	})
+
+	// result of the dry-run drain is reported before the node is cordoned
+	if drainPolicy == nil {
+		drainPolicy = &dh.DrainPolicy{}
+	}
+	drainPolicy.OnSimulation = func(sim *dh.DrainSimulation) {
+		// the simulation is done once the slot is acquired, the watcher writing the same object is stopped first
+		stopWatching()
+		r.updateDrainSimulation(n3000node, sim)
+	}
 
-		if n3000node.Spec.Fortville != nil {
-			err = r.fortville.flash(n3000node)
-			if err != nil {
-				log.Error(err, "Unable to flash Fortville")
-				flashErr = err
-				return true
+	var flashErr error
+	err = r.drainHelper.Run(runCtx, func(c context.Context) bool {
+		if stopWatching() {
+			if err := r.updateProgressCondition(n3000node, FlashInProgress, startedMsg); err != nil {
+				log.Error(err, "failed to update N3000Node flash condition")
 			}
 		}
+		flashErr = r.flash(n3000node)
 		return true
-	}, !n3000node.Spec.DrainSkip)
+	}, !n3000node.Spec.DrainSkip, drainPolicy)
+	stopWatching()
+
+	if err := r.journal.finish(); err != nil {
+		log.Error(err, "failed to remove flash journal")
+	}
+
+	if errors.Is(err, dh.ErrWaitCancelled) {
+		log.V(2).Info("N3000Node changed while waiting for maintenance slot - requeueing")
+		return ctrl.Result{Requeue: true}, nil
+	}
 
 	if err != nil {
 		// some kind of error around leader election / node (un)cordon / node drain
 		r.updateFlashCondition(n3000node, metav1.ConditionUnknown, FlashUnknown, err.Error())
-		return ctrl.Result{}, nil
+		return result, nil
 	}
 
 	if flashErr != nil {
-		r.updateFlashCondition(n3000node, metav1.ConditionFalse, FlashFailed, flashErr.Error())
+		r.updateFlashCondition(n3000node, metav1.ConditionFalse, flashFailureReason(flashErr), flashErr.Error())
 	} else {
-		r.updateFlashCondition(n3000node, metav1.ConditionTrue, FlashSucceeded, "Flashed successfully")
+		driftCondition := metav1.Condition{
+			Type:               DriftCondition,
+			Status:             metav1.ConditionFalse,
+			Reason:             string(DriftNotDetected),
+			Message:            "Declared state applied",
+			ObservedGeneration: n3000node.GetGeneration(),
+		}
+		if reapply {
+			driftCondition.Reason = string(DriftReapplied)
+			driftCondition.Message = "Declared state applied again after drift"
+		}
+		r.updateFlashCondition(n3000node, metav1.ConditionTrue, FlashSucceeded, "Flashed successfully",
+			driftCondition)
 	}
 
 	log.V(2).Info("Reconciled")
-	return ctrl.Result{}, nil
+	return result, nil
 }
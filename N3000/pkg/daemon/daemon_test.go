@@ -10,6 +10,8 @@ import (
 	"os/exec"
 
 	dh "github.com/open-ness/openshift-operator/common/pkg/drainhelper"
+	"github.com/open-ness/openshift-operator/common/pkg/events"
+	"github.com/open-ness/openshift-operator/common/pkg/statuswriter"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -17,10 +19,12 @@ import (
 	"github.com/go-logr/logr"
 
 	fpgav1 "github.com/open-ness/openshift-operator/N3000/api/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	clientset "k8s.io/client-go/kubernetes"
 	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2/klogr"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
@@ -30,6 +34,7 @@ func setupManagers() {
 	nvmupdateExec = fakeNvmupdate
 	fpgaInfoExec = fakeFpgaInfo
 	fpgadiagExec = fakeFpgadiag
+	ethtoolExec = fakeEthtoolBusInfo
 	tarExec = fakeTar
 
 	fpgasUpdateExec = fakeFpgasUpdate
@@ -266,6 +271,33 @@ var _ = Describe("N3000 Daemon Tests", func() {
 			reconciler.updateFlashCondition(n3000node, metav1.ConditionTrue, FlashFailed, "OK")
 		})
 
+		var _ = It("will write the drift condition with the flash condition of outdated node", func() {
+			log = klogr.New().WithName("N3000NodeReconciler-Test")
+			reconciler = N3000NodeReconciler{Client: k8sClient, log: log, namespace: namespace, nodeName: "dummy",
+				fortville: FortvilleManager{Log: log.WithName("fortvilleManager")},
+				fpga:      FPGAManager{Log: log.WithName("fpgaManager")},
+			}
+			Expect(reconciler.CreateEmptyN3000NodeIfNeeded(k8sClient)).To(Succeed())
+
+			outdated := &fpgav1.N3000Node{}
+			Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: "dummy"},
+				outdated)).To(Succeed())
+			latest := outdated.DeepCopy()
+			latest.Status.FPGA = []fpgav1.N3000FpgaStatus{{PciAddr: "0000:1b:00.0"}}
+			Expect(k8sClient.Status().Update(context.TODO(), latest)).To(Succeed())
+
+			reconciler.updateFlashCondition(outdated, metav1.ConditionTrue, FlashSucceeded, "Flashed successfully",
+				metav1.Condition{Type: DriftCondition, Status: metav1.ConditionFalse, Reason: string(DriftReapplied),
+					Message: "Declared state applied again after drift"})
+
+			Expect(k8sClient.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: "dummy"},
+				latest)).To(Succeed())
+			Expect(meta.FindStatusCondition(latest.Status.Conditions, FlashCondition).Reason).
+				To(Equal(string(FlashSucceeded)))
+			Expect(meta.FindStatusCondition(latest.Status.Conditions, DriftCondition).Reason).
+				To(Equal(string(DriftReapplied)))
+		})
+
 		var _ = It("check updateFlash failure ", func() {
 
 			n3000node = &fpgav1.N3000Node{
@@ -315,6 +347,99 @@ var _ = Describe("N3000 Daemon Tests", func() {
 			fpgaInfoExec = fakeFpgaInfo
 		})
 
+		var _ = It("check recoverInterruptedFlash", func() {
+			n3000node = &fpgav1.N3000Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "interrupted",
+					Namespace: namespace,
+				},
+			}
+			err := k8sClient.Create(context.Background(), n3000node)
+			Expect(err).ToNot(HaveOccurred())
+			log = klogr.New().WithName("N3000NodeReconciler-Test")
+
+			reconciler = N3000NodeReconciler{Client: k8sClient, log: log,
+				namespace: namespace,
+				nodeName:  "dummy",
+				journal:   newFlashJournal(journalPath),
+			}
+			Expect(reconciler.journal.begin(n3000node.GetGeneration(), false)).To(Succeed())
+
+			reconciler.interrupted = &journalState{Generation: n3000node.GetGeneration()}
+			Expect(reconciler.recoverInterruptedFlash(context.TODO(), n3000node)).To(BeTrue())
+			Expect(reconciler.interrupted).To(BeNil())
+			flashCondition := meta.FindStatusCondition(n3000node.Status.Conditions, FlashCondition)
+			Expect(flashCondition.Reason).To(Equal(string(FlashInterrupted)))
+			// the spec is applied again
+			Expect(flashCondition.ObservedGeneration).ToNot(Equal(n3000node.GetGeneration()))
+
+			s, err := reconciler.journal.load()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s).To(BeNil())
+
+			reconciler.interrupted = &journalState{Generation: n3000node.GetGeneration() - 1}
+			Expect(reconciler.recoverInterruptedFlash(context.TODO(), n3000node)).To(BeFalse())
+		})
+
+		var _ = It("check waiting for maintenance slot", func() {
+			n3000node = &fpgav1.N3000Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "waiting",
+					Namespace: namespace,
+				},
+			}
+			err := k8sClient.Create(context.Background(), n3000node)
+			Expect(err).ToNot(HaveOccurred())
+			log = klogr.New().WithName("N3000NodeReconciler-Test")
+
+			reconciler = N3000NodeReconciler{Client: k8sClient, log: log,
+				namespace: namespace,
+				nodeName:  "dummy",
+			}
+
+			// the generation is not marked as observed while waiting
+			Expect(reconciler.updateProgressCondition(n3000node, FlashWaiting,
+				"Waiting for maintenance slot, held by: node2")).To(Succeed())
+			c := meta.FindStatusCondition(n3000node.Status.Conditions, FlashCondition)
+			Expect(c.Reason).To(Equal(string(FlashWaiting)))
+			Expect(c.ObservedGeneration).To(BeZero())
+			Expect(statuswriter.GenerationChanged(context.Background(), k8sClient, n3000node)).To(BeFalse())
+
+			updated := n3000node.DeepCopy()
+			updated.Spec.DrainSkip = true
+			Expect(k8sClient.Update(context.Background(), updated)).To(Succeed())
+			Eventually(func() bool { return statuswriter.GenerationChanged(context.Background(), k8sClient, n3000node) }).Should(BeTrue())
+		})
+
+		var _ = It("check updateDrainSimulation", func() {
+			n3000node = &fpgav1.N3000Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "simulated",
+					Namespace: namespace,
+				},
+			}
+			err := k8sClient.Create(context.Background(), n3000node)
+			Expect(err).ToNot(HaveOccurred())
+			log = klogr.New().WithName("N3000NodeReconciler-Test")
+
+			reconciler = N3000NodeReconciler{Client: k8sClient, log: log,
+				namespace: namespace,
+				nodeName:  "dummy",
+			}
+			reconciler.updateDrainSimulation(n3000node, &dh.DrainSimulation{
+				Pods:         []string{"vran/du-1"},
+				BlockingPDBs: []string{"vran/du-pdb (1 pods)"},
+			})
+
+			// the simulation is kept by the following status updates
+			reconciler.updateFlashCondition(n3000node, metav1.ConditionUnknown, FlashUnknown, "drain blocked")
+			updated := &fpgav1.N3000Node{}
+			Expect(k8sClient.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: "simulated"},
+				updated)).To(Succeed())
+			Expect(updated.Status.DrainSimulation).ToNot(BeNil())
+			Expect(updated.Status.DrainSimulation.BlockingPDBs).To(Equal([]string{"vran/du-pdb (1 pods)"}))
+		})
+
 		var _ = It("check verifySpec", func() {
 			var err error
 
@@ -352,6 +477,22 @@ var _ = Describe("N3000 Daemon Tests", func() {
 			err = reconciler.verifySpec(&noUserimageUrlNode)
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("PCI2"))
+
+			var duplicatePCINode fpgav1.N3000Node
+
+			duplicatePCINode.Spec.FPGA = []fpgav1.N3000Fpga{
+				{
+					PCIAddr:      "PCI1",
+					UserImageURL: "someUrl",
+				},
+				{
+					PCIAddr:      "PCI1",
+					UserImageURL: "otherUrl",
+				},
+			}
+			err = reconciler.verifySpec(&duplicatePCINode)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Duplicate FPGA entry for PCI: PCI1"))
 		})
 
 		var _ = It("will create node config", func() {
@@ -670,3 +811,56 @@ var _ = Describe("N3000 Daemon Tests", func() {
 		})
 	})
 })
+
+var _ = Describe("flash events", func() {
+	var _ = It("will record programmed and power cycled cards", func() {
+		fpgaInfoExec = fakeFpgaInfo
+		fpgasUpdateExec = fakeFpgasUpdate
+		rsuExec = fakeRsu
+		defer cleanFPGA()
+
+		fakeRecorder := record.NewFakeRecorder(10)
+		log := klogr.New()
+		r := N3000NodeReconciler{log: log,
+			fpga:     FPGAManager{Log: log},
+			recorder: events.NewNodeRecorder(fakeRecorder, "node1"),
+		}
+		n := &fpgav1.N3000Node{
+			Spec: fpgav1.N3000NodeSpec{
+				FPGA: []fpgav1.N3000Fpga{
+					{PCIAddr: "0000:1b:00.0", UserImageURL: "http://www.test.com/fpga/image/1.bin"},
+				},
+			},
+		}
+
+		Expect(r.flash(n)).To(Succeed())
+		Expect(fakeRecorder.Events).To(HaveLen(4))
+		Expect(<-fakeRecorder.Events).To(Equal("Normal FPGAProgrammed FPGA 0000:1b:00.0 programmed with " +
+			"http://www.test.com/fpga/image/1.bin"))
+		<-fakeRecorder.Events
+		Expect(<-fakeRecorder.Events).To(Equal("Normal PowerCycled N3000 0000:1b:00.0 power cycled"))
+	})
+})
+
+var _ = Describe("setFlashResults", func() {
+	var _ = It("will store the result of each card in its status", func() {
+		n := &fpgav1.N3000Node{
+			Status: fpgav1.N3000NodeStatus{
+				FPGA: []fpgav1.N3000FpgaStatus{{PciAddr: "0000:1b:00.0"}, {PciAddr: "0000:2b:00.0"}},
+			},
+		}
+		setFlashResults(n, map[string]error{
+			"0000:1b:00.0": nil,
+			"0000:2b:00.0": newPreconditionError("FPGA temperature too high"),
+			"0000:3b:00.0": fmt.Errorf("error"),
+		})
+
+		Expect(n.Status.FPGA).To(HaveLen(3))
+		Expect(n.Status.FPGA[0].LastFlash.Reason).To(Equal(string(FlashSucceeded)))
+		Expect(n.Status.FPGA[0].LastFlash.Message).To(BeEmpty())
+		Expect(n.Status.FPGA[1].LastFlash.Reason).To(Equal(string(FlashPreconditionFailed)))
+		Expect(n.Status.FPGA[1].LastFlash.Message).To(Equal("FPGA temperature too high"))
+		Expect(n.Status.FPGA[2].PciAddr).To(Equal("0000:3b:00.0"))
+		Expect(n.Status.FPGA[2].LastFlash.Reason).To(Equal(string(FlashFailed)))
+	})
+})
@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"sort"
+
+	fpgav1 "github.com/open-ness/openshift-operator/N3000/api/v1"
+)
+
+type DriftConditionReason string
+
+const (
+	// DriftCondition drift condition name
+	DriftCondition string = "Drifted"
+
+	// DriftDetected indicates that the devices were changed outside of the operator
+	DriftDetected DriftConditionReason = "DriftDetected"
+	// DriftNotDetected indicates that the devices match the last observed inventory
+	DriftNotDetected DriftConditionReason = "NotDetected"
+	// DriftReapplied indicates that the declared state was applied again after the drift
+	DriftReapplied DriftConditionReason = "Reapplied"
+)
+
+// detectDrift compares the previously observed inventory with the current one
+// and returns the differences, e.g. a new bitstream or a changed Fortville NVM version.
+// Nothing is reported when there is no previous inventory.
+func detectDrift(previous, current *fpgav1.N3000NodeStatus) []string {
+	if len(previous.FPGA) == 0 && len(previous.Fortville) == 0 {
+		return nil
+	}
+
+	var drift []string
+
+	prevFPGA := map[string]fpgav1.N3000FpgaStatus{}
+	for _, f := range previous.FPGA {
+		prevFPGA[f.PciAddr] = f
+	}
+	for _, f := range current.FPGA {
+		p, ok := prevFPGA[f.PciAddr]
+		if !ok {
+			drift = append(drift, fmt.Sprintf("FPGA %s appeared", f.PciAddr))
+			continue
+		}
+		delete(prevFPGA, f.PciAddr)
+		if p.BitstreamID != f.BitstreamID || p.BitstreamVersion != f.BitstreamVersion {
+			drift = append(drift, fmt.Sprintf("FPGA %s bitstream changed from %s (%s) to %s (%s)",
+				f.PciAddr, p.BitstreamID, p.BitstreamVersion, f.BitstreamID, f.BitstreamVersion))
+		}
+	}
+	for pci := range prevFPGA {
+		drift = append(drift, fmt.Sprintf("FPGA %s disappeared", pci))
+	}
+
+	prevNICs := map[string]fpgav1.FortvilleStatus{}
+	for _, f := range previous.Fortville {
+		for _, nic := range f.NICs {
+			prevNICs[nic.PciAddr] = nic
+		}
+	}
+	for _, f := range current.Fortville {
+		for _, nic := range f.NICs {
+			p, ok := prevNICs[nic.PciAddr]
+			if !ok {
+				drift = append(drift, fmt.Sprintf("NIC %s appeared", nic.PciAddr))
+				continue
+			}
+			delete(prevNICs, nic.PciAddr)
+			if p.Version != nic.Version {
+				drift = append(drift, fmt.Sprintf("NIC %s NVM version changed from %s to %s",
+					nic.PciAddr, p.Version, nic.Version))
+			}
+		}
+	}
+	for pci := range prevNICs {
+		drift = append(drift, fmt.Sprintf("NIC %s disappeared", pci))
+	}
+
+	sort.Strings(drift)
+	return drift
+}
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package daemon
+
+import (
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	fpgav1 "github.com/open-ness/openshift-operator/N3000/api/v1"
+	"k8s.io/klog/v2/klogr"
+)
+
+var _ = Describe("Drift", func() {
+	inventory := func() *fpgav1.N3000NodeStatus {
+		return &fpgav1.N3000NodeStatus{
+			FPGA: []fpgav1.N3000FpgaStatus{
+				{PciAddr: "0000:1b:00.0", BitstreamID: "0x23000410010309", BitstreamVersion: "0.2.3"},
+			},
+			Fortville: []fpgav1.N3000FortvilleStatus{
+				{
+					N3000PCI: "0000:1b:00.0",
+					NICs: []fpgav1.FortvilleStatus{
+						{PciAddr: "0000:1d:00.0", Version: "7.00 0x800052b0 0.0.0"},
+						{PciAddr: "0000:1d:00.1", Version: "7.00 0x800052b0 0.0.0"},
+					},
+				},
+			},
+		}
+	}
+
+	var _ = Describe("detectDrift", func() {
+		var _ = It("will not report drift for unchanged inventory", func() {
+			Expect(detectDrift(inventory(), inventory())).To(BeEmpty())
+		})
+		var _ = It("will not report drift without previous inventory", func() {
+			Expect(detectDrift(&fpgav1.N3000NodeStatus{}, inventory())).To(BeEmpty())
+		})
+		var _ = It("will report changed bitstream and NVM version", func() {
+			current := inventory()
+			current.FPGA[0].BitstreamID = "0x23000410010310"
+			current.Fortville[0].NICs[1].Version = "7.10 0x80006471 1.2527.0"
+
+			Expect(detectDrift(inventory(), current)).To(Equal([]string{
+				"FPGA 0000:1b:00.0 bitstream changed from 0x23000410010309 (0.2.3) to 0x23000410010310 (0.2.3)",
+				"NIC 0000:1d:00.1 NVM version changed from 7.00 0x800052b0 0.0.0 to 7.10 0x80006471 1.2527.0",
+			}))
+		})
+		var _ = It("will report appeared and disappeared devices", func() {
+			current := inventory()
+			current.FPGA[0].PciAddr = "0000:2b:00.0"
+			current.Fortville[0].NICs = current.Fortville[0].NICs[:1]
+
+			Expect(detectDrift(inventory(), current)).To(Equal([]string{
+				"FPGA 0000:1b:00.0 disappeared",
+				"FPGA 0000:2b:00.0 appeared",
+				"NIC 0000:1d:00.1 disappeared",
+			}))
+		})
+	})
+
+	var _ = Describe("getResyncPeriod", func() {
+		AfterEach(func() {
+			Expect(os.Unsetenv(resyncPeriodEnvVarName)).To(Succeed())
+		})
+
+		var _ = It("will return value from env", func() {
+			Expect(os.Setenv(resyncPeriodEnvVarName, "60")).To(Succeed())
+			Expect(getResyncPeriod(klogr.New())).To(Equal(time.Minute))
+		})
+		var _ = It("will return default value for invalid env", func() {
+			Expect(os.Setenv(resyncPeriodEnvVarName, "-1")).To(Succeed())
+			Expect(getResyncPeriod(klogr.New())).To(Equal(time.Duration(resyncPeriodDefault) * time.Second))
+		})
+	})
+})
@@ -12,6 +12,7 @@ import (
 	"regexp"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/go-logr/logr"
 	fpgav1 "github.com/open-ness/openshift-operator/N3000/api/v1"
@@ -46,24 +47,19 @@ var (
 type FortvilleManager struct {
 	Log           logr.Logger
 	nvmupdatePath string
+	journal       *flashJournal
 }
 
 func (fm *FortvilleManager) getN3000Devices() ([]string, error) {
 	log := fm.Log.WithName("getN3000Device")
-	fpgaInfoBMCOutput, err := fpgaInfoExec(exec.Command(fpgaInfoPath, "bmc"), log, false)
+	devices, err := newFPGAInfoReader(log).GetDevices()
 	if err != nil {
 		return nil, err
 	}
 
 	var devs []string
-	for _, deviceBMCOutput := range strings.Split(fpgaInfoBMCOutput, "//****** BMC SENSORS ******//") {
-		for _, line := range strings.Split(deviceBMCOutput, "\n") {
-			matches := bmcRegex.FindStringSubmatch(line)
-			if len(matches) == 3 && matches[1] == "PCIe s:b:d.f" {
-				devs = append(devs, matches[2])
-				break
-			}
-		}
+	for _, d := range devices {
+		devs = append(devs, d.PCIAddr)
 	}
 	return devs, nil
 }
@@ -229,8 +225,10 @@ func (fm *FortvilleManager) getNVMUpdate(n *fpgav1.N3000Node) error {
 	return verifyImagePaths()
 }
 
-func (fm *FortvilleManager) flashMac(mac string, dryRun bool) error {
+func (fm *FortvilleManager) flashMac(mac string, dryRun bool) (err error) {
 	log := fm.Log.WithName("flashMac")
+	start := time.Now()
+	defer func() { observeDeviceUpdate(nvmUpdateSeconds, metricsDeviceNIC, mac, start, err) }()
 	step := 0
 	for {
 		rootAttr := &syscall.SysProcAttr{
@@ -317,37 +315,98 @@ func appendBMC(bmcs []string, bmcPCI string) []string {
 	return bmcs
 }
 
-func (fm *FortvilleManager) flash(n *fpgav1.N3000Node) error {
+// flash updates NVM of the Fortville NICs from the spec.
+// Updates are done one by one, as nvmupdate64e does not support parallel runs.
+// Returns PCI addresses of the N3000 cards with updated NICs - they need to be power cycled.
+func (fm *FortvilleManager) flash(n *fpgav1.N3000Node) ([]string, error) {
 	log := fm.Log.WithName("flashMac")
 
 	inv, err := fm.getInventory()
 	if err != nil {
 		log.Error(err, "Unable to get inventory")
-		return err
+		return nil, err
+	}
+
+	nics, err := selectNICs(n.Spec.Fortville, inv)
+	if err != nil {
+		return nil, err
 	}
 
 	var bmcs []string
-	for _, m := range n.Spec.Fortville.MACs {
+	for _, nic := range nics {
+		fm.journal.mark(log, journalStepUpdateNIC, nic.mac, n.Spec.Fortville.FirmwareURL, false)
+		err := fm.flashMac(nic.mac, n.Spec.DryRun)
+		if err != nil {
+			log.Error(err, "Failed to update", "N3000", nic.n3000PCI)
+			return bmcs, err
+		}
+		fm.journal.mark(log, journalStepUpdateNIC, nic.mac, n.Spec.Fortville.FirmwareURL, true)
+		bmcs = appendBMC(bmcs, nic.n3000PCI)
+	}
+
+	return bmcs, nil
+}
+
+type selectedNIC struct {
+	n3000PCI string
+	mac      string
+}
+
+// selectNICs returns the NICs selected by the MACs and selectors from the spec.
+// An error is returned if any of them does not match a NIC.
+func selectNICs(f *fpgav1.N3000Fortville, inv []fpgav1.N3000FortvilleStatus) ([]selectedNIC, error) {
+	var selected []selectedNIC
+	add := func(n3000PCI string, nic fpgav1.FortvilleStatus) {
+		for _, s := range selected {
+			if s.mac == nic.MAC {
+				return
+			}
+		}
+		selected = append(selected, selectedNIC{n3000PCI: n3000PCI, mac: nic.MAC})
+	}
+
+	for _, m := range f.MACs {
+		found := false
 		for _, i := range inv {
 			for _, nic := range i.NICs {
 				if m.MAC == nic.MAC {
-					bmcs = appendBMC(bmcs, i.N3000PCI)
-					err := fm.flashMac(m.MAC, n.Spec.DryRun)
-					if err != nil {
-						log.Error(err, "Failed to update")
-						return err
-					}
-					break
+					found = true
+					add(i.N3000PCI, nic)
 				}
 			}
 		}
+
+		if !found {
+			return nil, errors.New("MAC not found: " + m.MAC)
+		}
 	}
 
-	if len(bmcs) != 0 {
-		err = fm.powerCycle(bmcs, n.Spec.DryRun)
+	for _, s := range f.Selectors {
+		if s.N3000PCIAddr == "" && s.NICPCIAddr == "" {
+			return nil, errors.New("Empty Fortville selector")
+		}
+
+		found := false
+		for _, i := range inv {
+			if s.N3000PCIAddr != "" && s.N3000PCIAddr != i.N3000PCI {
+				continue
+			}
+			for _, nic := range i.NICs {
+				if s.NICPCIAddr != "" && s.NICPCIAddr != nic.PciAddr {
+					continue
+				}
+				found = true
+				add(i.N3000PCI, nic)
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("No NIC found for Fortville selector: N3000PCIAddr: %q, NICPCIAddr: %q",
+				s.N3000PCIAddr, s.NICPCIAddr)
+		}
 	}
 
-	return err
+	return selected, nil
 }
 
 func (fm *FortvilleManager) verifyPreconditions(n *fpgav1.N3000Node) error {
@@ -362,19 +421,18 @@ func (fm *FortvilleManager) verifyPreconditions(n *fpgav1.N3000Node) error {
 		return err
 	}
 
-	for _, m := range n.Spec.Fortville.MACs {
-		found := false
-		for _, i := range inv {
-			for _, nic := range i.NICs {
-				if m.MAC == nic.MAC {
-					found = true
-					break
-				}
-			}
-		}
+	nics, err := selectNICs(n.Spec.Fortville, inv)
+	if err != nil {
+		return err
+	}
 
-		if !found {
-			return errors.New("MAC not found: " + m.MAC)
+	var cards []string
+	for _, nic := range nics {
+		cards = appendBMC(cards, nic.n3000PCI)
+	}
+	for _, card := range cards {
+		if err := checkN3000Health(card, log); err != nil {
+			return err
 		}
 	}
 
@@ -392,16 +450,3 @@ func (fm *FortvilleManager) verifyPreconditions(n *fpgav1.N3000Node) error {
 
 	return nil
 }
-
-func (fm *FortvilleManager) powerCycle(pcis []string, dryRun bool) error {
-	log := fm.Log.WithName("powerCycle")
-	for _, p := range pcis {
-		log.V(2).Info("Power cycling N3000 device", "pci", p)
-		err := rsuExec(exec.Command(rsuPath, "bmcimg", p), log, dryRun)
-		if err != nil {
-			log.Error(err, "Failed to power cycle N3000 device")
-		}
-	}
-
-	return nil
-}
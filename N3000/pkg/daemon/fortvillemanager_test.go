@@ -250,6 +250,10 @@ func fakeFpgaInfoDoubleBMC(cmd *exec.Cmd, log logr.Logger, dryRun bool) (string,
 	return bmcOutputDoublePCI, nil
 }
 
+func fakeEthtoolBusInfo(cmd *exec.Cmd, log logr.Logger, dryRun bool) (string, error) {
+	return ethtoolOutput, nil
+}
+
 func fakeEthtoolInvalidMac(cmd *exec.Cmd, log logr.Logger, dryRun bool) (string, error) {
 	return ethtoolOutput, nil
 }
@@ -329,7 +333,7 @@ var _ = Describe("Fortville Manager", func() {
 			fpgaInfoExec = fakeFpgaInfo
 			fpgadiagExec = fakeFpgadiag
 
-			err := f.flash(&sampleOneFortville)
+			_, err := f.flash(&sampleOneFortville)
 			Expect(err).ToNot(HaveOccurred())
 		})
 		var _ = It("will fail because of invalid MAC", func() {
@@ -340,7 +344,7 @@ var _ = Describe("Fortville Manager", func() {
 			fpgadiagExec = fakeFpgadiag
 			fakeNvmupdateSecondErrReturn = fmt.Errorf("error")
 
-			err := f.flash(&sampleOneFortville)
+			_, err := f.flash(&sampleOneFortville)
 			Expect(err).To(HaveOccurred())
 		})
 		var _ = It("will fail because of invalid outfile", func() {
@@ -353,7 +357,7 @@ var _ = Describe("Fortville Manager", func() {
 			tmpUpdateOutFile := updateOutFile
 			updateOutFile = testTmpFolder + "/invalidOutFile"
 
-			err := f.flash(&sampleOneFortville)
+			_, err := f.flash(&sampleOneFortville)
 			updateOutFile = tmpUpdateOutFile
 			Expect(err).To(HaveOccurred())
 		})
@@ -367,7 +371,7 @@ var _ = Describe("Fortville Manager", func() {
 			tmpUpdateOutFile := updateOutFile
 			updateOutFile = nvmupdateOutputFile_bad
 
-			err := f.flash(&sampleOneFortville)
+			_, err := f.flash(&sampleOneFortville)
 			updateOutFile = tmpUpdateOutFile
 			Expect(err).To(HaveOccurred())
 		})
@@ -381,7 +385,7 @@ var _ = Describe("Fortville Manager", func() {
 			tmpUpdateOutFile := updateOutFile
 			updateOutFile = nvmupdateOutputFile_nonextupdate
 
-			err := f.flash(&sampleOneFortville)
+			_, err := f.flash(&sampleOneFortville)
 			updateOutFile = tmpUpdateOutFile
 			Expect(err).ToNot(HaveOccurred())
 		})
@@ -392,7 +396,7 @@ var _ = Describe("Fortville Manager", func() {
 			fpgadiagExec = fakeFpgadiag
 			fpgaInfoExec = fakeFpgaInfoDoubleBMC
 
-			err := f.flash(&sampleOneFortville)
+			_, err := f.flash(&sampleOneFortville)
 			Expect(err).ToNot(HaveOccurred())
 		})
 		var _ = It("will return error when nvmupdate failed", func() {
@@ -401,7 +405,7 @@ var _ = Describe("Fortville Manager", func() {
 			nvmupdateExec = fakeNvmupdate
 			fpgaInfoExec = fakeFpgaInfo
 			fpgadiagExec = fakeFpgadiag
-			err := f.flash(&sampleOneFortville)
+			_, err := f.flash(&sampleOneFortville)
 			Expect(err).To(HaveOccurred())
 		})
 		var _ = It("will return error when fpgadiag failed", func() {
@@ -410,7 +414,7 @@ var _ = Describe("Fortville Manager", func() {
 			nvmupdateExec = fakeNvmupdate
 			fpgaInfoExec = fakeFpgaInfo
 			fpgadiagExec = fakeFpgadiag
-			err := f.flash(&sampleOneFortville)
+			_, err := f.flash(&sampleOneFortville)
 			Expect(err).To(HaveOccurred())
 		})
 		var _ = It("will call runExc", func() {
@@ -420,7 +424,7 @@ var _ = Describe("Fortville Manager", func() {
 			fpgadiagExec = fakeFpgadiag
 			rsuExec = runExecWithLog
 
-			err := f.flash(&sampleOneFortville)
+			_, err := f.flash(&sampleOneFortville)
 			Expect(err).ToNot(HaveOccurred())
 		})
 		var _ = It("will call runExec with DryRun flag", func() {
@@ -430,7 +434,7 @@ var _ = Describe("Fortville Manager", func() {
 			fpgadiagExec = fakeFpgadiag
 			rsuExec = runExecWithLog
 
-			err := f.flash(&sampleOneFortvilleDryRun)
+			_, err := f.flash(&sampleOneFortvilleDryRun)
 			Expect(err).ToNot(HaveOccurred())
 		})
 	})
@@ -513,6 +517,7 @@ var _ = Describe("Fortville Manager", func() {
 			cleanFortville()
 			fpgaInfoExec = fakeFpgaInfo
 			fpgadiagExec = fakeFpgadiag
+			ethtoolExec = fakeEthtoolBusInfo
 			tarExec = fakeTar
 			srv := serverFortvilleMock()
 			defer srv.Close()
@@ -548,3 +553,54 @@ var _ = Describe("Fortville Manager", func() {
 		})
 	})
 })
+
+var _ = Describe("selectNICs", func() {
+	inv := []fpgav1.N3000FortvilleStatus{
+		{
+			N3000PCI: "0000:1b:00.0",
+			NICs: []fpgav1.FortvilleStatus{
+				{PciAddr: "0000:1d:00.0", MAC: "64:4c:36:11:1b:a8"},
+				{PciAddr: "0000:1d:00.1", MAC: "64:4c:36:11:1b:a9"},
+			},
+		},
+		{
+			N3000PCI: "0000:2b:00.0",
+			NICs: []fpgav1.FortvilleStatus{
+				{PciAddr: "0000:2d:00.0", MAC: "64:4c:36:11:2b:a8"},
+			},
+		},
+	}
+
+	var _ = It("will select NICs by MAC, NIC PCI address and N3000 PCI address without duplicates", func() {
+		nics, err := selectNICs(&fpgav1.N3000Fortville{
+			MACs: []fpgav1.FortvilleMAC{{MAC: "64:4c:36:11:1b:a9"}},
+			Selectors: []fpgav1.FortvilleSelector{
+				{NICPCIAddr: "0000:2d:00.0"},
+				{N3000PCIAddr: "0000:1b:00.0"},
+			},
+		}, inv)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nics).To(Equal([]selectedNIC{
+			{n3000PCI: "0000:1b:00.0", mac: "64:4c:36:11:1b:a9"},
+			{n3000PCI: "0000:2b:00.0", mac: "64:4c:36:11:2b:a8"},
+			{n3000PCI: "0000:1b:00.0", mac: "64:4c:36:11:1b:a8"},
+		}))
+	})
+	var _ = It("will return error when MAC is not found", func() {
+		_, err := selectNICs(&fpgav1.N3000Fortville{
+			MACs: []fpgav1.FortvilleMAC{{MAC: "aa:bb:cc:dd:ee:ff"}},
+		}, inv)
+		Expect(err).To(MatchError("MAC not found: aa:bb:cc:dd:ee:ff"))
+	})
+	var _ = It("will return error when selector matches no NIC", func() {
+		_, err := selectNICs(&fpgav1.N3000Fortville{
+			Selectors: []fpgav1.FortvilleSelector{{N3000PCIAddr: "0000:2b:00.0", NICPCIAddr: "0000:1d:00.0"}},
+		}, inv)
+		Expect(err).To(MatchError(`No NIC found for Fortville selector: N3000PCIAddr: "0000:2b:00.0", NICPCIAddr: "0000:1d:00.0"`))
+
+		_, err = selectNICs(&fpgav1.N3000Fortville{
+			Selectors: []fpgav1.FortvilleSelector{{}},
+		}, inv)
+		Expect(err).To(MatchError("Empty Fortville selector"))
+	})
+})
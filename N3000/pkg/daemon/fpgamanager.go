@@ -8,20 +8,21 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	fpgav1 "github.com/open-ness/openshift-operator/N3000/api/v1"
+	"github.com/open-ness/openshift-operator/common/pkg/fpgainfo"
 	"github.com/pkg/errors"
 )
 
 var (
 	fpgaInfoPath                = "fpgainfo"
 	fpgaInfoExec                = runExec
-	bmcRegex                    = regexp.MustCompile(`^([a-zA-Z .:]+?)(?:\s*:\s)(.+)$`)
-	bmcParametersRegex          = regexp.MustCompile(`^([()0-9]+?) (.+)(?:\s*:\s)(.+) (.+)$`)
+	sysfsRoot                   = "/sys"
 	fpgaUserImageSubfolderPath  = "/n3000-workdir"
 	fpgaUserImageFile           = filepath.Join(fpgaUserImageSubfolderPath, "fpga")
 	fpgasUpdatePath             = "fpgasupdate"
@@ -32,8 +33,19 @@ var (
 	fpgaTemperatureBottomRange  = 40.0 //in Celsius degrees
 	fpgaTemperatureTopRange     = 95.0 //in Celsius degrees
 	envTemperatureLimitName     = "FPGA_DIE_TEMP_LIMIT"
+	fpgaFlashConcurrencyDefault = 2
+	envFlashConcurrencyName     = "FPGA_FLASH_CONCURRENCY"
 )
 
+// getFPGAFlashConcurrency returns the maximum number of FPGAs programmed at the same time
+func getFPGAFlashConcurrency() int {
+	val, err := strconv.Atoi(os.Getenv(envFlashConcurrencyName))
+	if err != nil || val < 1 {
+		return fpgaFlashConcurrencyDefault
+	}
+	return val
+}
+
 func getFPGATemperatureLimit() float64 {
 	val := os.Getenv(envTemperatureLimitName)
 	if val == "" {
@@ -49,100 +61,91 @@ func getFPGATemperatureLimit() float64 {
 	return temperature
 }
 
+// newFPGAInfoReader returns a reader of N3000 devices which falls back to `fpgainfo bmc`
+// when the sysfs does not provide the information
+func newFPGAInfoReader(log logr.Logger) *fpgainfo.Reader {
+	return &fpgainfo.Reader{
+		SysfsRoot: sysfsRoot,
+		FpgaInfoBMC: func() (string, error) {
+			return fpgaInfoExec(exec.Command(fpgaInfoPath, "bmc"), log, false)
+		},
+	}
+}
+
 func getFPGAInventory(log logr.Logger) ([]fpgav1.N3000FpgaStatus, error) {
-	fpgaInfoBMCOutput, err := fpgaInfoExec(exec.Command(fpgaInfoPath, "bmc"), log, false)
+	devs, err := newFPGAInfoReader(log).GetDevices()
 	if err != nil {
 		return nil, err
 	}
 
 	var inventory []fpgav1.N3000FpgaStatus
-	for _, deviceBMCOutput := range strings.Split(fpgaInfoBMCOutput, "//****** BMC SENSORS ******//") {
-		var dev fpgav1.N3000FpgaStatus
-		pciFound := false
-		for _, line := range strings.Split(deviceBMCOutput, "\n") {
-			matches := bmcRegex.FindStringSubmatch(line)
-			if len(matches) == 3 {
-				switch matches[1] {
-				case "PCIe s:b:d.f":
-					dev.PciAddr = matches[2]
-					pciFound = true
-				case "Device Id":
-					dev.DeviceID = matches[2]
-				case "Bitstream Id":
-					dev.BitstreamID = matches[2]
-				case "Bitstream Version":
-					dev.BitstreamVersion = matches[2]
-				case "Numa Node":
-					dev.NumaNode, _ = strconv.Atoi(matches[2])
-				}
-			}
-		}
-		if pciFound {
-			inventory = append(inventory, dev)
-		}
+	for _, d := range devs {
+		inventory = append(inventory, fpgav1.N3000FpgaStatus{
+			PciAddr:          d.PCIAddr,
+			DeviceID:         d.DeviceID,
+			BitstreamID:      d.BitstreamID,
+			BitstreamVersion: d.BitstreamVersion,
+			NumaNode:         d.NumaNode,
+		})
 	}
 	return inventory, nil
 }
 
 func checkFPGADieTemperature(PCIAddr string, log logr.Logger) error {
-	fpgaInfoBMCOutput, err := fpgaInfoExec(exec.Command(fpgaInfoPath, "bmc"), log, false)
+	dev, err := newFPGAInfoReader(log).GetDevice(PCIAddr)
 	if err != nil {
 		return err
 	}
-	pciFound := false
-	fpgaDieTemperature := 0.0
-	for _, deviceBMCOutput := range strings.Split(fpgaInfoBMCOutput, "//****** BMC SENSORS ******//") {
-		for _, line := range strings.Split(deviceBMCOutput, "\n") {
-			matches := bmcRegex.FindStringSubmatch(line)
-			if len(matches) == 3 {
-				switch matches[1] {
-				case "PCIe s:b:d.f":
-					if PCIAddr == matches[2] {
-						pciFound = true
-					}
-				}
-			}
-			matches = bmcParametersRegex.FindStringSubmatch(line)
-			if len(matches) == 5 {
-				switch matches[1] {
-				case "(12)":
-					fpgaDieTemperature, _ = strconv.ParseFloat(matches[3], 64)
-				}
-			}
-		}
-		if pciFound {
-			break
-		}
+	fpgaDieTemperature, ok := dev.Sensor(fpgainfo.FPGADieTemperature)
+	if !ok || !fpgaDieTemperature.Available {
+		return newPreconditionError("Unable to read FPGA temperature on PCIAddr: %s", PCIAddr)
 	}
-	if pciFound {
-		limit := getFPGATemperatureLimit()
-		if fpgaDieTemperature > limit {
-			return fmt.Errorf("FPGA temperature: %f, exceeded limit: %f, on PCIAddr: %s",
-				fpgaDieTemperature, limit, PCIAddr)
-		}
-		return nil
+	limit := getFPGATemperatureLimit()
+	if fpgaDieTemperature.Value > limit {
+		return newPreconditionError("FPGA temperature: %f, exceeded limit: %f, on PCIAddr: %s",
+			fpgaDieTemperature.Value, limit, PCIAddr)
 	}
-	return fmt.Errorf("Not found PCIAddr: %s", PCIAddr)
+	return nil
 }
 
 type FPGAManager struct {
-	Log logr.Logger
+	Log       logr.Logger
+	journal   *flashJournal
+	knownGood *knownGoodStore
 }
 
+// ProgramFPGA programs the user image into the FPGA. New image is loaded after power cycle of the card.
 func (fpga *FPGAManager) ProgramFPGA(file string, PCIAddr string, dryRun bool) error {
 	log := fpga.Log.WithName("ProgramFPGA").WithValues("pci", PCIAddr)
 
 	log.V(4).Info("Starting")
-	err := fpgasUpdateExec(exec.Command(fpgasUpdatePath, file, PCIAddr), fpga.Log, dryRun)
+	start := time.Now()
+	err := fpgasUpdateExec(exec.Command(fpgasUpdatePath, file, PCIAddr), log, dryRun)
+	observeDeviceUpdate(fpgasUpdateSeconds, metricsDeviceFPGA, PCIAddr, start, err)
 	if err != nil {
 		log.Error(err, "Failed to program FPGA")
 		return err
 	}
-	log.V(4).Info("Program FPGA completed, start new power cycle N3000 ...")
-	err = rsuExec(exec.Command(rsuPath, "bmcimg", PCIAddr), fpga.Log, dryRun)
-	if err != nil {
-		log.Error(err, "Failed to execute rsu")
-		return err
+	log.V(4).Info("Program FPGA completed")
+	return nil
+}
+
+// powerCycle reloads the N3000 cards, so the new FPGA user image and Fortville firmware are used
+func powerCycle(pcis []string, dryRun bool, log logr.Logger) error {
+	log = log.WithName("powerCycle")
+
+	var failed []string
+	for _, p := range pcis {
+		log.V(2).Info("Power cycling N3000 device", "pci", p)
+		err := rsuExec(exec.Command(rsuPath, "bmcimg", p), log.WithValues("pci", p), dryRun)
+		if err != nil {
+			log.Error(err, "Failed to power cycle N3000 device", "pci", p)
+			failed = append(failed, p)
+		}
+	}
+
+	if len(failed) != 0 {
+		return fmt.Errorf("Failed to power cycle N3000 devices: %s", strings.Join(failed, ", "))
 	}
 	return nil
 }
@@ -184,6 +187,10 @@ func (fpga *FPGAManager) verifyPreconditions(n *fpgav1.N3000Node) error {
 		if err != nil {
 			return err
 		}
+		err = checkN3000Health(obj.PCIAddr, fpga.Log)
+		if err != nil {
+			return err
+		}
 		indexStr := strconv.Itoa(i)
 		log.V(4).Info("Start downloading", "url", obj.UserImageURL)
 		err = getImage(fpgaUserImageFile+indexStr+".bin",
@@ -199,20 +206,49 @@ func (fpga *FPGAManager) verifyPreconditions(n *fpgav1.N3000Node) error {
 	return nil
 }
 
-func (fpga *FPGAManager) ProgramFPGAs(n *fpgav1.N3000Node) error {
+// ProgramFPGAs programs the FPGAs from the spec, up to FPGA_FLASH_CONCURRENCY cards at the same time.
+// Returns PCI addresses of the programmed cards - they need to be power cycled, also when other cards failed -
+// and the errors of the failed ones, by PCI address.
+func (fpga *FPGAManager) ProgramFPGAs(n *fpgav1.N3000Node) ([]string, map[string]error) {
 	log := fpga.Log.WithName("ProgramFPGAs")
+
+	errs := make([]error, len(n.Spec.FPGA))
+	sem := make(chan struct{}, getFPGAFlashConcurrency())
+	var wg sync.WaitGroup
 	for i, obj := range n.Spec.FPGA {
-		err := checkFPGADieTemperature(obj.PCIAddr, fpga.Log)
-		if err != nil {
-			return err
-		}
-		indexStr := strconv.Itoa(i)
-		log.V(4).Info("Start program", "PCIAddr", obj.PCIAddr)
-		err = fpga.ProgramFPGA(fpgaUserImageFile+indexStr+".bin", obj.PCIAddr, n.Spec.DryRun)
-		if err != nil {
-			log.Error(err, "Failed to program FPGA:", "pci", obj.PCIAddr)
-			return err
+		wg.Add(1)
+		go func(i int, obj fpgav1.N3000Fpga) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			err := checkFPGADieTemperature(obj.PCIAddr, fpga.Log)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			indexStr := strconv.Itoa(i)
+			log.V(4).Info("Start program", "PCIAddr", obj.PCIAddr)
+			fpga.journal.mark(log, journalStepProgramFPGA, obj.PCIAddr, obj.UserImageURL, false)
+			err = fpga.ProgramFPGA(fpgaUserImageFile+indexStr+".bin", obj.PCIAddr, n.Spec.DryRun)
+			if err != nil {
+				log.Error(err, "Failed to program FPGA:", "pci", obj.PCIAddr)
+				errs[i] = err
+				return
+			}
+			fpga.journal.mark(log, journalStepProgramFPGA, obj.PCIAddr, obj.UserImageURL, true)
+		}(i, obj)
+	}
+	wg.Wait()
+
+	var programmed []string
+	failures := map[string]error{}
+	for i, obj := range n.Spec.FPGA {
+		if errs[i] != nil {
+			failures[obj.PCIAddr] = errs[i]
+			continue
 		}
+		programmed = append(programmed, obj.PCIAddr)
 	}
-	return nil
+	return programmed, failures
 }
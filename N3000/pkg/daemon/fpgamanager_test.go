@@ -11,6 +11,8 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	. "github.com/onsi/ginkgo"
@@ -95,6 +97,10 @@ var (
 func mockFPGAEnv() {
 	fpgaUserImageSubfolderPath = testTmpFolder
 	fpgaUserImageFile = filepath.Join(testTmpFolder, "fpga")
+	// no N3000 in the fake sysfs - fpgainfo output is used instead
+	sysfsRoot = testTmpFolder
+	journalPath = filepath.Join(testTmpFolder, "flash-journal.json")
+	knownGoodPath = filepath.Join(testTmpFolder, "known-good")
 }
 
 func fakeFpgaInfo(cmd *exec.Cmd, log logr.Logger, dryRun bool) (string, error) {
@@ -112,7 +118,8 @@ func fakeFpgasUpdate(cmd *exec.Cmd, log logr.Logger, dryRun bool) error {
 }
 
 func fakeRsu(cmd *exec.Cmd, log logr.Logger, dryRun bool) error {
-	if strings.Contains(cmd.String(), "rsu") && strings.Contains(cmd.String(), "bmcimg") {
+	if strings.Contains(cmd.String(), "rsu") && (strings.Contains(cmd.String(), "bmcimg") ||
+		strings.Contains(cmd.String(), "fpga --page=factory")) {
 		return fakeRsuUpdateErrReturn
 	}
 	return fmt.Errorf("Unsupported command: %s", cmd)
@@ -228,6 +235,15 @@ var _ = Describe("FPGA Manager", func() {
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(Equal("Not found PCIAddr: 0000:xx:00.0"))
 		})
+		var _ = It("will return error when FPGA temperature is not available", func() {
+			fpgaInfoExec = func(cmd *exec.Cmd, log logr.Logger, dryRun bool) (string, error) {
+				return strings.Replace(bmcOutput, "98.50 Celsius", "N/A", 1), nil
+			}
+			err := checkFPGADieTemperature("0000:2b:00.0", log)
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Unable to read FPGA temperature on PCIAddr: 0000:2b:00.0"))
+		})
 		var _ = It("will return error when fpgaInfo failed", func() {
 			fakeFpgaInfoErrReturn = fmt.Errorf("error")
 			fpgaInfoExec = fakeFpgaInfo
@@ -262,34 +278,101 @@ var _ = Describe("FPGA Manager", func() {
 		var _ = It("will return nil in successfully scenario", func() {
 			fpgaInfoExec = fakeFpgaInfo
 			fpgasUpdateExec = fakeFpgasUpdate
-			rsuExec = fakeRsu
-			err := f.ProgramFPGAs(&sampleOneFPGA)
+			programmed, failures := f.ProgramFPGAs(&sampleOneFPGA)
+			Expect(failures).To(BeEmpty())
+			Expect(programmed).To(Equal([]string{"0000:1b:00.0"}))
+		})
+		var _ = It("will program cards in parallel up to the limit", func() {
+			err := os.Setenv(envFlashConcurrencyName, "2")
 			Expect(err).ToNot(HaveOccurred())
+			defer os.Unsetenv(envFlashConcurrencyName)
+
+			var lock sync.Mutex
+			running, maxRunning := 0, 0
+			pcis := []string{"0000:1b:00.0", "0000:2b:00.0", "0000:3b:00.0", "0000:4b:00.0"}
+			fpgaInfoExec = func(cmd *exec.Cmd, log logr.Logger, dryRun bool) (string, error) {
+				// all the cards report the sensors of 0000:1b:00.0
+				banner := "//****** BMC SENSORS ******//"
+				card := strings.Split(bmcOutput, banner)[1]
+				out := ""
+				for _, pci := range pcis {
+					out += banner + strings.Replace(card, "0000:1b:00.0", pci, 1)
+				}
+				return out, nil
+			}
+			fpgasUpdateExec = func(cmd *exec.Cmd, log logr.Logger, dryRun bool) error {
+				lock.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				lock.Unlock()
+				time.Sleep(50 * time.Millisecond)
+				lock.Lock()
+				running--
+				lock.Unlock()
+				return nil
+			}
+
+			node := fpgav1.N3000Node{
+				Spec: fpgav1.N3000NodeSpec{
+					FPGA: []fpgav1.N3000Fpga{
+						{PCIAddr: pcis[0]}, {PCIAddr: pcis[1]}, {PCIAddr: pcis[2]}, {PCIAddr: pcis[3]},
+					},
+				},
+			}
+			programmed, failures := f.ProgramFPGAs(&node)
+			Expect(failures).To(BeEmpty())
+			Expect(programmed).To(Equal(pcis))
+			Expect(maxRunning).To(Equal(2))
 		})
 		var _ = It("will return error when fpgasUpdate failed", func() {
 			fpgaInfoExec = fakeFpgaInfo
 			fakeFpgasUpdateErrReturn = fmt.Errorf("error")
 			fpgasUpdateExec = fakeFpgasUpdate
-			rsuExec = fakeRsu
-			err := f.ProgramFPGAs(&sampleOneFPGA)
+			programmed, failures := f.ProgramFPGAs(&sampleOneFPGA)
 			cleanFPGA()
-			Expect(err).To(HaveOccurred())
+			Expect(failures).To(HaveKey("0000:1b:00.0"))
+			Expect(programmed).To(BeEmpty())
 		})
-		var _ = It("will return error when rsuExec failed", func() {
+		var _ = It("will return error when one PCIAddr in CR does not exist", func() {
 			fpgaInfoExec = fakeFpgaInfo
 			fpgasUpdateExec = fakeFpgasUpdate
-			fakeRsuUpdateErrReturn = fmt.Errorf("error")
-			rsuExec = fakeRsu
-			err := f.ProgramFPGAs(&sampleOneFPGA)
-			cleanFPGA()
-			Expect(err).To(HaveOccurred())
+			programmed, failures := f.ProgramFPGAs(&sampleTwoFPGAs)
+			Expect(failures).To(HaveLen(1))
+			Expect(failures).To(HaveKey("0000:1x:00.0"))
+			Expect(programmed).To(Equal([]string{"0000:1b:00.0"}))
 		})
-		var _ = It("will return error when one PCIAddr in CR does not exist", func() {
+		var _ = It("will return precondition error when all cards are too hot", func() {
+			err := os.Setenv(envTemperatureLimitName, "50")
+			Expect(err).ToNot(HaveOccurred())
+			defer cleanFPGA()
+
 			fpgaInfoExec = fakeFpgaInfo
 			fpgasUpdateExec = fakeFpgasUpdate
+			node := fpgav1.N3000Node{
+				Spec: fpgav1.N3000NodeSpec{
+					FPGA: []fpgav1.N3000Fpga{{PCIAddr: "0000:1b:00.0"}, {PCIAddr: "0000:2b:00.0"}},
+				},
+			}
+			programmed, failures := f.ProgramFPGAs(&node)
+			Expect(programmed).To(BeEmpty())
+			err = joinDeviceErrors(failures)
+			Expect(isPreconditionError(err)).To(BeTrue())
+			Expect(err.Error()).To(HavePrefix("0000:1b:00.0: FPGA temperature"))
+		})
+	})
+	var _ = Describe("powerCycle", func() {
+		var _ = It("will return nil in successfully scenario", func() {
 			rsuExec = fakeRsu
-			err := f.ProgramFPGAs(&sampleTwoFPGAs)
-			Expect(err).To(HaveOccurred())
+			Expect(powerCycle([]string{"0000:1b:00.0", "0000:2b:00.0"}, false, log)).To(Succeed())
+		})
+		var _ = It("will return error when rsuExec failed", func() {
+			fakeRsuUpdateErrReturn = fmt.Errorf("error")
+			rsuExec = fakeRsu
+			err := powerCycle([]string{"0000:1b:00.0", "0000:2b:00.0"}, false, log)
+			cleanFPGA()
+			Expect(err).To(MatchError("Failed to power cycle N3000 devices: 0000:1b:00.0, 0000:2b:00.0"))
 		})
 	})
 	var _ = Describe("verifyPreconditions", func() {
@@ -297,6 +380,8 @@ var _ = Describe("FPGA Manager", func() {
 			srv := serverMock()
 			defer srv.Close()
 			fpgaInfoExec = fakeFpgaInfo
+			fpgadiagExec = fakeFpgadiag
+			ethtoolExec = fakeEthtoolBusInfo
 			err := f.verifyPreconditions(&sampleOneFPGA)
 			Expect(err).ToNot(HaveOccurred())
 		})
@@ -333,6 +418,8 @@ var _ = Describe("FPGA Manager", func() {
 			srv := serverMock()
 			defer srv.Close()
 			fpgaInfoExec = fakeFpgaInfo
+			fpgadiagExec = fakeFpgadiag
+			ethtoolExec = fakeEthtoolBusInfo
 
 			tmpPathHolder := fpgaUserImageSubfolderPath
 			fpgaUserImageSubfolderPath = testTmpFolder + "/fakeFPGApath"
@@ -0,0 +1,261 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package daemon
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/open-ness/openshift-operator/common/pkg/fpgainfo"
+)
+
+type sensorRange struct {
+	min float64
+	max float64
+}
+
+var (
+	// bmcSensorLimits are the acceptable ranges of the N3000 BMC voltage (Volts) and current (Amps) readings,
+	// used when the hwmon driver does not report the thresholds of the sensor.
+	// Supply voltages are allowed to deviate by 8% (12V) or 5% (other rails) from the nominal value.
+	// Sensors without a reading (e.g. supply of a missing QSFP) are not checked.
+	bmcSensorLimits = map[string]sensorRange{
+		"12V Backplane Voltage": {11.04, 12.96},
+		"12V AUX Voltage":       {11.04, 12.96},
+		"1.2V Voltage":          {1.14, 1.26},
+		"1.8V Voltage":          {1.71, 1.89},
+		"3.3V Voltage":          {3.135, 3.465},
+		"FPGA Core Voltage":     {0.85, 0.95},
+		"QSFP0 Supply Voltage":  {3.135, 3.465},
+		"QSFP1 Supply Voltage":  {3.135, 3.465},
+		"12V Backplane Current": {0, 6.25},
+		"12V AUX Current":       {0, 6.25},
+		"FPGA Core Current":     {0, 40},
+	}
+
+	// aerCounterFiles are the PCIe Advanced Error Reporting statistics of a PCI device
+	aerCounterFiles          = []string{"aer_dev_correctable", "aer_dev_nonfatal", "aer_dev_fatal"}
+	aerSampleIntervalDefault = 2 * time.Second
+	envAERSampleIntervalName = "AER_SAMPLE_INTERVAL_SECONDS"
+	// aerSampleWait is called between two readings of the AER counters
+	aerSampleWait = func() { time.Sleep(getAERSampleInterval()) }
+)
+
+// getAERSampleInterval returns the time between two readings of the AER counters, 0 disables the AER check
+func getAERSampleInterval() time.Duration {
+	val, err := strconv.Atoi(os.Getenv(envAERSampleIntervalName))
+	if err != nil || val < 0 {
+		return aerSampleIntervalDefault
+	}
+	return time.Duration(val) * time.Second
+}
+
+// preconditionError indicates that the device is not in a state which allows to flash it
+type preconditionError struct {
+	msg string
+}
+
+func (e *preconditionError) Error() string {
+	return e.msg
+}
+
+func newPreconditionError(format string, a ...interface{}) error {
+	return &preconditionError{msg: fmt.Sprintf(format, a...)}
+}
+
+func isPreconditionError(err error) bool {
+	var pe *preconditionError
+	return errors.As(err, &pe)
+}
+
+// joinDeviceErrors combines errors of multiple devices (keyed by PCI address) into one.
+// The result is a precondition (rollback) error only if all the errors are.
+func joinDeviceErrors(errs map[string]error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	var pcis []string
+	allPrecondition := true
+	allRollback := true
+	for pci, err := range errs {
+		pcis = append(pcis, pci)
+		allPrecondition = allPrecondition && isPreconditionError(err)
+		allRollback = allRollback && isRollbackError(err)
+	}
+	if len(pcis) == 1 {
+		return errs[pcis[0]]
+	}
+
+	sort.Strings(pcis)
+	var msgs []string
+	for _, pci := range pcis {
+		msgs = append(msgs, pci+": "+errs[pci].Error())
+	}
+	if allPrecondition {
+		return newPreconditionError("%s", strings.Join(msgs, "; "))
+	}
+	if allRollback {
+		return &rollbackError{msg: strings.Join(msgs, "; ")}
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+// checkN3000Health verifies that the N3000 card is healthy enough to be flashed: BMC voltages
+// and currents are in range, all the interfaces reported by `fpgadiag -m mactest` are present
+// and the PCIe AER error counters of the card and its NICs are not incrementing
+func checkN3000Health(PCIAddr string, log logr.Logger) error {
+	log = log.WithName("checkN3000Health").WithValues("pci", PCIAddr)
+
+	dev, err := newFPGAInfoReader(log).GetDevice(PCIAddr)
+	if err != nil {
+		return err
+	}
+	if err := checkBMCSensors(dev); err != nil {
+		return err
+	}
+
+	fm := FortvilleManager{Log: log}
+	nics, err := fm.getN3000NICs(PCIAddr)
+	if err != nil {
+		return err
+	}
+	if len(nics) == 0 {
+		return newPreconditionError("No interfaces reported by fpgadiag mactest on PCIAddr: %s", PCIAddr)
+	}
+	pcis := []string{PCIAddr}
+	for _, nic := range nics {
+		// interface which is not present on the host is not reported by ethtool
+		if nic.PciAddr == "" {
+			return newPreconditionError("Interface with MAC %s reported by fpgadiag mactest is not present, on PCIAddr: %s",
+				nic.MAC, PCIAddr)
+		}
+		pcis = append(pcis, nic.PciAddr)
+	}
+
+	if getAERSampleInterval() == 0 {
+		log.V(4).Info("AER counters check disabled")
+		return nil
+	}
+	log.V(4).Info("Checking AER counters", "devices", pcis)
+	return checkAERCounters(pcis)
+}
+
+// getSensorLimit returns the acceptable range of the voltage or current sensor: the thresholds reported
+// by hwmon, or the default limits of the sensor if not reported. Returns false if the sensor is not checked.
+func getSensorLimit(s fpgainfo.Sensor) (sensorRange, bool) {
+	if s.Type != fpgainfo.Voltage && s.Type != fpgainfo.Current {
+		return sensorRange{}, false
+	}
+	limit, ok := bmcSensorLimits[s.Name]
+	if !ok {
+		limit = sensorRange{min: math.Inf(-1), max: math.Inf(1)}
+	}
+	if s.Min != nil {
+		limit.min = *s.Min
+	}
+	if s.Max != nil {
+		limit.max = *s.Max
+	}
+	return limit, ok || s.Min != nil || s.Max != nil
+}
+
+func checkBMCSensors(dev *fpgainfo.Device) error {
+	for _, s := range dev.Sensors {
+		limit, ok := getSensorLimit(s)
+		if !ok || !s.Available {
+			continue
+		}
+		if s.Value < limit.min || s.Value > limit.max {
+			return newPreconditionError("BMC sensor %s: %f, out of range [%f, %f], on PCIAddr: %s",
+				s.Name, s.Value, limit.min, limit.max, dev.PCIAddr)
+		}
+	}
+	return nil
+}
+
+// readAERCounters returns the AER counters of the PCI device, e.g. "aer_dev_correctable/RxErr".
+// Devices without AER support have no counters.
+func readAERCounters(PCIAddr string) (map[string]uint64, error) {
+	counters := map[string]uint64{}
+	for _, name := range aerCounterFiles {
+		f, err := os.Open(filepath.Join(sysfsRoot, "bus", "pci", "devices", PCIAddr, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) != 2 {
+				continue
+			}
+			val, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			counters[name+"/"+fields[0]] = val
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return counters, nil
+}
+
+// checkAERCounters reads the AER counters of the devices twice and fails if any of them increased
+func checkAERCounters(pcis []string) error {
+	before := map[string]map[string]uint64{}
+	for _, pci := range pcis {
+		c, err := readAERCounters(pci)
+		if err != nil {
+			return err
+		}
+		if len(c) > 0 {
+			before[pci] = c
+		}
+	}
+	if len(before) == 0 {
+		return nil
+	}
+
+	aerSampleWait()
+
+	for _, pci := range pcis {
+		if _, ok := before[pci]; !ok {
+			continue
+		}
+		after, err := readAERCounters(pci)
+		if err != nil {
+			return err
+		}
+
+		var names []string
+		for name := range after {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if after[name] > before[pci][name] {
+				return newPreconditionError("PCIe AER counter %s incrementing (%d -> %d), on PCIAddr: %s",
+					name, before[pci][name], after[name], pci)
+			}
+		}
+	}
+	return nil
+}
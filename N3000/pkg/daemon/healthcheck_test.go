@@ -0,0 +1,132 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/openshift-operator/common/pkg/fpgainfo"
+	"k8s.io/klog/v2/klogr"
+)
+
+var _ = Describe("checkN3000Health", func() {
+	log := klogr.New()
+	aerDir := func(pci string) string {
+		return filepath.Join(sysfsRoot, "bus", "pci", "devices", pci)
+	}
+	writeAER := func(pci string, correctable string) {
+		Expect(os.MkdirAll(aerDir(pci), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(aerDir(pci), "aer_dev_correctable"),
+			[]byte(correctable), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		cleanFortville()
+		cleanFPGA()
+		fpgaInfoExec = fakeFpgaInfo
+		fpgadiagExec = fakeFpgadiag
+		ethtoolExec = fakeEthtoolBusInfo
+		aerSampleWait = func() {}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filepath.Join(sysfsRoot, "bus"))).To(Succeed())
+		cleanUpHandlers()
+	})
+
+	var _ = It("will return nil for a healthy card", func() {
+		writeAER("0000:1b:00.0", "RxErr 0\nBadTLP 1\nTOTAL_ERR_COR 1\n")
+		Expect(checkN3000Health("0000:1b:00.0", log)).To(Succeed())
+	})
+	var _ = It("will return error when voltage is out of range", func() {
+		fpgaInfoExec = func(cmd *exec.Cmd, log logr.Logger, dryRun bool) (string, error) {
+			return strings.Replace(bmcOutput, "11.64 Volts", "10.50 Volts", 1), nil
+		}
+		err := checkN3000Health("0000:1b:00.0", log)
+		Expect(err).To(HaveOccurred())
+		Expect(isPreconditionError(err)).To(BeTrue())
+		Expect(err.Error()).To(Equal("BMC sensor 12V AUX Voltage: 10.500000, out of range [11.040000, 12.960000]," +
+			" on PCIAddr: 0000:1b:00.0"))
+	})
+	var _ = It("will return error when interface is not present", func() {
+		fakeEthtoolErrReturn = fmt.Errorf("error")
+		ethtoolExec = fakeEthtool
+		err := checkN3000Health("0000:1b:00.0", log)
+		Expect(err).To(HaveOccurred())
+		Expect(isPreconditionError(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("64:4c:36:11:1b:a8"))
+	})
+	var _ = It("will return error when AER counter is incrementing", func() {
+		writeAER("0000:00:03.0", "RxErr 0\nBadTLP 1\nTOTAL_ERR_COR 1\n")
+		aerSampleWait = func() {
+			writeAER("0000:00:03.0", "RxErr 0\nBadTLP 3\nTOTAL_ERR_COR 3\n")
+		}
+		err := checkN3000Health("0000:1b:00.0", log)
+		Expect(err).To(HaveOccurred())
+		Expect(isPreconditionError(err)).To(BeTrue())
+		Expect(err.Error()).To(Equal("PCIe AER counter aer_dev_correctable/BadTLP incrementing (1 -> 3), " +
+			"on PCIAddr: 0000:00:03.0"))
+	})
+	var _ = It("will return error when fpgadiag failed", func() {
+		fakeFpgadiagErrReturn = fmt.Errorf("error")
+		err := checkN3000Health("0000:1b:00.0", log)
+		Expect(err).To(HaveOccurred())
+		Expect(isPreconditionError(err)).To(BeFalse())
+	})
+	var _ = It("will not check AER counters when disabled", func() {
+		os.Setenv(envAERSampleIntervalName, "0")
+		defer os.Unsetenv(envAERSampleIntervalName)
+		aerSampleWait = func() {
+			Fail("AER counters should not be sampled")
+		}
+		writeAER("0000:00:03.0", "RxErr 0\nBadTLP 1\nTOTAL_ERR_COR 1\n")
+		Expect(checkN3000Health("0000:1b:00.0", log)).To(Succeed())
+	})
+})
+
+var _ = Describe("checkBMCSensors", func() {
+	float := func(f float64) *float64 { return &f }
+	device := func(s fpgainfo.Sensor) *fpgainfo.Device {
+		return &fpgainfo.Device{PCIAddr: "0000:1b:00.0", Sensors: []fpgainfo.Sensor{s}}
+	}
+
+	var _ = It("will use the thresholds reported by hwmon", func() {
+		s := fpgainfo.Sensor{Name: "12V AUX Voltage", Type: fpgainfo.Voltage, Value: 12.5, Available: true,
+			Min: float(11.4), Max: float(12.4)}
+		err := checkBMCSensors(device(s))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("BMC sensor 12V AUX Voltage: 12.500000, out of range [11.400000, 12.400000]," +
+			" on PCIAddr: 0000:1b:00.0"))
+	})
+	var _ = It("will use the default limit for the threshold not reported by hwmon", func() {
+		s := fpgainfo.Sensor{Name: "12V AUX Voltage", Type: fpgainfo.Voltage, Value: 10.5, Available: true,
+			Max: float(12.4)}
+		err := checkBMCSensors(device(s))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("out of range [11.040000, 12.400000]"))
+	})
+	var _ = It("will check the sensor without default limits if hwmon reports its thresholds", func() {
+		s := fpgainfo.Sensor{Name: "QSFP0 Current", Type: fpgainfo.Current, Value: 2, Available: true,
+			Max: float(1.5)}
+		Expect(checkBMCSensors(device(s))).ToNot(Succeed())
+
+		s.Max = nil
+		Expect(checkBMCSensors(device(s))).To(Succeed())
+	})
+})
+
+var _ = Describe("flashFailureReason", func() {
+	var _ = It("will return PreconditionFailed for precondition errors", func() {
+		Expect(flashFailureReason(newPreconditionError("error"))).To(Equal(FlashPreconditionFailed))
+		Expect(flashFailureReason(fmt.Errorf("error"))).To(Equal(FlashFailed))
+	})
+})
@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	journalStepProgramFPGA = "ProgramFPGA"
+	journalStepUpdateNIC   = "UpdateNIC"
+	journalStepPowerCycle  = "PowerCycle"
+)
+
+var (
+	// journalPath is a location of the flash journal on the host, it has to survive a restart of the daemon
+	journalPath = "/var/lib/n3000-daemon/flash-journal.json"
+)
+
+// journalEntry is a single step of the flash, e.g. programming of one FPGA
+type journalEntry struct {
+	Step   string    `json:"step"`
+	Device string    `json:"device"`
+	Image  string    `json:"image,omitempty"`
+	Done   bool      `json:"done"`
+	Time   time.Time `json:"time"`
+}
+
+// journalState is the content of the journal file
+type journalState struct {
+	// Generation of the N3000Node which was being flashed
+	Generation int64 `json:"generation"`
+	// Drain is true if the node was cordoned and drained for the flash
+	Drain   bool           `json:"drain"`
+	Entries []journalEntry `json:"entries,omitempty"`
+}
+
+// pending returns the steps which were started, but not completed
+func (s *journalState) pending() []journalEntry {
+	var p []journalEntry
+	for _, e := range s.Entries {
+		if !e.Done {
+			p = append(p, e)
+		}
+	}
+	return p
+}
+
+// String describes the interrupted steps, e.g. "ProgramFPGA of 0000:1b:00.0 (http://host/image.bin)"
+func (s *journalState) String() string {
+	var msgs []string
+	for _, e := range s.pending() {
+		msg := e.Step + " of " + e.Device
+		if e.Image != "" {
+			msg += " (" + e.Image + ")"
+		}
+		msgs = append(msgs, msg)
+	}
+	if len(msgs) == 0 {
+		return fmt.Sprintf("generation %d, no step in progress", s.Generation)
+	}
+	return fmt.Sprintf("generation %d, in progress: %s", s.Generation, strings.Join(msgs, ", "))
+}
+
+// flashJournal persists the progress of the flash, so an interrupted flash can be detected after a restart.
+// All methods of a nil journal are no-ops.
+type flashJournal struct {
+	path  string
+	mu    sync.Mutex
+	state *journalState
+}
+
+func newFlashJournal(path string) *flashJournal {
+	return &flashJournal{path: path}
+}
+
+// load returns the state left by the previous run of the daemon, nil if there is none
+func (j *flashJournal) load() (*journalState, error) {
+	if j == nil {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(j.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	s := &journalState{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("Invalid flash journal %s: %v", j.path, err)
+	}
+	return s, nil
+}
+
+// begin starts a new journal for the flash of the given generation
+func (j *flashJournal) begin(generation int64, drain bool) error {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state = &journalState{Generation: generation, Drain: drain}
+	return j.write()
+}
+
+// record stores the step as started or done
+func (j *flashJournal) record(step, device, image string, done bool) error {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.state == nil {
+		return nil
+	}
+
+	entry := journalEntry{Step: step, Device: device, Image: image, Done: done, Time: time.Now().UTC()}
+	for i, e := range j.state.Entries {
+		if e.Step == step && e.Device == device {
+			j.state.Entries[i] = entry
+			return j.write()
+		}
+	}
+	j.state.Entries = append(j.state.Entries, entry)
+	return j.write()
+}
+
+// mark records the step, failure to update the journal doesn't stop the flash
+func (j *flashJournal) mark(log logr.Logger, step, device, image string, done bool) {
+	if err := j.record(step, device, image, done); err != nil {
+		log.Error(err, "failed to update flash journal", "step", step, "device", device)
+	}
+}
+
+// finish removes the journal, the flash is no longer in progress
+func (j *flashJournal) finish() error {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state = nil
+	if err := os.Remove(j.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// write replaces the journal file atomically, so it's never left partially written
+func (j *flashJournal) write() error {
+	data, err := json.Marshal(j.state)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+		return err
+	}
+	tmp := j.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, j.path)
+}
@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("flashJournal", func() {
+	var path string
+
+	BeforeEach(func() {
+		path = filepath.Join(testTmpFolder, "journal", "flash-journal.json")
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(filepath.Dir(path))).To(Succeed())
+	})
+
+	var _ = It("will return nothing when there is no journal", func() {
+		s, err := newFlashJournal(path).load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s).To(BeNil())
+	})
+	var _ = It("will read back the interrupted steps", func() {
+		j := newFlashJournal(path)
+		Expect(j.begin(3, true)).To(Succeed())
+		Expect(j.record(journalStepProgramFPGA, "0000:1b:00.0", "http://www.test.com/fpga/image.bin", false)).To(Succeed())
+		Expect(j.record(journalStepProgramFPGA, "0000:2b:00.0", "http://www.test.com/fpga/image.bin", false)).To(Succeed())
+		Expect(j.record(journalStepProgramFPGA, "0000:1b:00.0", "http://www.test.com/fpga/image.bin", true)).To(Succeed())
+
+		s, err := newFlashJournal(path).load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Generation).To(Equal(int64(3)))
+		Expect(s.Drain).To(BeTrue())
+		Expect(s.Entries).To(HaveLen(2))
+		Expect(s.String()).To(Equal("generation 3, in progress: ProgramFPGA of 0000:2b:00.0 (http://www.test.com/fpga/image.bin)"))
+	})
+	var _ = It("will remove the journal when the flash finished", func() {
+		j := newFlashJournal(path)
+		Expect(j.begin(1, false)).To(Succeed())
+		Expect(j.finish()).To(Succeed())
+		Expect(j.record(journalStepPowerCycle, "0000:1b:00.0", "", false)).To(Succeed())
+
+		s, err := j.load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s).To(BeNil())
+	})
+	var _ = It("will return error for a corrupted journal", func() {
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(path, []byte("{\"generation\":"), 0644)).To(Succeed())
+		_, err := newFlashJournal(path).load()
+		Expect(err).To(HaveOccurred())
+	})
+	var _ = It("will do nothing for nil journal", func() {
+		var j *flashJournal
+		Expect(j.begin(1, true)).To(Succeed())
+		Expect(j.record(journalStepUpdateNIC, "64:4c:36:11:1b:a8", "", false)).To(Succeed())
+		Expect(j.finish()).To(Succeed())
+	})
+})
@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package daemon
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	metricsResultSuccess = "success"
+	metricsResultFailure = "failure"
+
+	metricsDeviceFPGA = "fpga"
+	metricsDeviceNIC  = "nic"
+)
+
+var (
+	imageDownloadSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "n3000_image_download_duration_seconds",
+		Help:    "Duration of the download of the FPGA user images and the NVM update packages",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+	})
+	imageDownloadBytes = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "n3000_image_download_bytes_total",
+		Help: "Number of the downloaded bytes of the FPGA user images and the NVM update packages",
+	})
+	fpgasUpdateSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "n3000_fpgasupdate_duration_seconds",
+		Help:    "Duration of fpgasupdate programming the FPGA user image",
+		Buckets: []float64{30, 60, 120, 300, 600, 900, 1200, 1800},
+	}, []string{"pci"})
+	nvmUpdateSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "n3000_nvmupdate_duration_seconds",
+		Help:    "Duration of nvmupdate64e updating the Fortville firmware",
+		Buckets: []float64{30, 60, 120, 300, 600, 900, 1200, 1800},
+	}, []string{"mac"})
+	deviceUpdatesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "n3000_device_updates_total",
+		Help: "Number of the FPGA and Fortville updates by device and result",
+	}, []string{"type", "device", "result"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(imageDownloadSeconds, imageDownloadBytes, fpgasUpdateSeconds, nvmUpdateSeconds,
+		deviceUpdatesTotal)
+}
+
+// observeDeviceUpdate records the duration and the result of the update of the device
+func observeDeviceUpdate(h *prometheus.HistogramVec, deviceType, device string, start time.Time, err error) {
+	h.WithLabelValues(device).Observe(time.Since(start).Seconds())
+
+	result := metricsResultSuccess
+	if err != nil {
+		result = metricsResultFailure
+	}
+	deviceUpdatesTotal.WithLabelValues(deviceType, device, result).Inc()
+}
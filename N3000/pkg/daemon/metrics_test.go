@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package daemon
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/klog/v2/klogr"
+)
+
+var _ = Describe("metrics", func() {
+	const pci = "0000:7b:00.0"
+
+	AfterEach(func() {
+		fpgasUpdateExec = runExecWithLog
+		fakeFpgasUpdateErrReturn = nil
+	})
+
+	var _ = It("will count the FPGA updates by result", func() {
+		fpgasUpdateExec = fakeFpgasUpdate
+		fpga := FPGAManager{Log: klogr.New()}
+		succeeded := testutil.ToFloat64(deviceUpdatesTotal.WithLabelValues(metricsDeviceFPGA, pci, metricsResultSuccess))
+		failed := testutil.ToFloat64(deviceUpdatesTotal.WithLabelValues(metricsDeviceFPGA, pci, metricsResultFailure))
+
+		Expect(fpga.ProgramFPGA("/tmp/image.bin", pci, false)).To(Succeed())
+		fakeFpgasUpdateErrReturn = fmt.Errorf("error")
+		Expect(fpga.ProgramFPGA("/tmp/image.bin", pci, false)).ToNot(Succeed())
+
+		Expect(testutil.ToFloat64(deviceUpdatesTotal.WithLabelValues(metricsDeviceFPGA, pci, metricsResultSuccess))).
+			To(Equal(succeeded + 1))
+		Expect(testutil.ToFloat64(deviceUpdatesTotal.WithLabelValues(metricsDeviceFPGA, pci, metricsResultFailure))).
+			To(Equal(failed + 1))
+	})
+	var _ = It("will count the downloaded bytes", func() {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(make([]byte, 1024))
+		}))
+		defer srv.Close()
+		downloaded := testutil.ToFloat64(imageDownloadBytes)
+
+		Expect(downloadImage(filepath.Join(testTmpFolder, "image.bin"), srv.URL, "")).To(Succeed())
+		Expect(testutil.ToFloat64(imageDownloadBytes)).To(Equal(downloaded + 1024))
+	})
+})
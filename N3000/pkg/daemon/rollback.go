@@ -0,0 +1,285 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	fpgav1 "github.com/open-ness/openshift-operator/N3000/api/v1"
+)
+
+var (
+	// knownGoodPath is a location of the last known-good FPGA user images on the host,
+	// they have to survive a restart of the daemon
+	knownGoodPath = "/var/lib/n3000-daemon/known-good"
+)
+
+// rollbackError indicates that the flashed image failed the verification and the known-good one was restored
+type rollbackError struct {
+	msg string
+}
+
+func (e *rollbackError) Error() string {
+	return e.msg
+}
+
+func isRollbackError(err error) bool {
+	var re *rollbackError
+	return errors.As(err, &re)
+}
+
+// knownGoodImage describes the last image which passed the verification after the flash of the FPGA
+type knownGoodImage struct {
+	PCIAddr          string    `json:"pciAddr"`
+	UserImageURL     string    `json:"userImageURL"`
+	BitstreamID      string    `json:"bitstreamId"`
+	BitstreamVersion string    `json:"bitstreamVersion,omitempty"`
+	Time             time.Time `json:"time"`
+	// Factory is set for the factory image of the FPGA, used when there is no known-good user image
+	Factory bool `json:"-"`
+}
+
+func (k *knownGoodImage) String() string {
+	if k.Factory {
+		return "factory image"
+	}
+	return fmt.Sprintf("%s (bitstream %s)", k.UserImageURL, k.BitstreamID)
+}
+
+// knownGoodStore keeps a copy of the last known-good image of every FPGA, keyed by PCI address.
+// All methods of a nil store are no-ops.
+type knownGoodStore struct {
+	path string
+}
+
+func newKnownGoodStore(path string) *knownGoodStore {
+	return &knownGoodStore{path: path}
+}
+
+func (s *knownGoodStore) imagePath(pci string) string {
+	return filepath.Join(s.path, pci+".bin")
+}
+
+func (s *knownGoodStore) infoPath(pci string) string {
+	return filepath.Join(s.path, pci+".json")
+}
+
+// load returns the known-good image of the FPGA, nil if there is none
+func (s *knownGoodStore) load(pci string) (*knownGoodImage, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(s.infoPath(pci))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	info := &knownGoodImage{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, fmt.Errorf("invalid known-good image info %s: %v", s.infoPath(pci), err)
+	}
+	if _, err := os.Stat(s.imagePath(pci)); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// save stores a copy of the image as the known-good one of the FPGA
+func (s *knownGoodStore) save(image string, info knownGoodImage) error {
+	if s == nil {
+		return nil
+	}
+	if err := os.MkdirAll(s.path, 0755); err != nil {
+		return err
+	}
+
+	// the previous known-good image is replaced only when the new one is complete
+	tmp := s.imagePath(info.PCIAddr) + ".tmp"
+	if err := copyImage(image, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.imagePath(info.PCIAddr)); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.infoPath(info.PCIAddr), data, 0644)
+}
+
+// copyImage copies the image file, dst is overwritten
+func copyImage(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// verifyProgrammedFPGA checks the FPGA after the power cycle: the card is detected, reports the expected
+// bitstream ID (any, if expected is empty) and its NICs are reported by `fpgadiag -m mactest`.
+// Returns the current status of the FPGA.
+func (fpga *FPGAManager) verifyProgrammedFPGA(PCIAddr, expected string) (*fpgav1.N3000FpgaStatus, error) {
+	log := fpga.Log.WithName("verifyProgrammedFPGA").WithValues("pci", PCIAddr)
+
+	inventory, err := getFPGAInventory(fpga.Log)
+	if err != nil {
+		return nil, err
+	}
+	var status *fpgav1.N3000FpgaStatus
+	for i := range inventory {
+		if inventory[i].PciAddr == PCIAddr {
+			status = &inventory[i]
+			break
+		}
+	}
+	if status == nil {
+		return nil, fmt.Errorf("FPGA %s not detected after power cycle", PCIAddr)
+	}
+
+	if status.BitstreamID == "" {
+		return nil, fmt.Errorf("FPGA %s reports no bitstream ID after power cycle", PCIAddr)
+	}
+	if expected != "" && !strings.EqualFold(status.BitstreamID, expected) {
+		return nil, fmt.Errorf("FPGA %s reports bitstream ID %s, expected %s", PCIAddr, status.BitstreamID, expected)
+	}
+
+	fm := FortvilleManager{Log: fpga.Log}
+	nics, err := fm.getN3000NICs(PCIAddr)
+	if err != nil {
+		return nil, fmt.Errorf("fpgadiag mactest failed on FPGA %s after power cycle: %v", PCIAddr, err)
+	}
+	if len(nics) == 0 {
+		return nil, fmt.Errorf("No interfaces reported by fpgadiag mactest on FPGA %s after power cycle", PCIAddr)
+	}
+
+	log.V(4).Info("FPGA verified", "bitstreamId", status.BitstreamID)
+	return status, nil
+}
+
+// restoreKnownGoodFPGA programs the known-good image of the FPGA, power cycles and verifies it.
+// The FPGA without a known-good image (e.g. never flashed by the daemon, or the image can't be read)
+// is reloaded from its factory image instead. Returns the restored image.
+func (fpga *FPGAManager) restoreKnownGoodFPGA(PCIAddr string, dryRun bool) (*knownGoodImage, error) {
+	log := fpga.Log.WithName("restoreKnownGoodFPGA").WithValues("pci", PCIAddr)
+
+	known, err := fpga.knownGood.load(PCIAddr)
+	if err != nil {
+		log.Error(err, "failed to read known-good image")
+	}
+	if known == nil {
+		return fpga.restoreFactoryFPGA(PCIAddr, dryRun)
+	}
+
+	log.V(2).Info("restoring known-good image", "image", known.String())
+	fpga.journal.mark(log, journalStepProgramFPGA, PCIAddr, known.UserImageURL, false)
+	if err := fpga.ProgramFPGA(fpga.knownGood.imagePath(PCIAddr), PCIAddr, dryRun); err != nil {
+		return known, err
+	}
+	fpga.journal.mark(log, journalStepProgramFPGA, PCIAddr, known.UserImageURL, true)
+
+	if err := powerCycle([]string{PCIAddr}, dryRun, fpga.Log); err != nil {
+		return known, err
+	}
+	if _, err := fpga.verifyProgrammedFPGA(PCIAddr, known.BitstreamID); err != nil {
+		return known, err
+	}
+	return known, nil
+}
+
+// restoreFactoryFPGA reloads the FPGA from its factory image and verifies it. The user image is not changed.
+func (fpga *FPGAManager) restoreFactoryFPGA(PCIAddr string, dryRun bool) (*knownGoodImage, error) {
+	log := fpga.Log.WithName("restoreFactoryFPGA").WithValues("pci", PCIAddr)
+
+	factory := &knownGoodImage{PCIAddr: PCIAddr, Factory: true}
+	log.V(2).Info("no known-good image, reloading the factory image")
+	if err := rsuExec(exec.Command(rsuPath, "fpga", "--page=factory", PCIAddr), log, dryRun); err != nil {
+		return factory, err
+	}
+	if _, err := fpga.verifyProgrammedFPGA(PCIAddr, ""); err != nil {
+		return factory, err
+	}
+	return factory, nil
+}
+
+// rollbackFPGA restores the known-good (or factory) image of the FPGA which failed the verification with verifyErr.
+// Returns a rollbackError if the image was restored and verified.
+func (fpga *FPGAManager) rollbackFPGA(PCIAddr string, verifyErr error, dryRun bool) error {
+	fpga.Log.WithName("rollbackFPGA").V(2).Info("rolling back to known-good image", "pci", PCIAddr,
+		"reason", verifyErr.Error())
+
+	known, err := fpga.restoreKnownGoodFPGA(PCIAddr, dryRun)
+	if err != nil {
+		return fmt.Errorf("%v; rollback to %s failed: %v", verifyErr, known, err)
+	}
+
+	return &rollbackError{msg: fmt.Sprintf("%v; rolled back to %s", verifyErr, known)}
+}
+
+// verifyProgrammedFPGAs verifies the programmed FPGAs from the spec. The image of a verified FPGA becomes
+// its known-good image, a failed one is rolled back to the previous known-good image.
+// Returns the errors of the failed FPGAs, by PCI address.
+func (fpga *FPGAManager) verifyProgrammedFPGAs(n *fpgav1.N3000Node, programmed []string) map[string]error {
+	log := fpga.Log.WithName("verifyProgrammedFPGAs")
+
+	failures := map[string]error{}
+	for i, obj := range n.Spec.FPGA {
+		if !containsString(programmed, obj.PCIAddr) {
+			continue
+		}
+
+		status, err := fpga.verifyProgrammedFPGA(obj.PCIAddr, obj.BitstreamID)
+		if err != nil {
+			log.Error(err, "FPGA verification failed", "pci", obj.PCIAddr)
+			failures[obj.PCIAddr] = fpga.rollbackFPGA(obj.PCIAddr, err, n.Spec.DryRun)
+			continue
+		}
+
+		err = fpga.knownGood.save(fpgaUserImageFile+strconv.Itoa(i)+".bin", knownGoodImage{
+			PCIAddr:          obj.PCIAddr,
+			UserImageURL:     obj.UserImageURL,
+			BitstreamID:      status.BitstreamID,
+			BitstreamVersion: status.BitstreamVersion,
+			Time:             time.Now(),
+		})
+		if err != nil {
+			// the flash itself succeeded, only a future rollback is affected
+			log.Error(err, "failed to save known-good image", "pci", obj.PCIAddr)
+		}
+	}
+	return failures
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
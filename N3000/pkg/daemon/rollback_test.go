@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package daemon
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	fpgav1 "github.com/open-ness/openshift-operator/N3000/api/v1"
+	"k8s.io/klog/v2/klogr"
+)
+
+var _ = Describe("FPGA rollback", func() {
+	const pci = "0000:1b:00.0"
+	var fpga FPGAManager
+	var image string
+
+	BeforeEach(func() {
+		cleanFortville()
+		cleanFPGA()
+		fpgaInfoExec = fakeFpgaInfo
+		fpgadiagExec = fakeFpgadiag
+		ethtoolExec = fakeEthtoolBusInfo
+		fpgasUpdateExec = fakeFpgasUpdate
+		rsuExec = fakeRsu
+
+		fpga = FPGAManager{Log: klogr.New(), knownGood: newKnownGoodStore(knownGoodPath)}
+		image = filepath.Join(testTmpFolder, "fpga0.bin")
+		Expect(ioutil.WriteFile(image, []byte("image"), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(knownGoodPath)).To(Succeed())
+		Expect(os.Remove(image)).To(Succeed())
+		cleanUpHandlers()
+	})
+
+	var _ = It("will read back the known-good image", func() {
+		Expect(fpga.knownGood.save(image, knownGoodImage{PCIAddr: pci, UserImageURL: "http://www.test.com/fpga/image.bin",
+			BitstreamID: "0x21000000000000"})).To(Succeed())
+
+		known, err := fpga.knownGood.load(pci)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(known.String()).To(Equal("http://www.test.com/fpga/image.bin (bitstream 0x21000000000000)"))
+		content, err := ioutil.ReadFile(fpga.knownGood.imagePath(pci))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(Equal("image"))
+	})
+	var _ = It("will return nothing without known-good image", func() {
+		known, err := fpga.knownGood.load(pci)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(known).To(BeNil())
+
+		var store *knownGoodStore
+		Expect(store.save(image, knownGoodImage{PCIAddr: pci})).To(Succeed())
+		known, err = store.load(pci)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(known).To(BeNil())
+	})
+	var _ = It("will verify the programmed FPGA", func() {
+		status, err := fpga.verifyProgrammedFPGA(pci, "0x21000000000000")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(status.BitstreamID).To(Equal("0x21000000000000"))
+	})
+	var _ = It("will fail the verification of wrong bitstream ID", func() {
+		_, err := fpga.verifyProgrammedFPGA(pci, "0x23000410010310")
+		Expect(err).To(MatchError("FPGA 0000:1b:00.0 reports bitstream ID 0x21000000000000, expected 0x23000410010310"))
+	})
+	var _ = It("will fail the verification of missing FPGA", func() {
+		_, err := fpga.verifyProgrammedFPGA("0000:3b:00.0", "")
+		Expect(err).To(MatchError("FPGA 0000:3b:00.0 not detected after power cycle"))
+	})
+	var _ = It("will fail the verification without NICs", func() {
+		fakeFpgadiagErrReturn = errors.New("error")
+		_, err := fpga.verifyProgrammedFPGA(pci, "")
+		Expect(err).To(HaveOccurred())
+	})
+	var _ = It("will roll back to factory image without known-good image", func() {
+		err := fpga.rollbackFPGA(pci, errors.New("verification failed"), false)
+		Expect(err).To(MatchError("verification failed; rolled back to factory image"))
+		Expect(flashFailureReason(err)).To(Equal(FlashRolledBack))
+	})
+	var _ = It("will report failed rollback to factory image", func() {
+		fakeRsuUpdateErrReturn = errors.New("error")
+		err := fpga.rollbackFPGA(pci, errors.New("verification failed"), false)
+		Expect(err).To(MatchError("verification failed; rollback to factory image failed: error"))
+		Expect(flashFailureReason(err)).To(Equal(FlashFailed))
+	})
+	var _ = It("will roll back to known-good image", func() {
+		Expect(fpga.knownGood.save(image, knownGoodImage{PCIAddr: pci, UserImageURL: "http://www.test.com/fpga/image.bin",
+			BitstreamID: "0x21000000000000"})).To(Succeed())
+
+		err := fpga.rollbackFPGA(pci, errors.New("verification failed"), false)
+		Expect(err).To(MatchError("verification failed; rolled back to http://www.test.com/fpga/image.bin" +
+			" (bitstream 0x21000000000000)"))
+		Expect(flashFailureReason(err)).To(Equal(FlashRolledBack))
+	})
+	var _ = It("will report failed rollback", func() {
+		Expect(fpga.knownGood.save(image, knownGoodImage{PCIAddr: pci, UserImageURL: "http://www.test.com/fpga/image.bin",
+			BitstreamID: "0x21000000000000"})).To(Succeed())
+		fakeFpgasUpdateErrReturn = errors.New("error")
+
+		err := fpga.rollbackFPGA(pci, errors.New("verification failed"), false)
+		Expect(err).To(HaveOccurred())
+		Expect(flashFailureReason(err)).To(Equal(FlashFailed))
+	})
+	var _ = It("will save known-good image of verified FPGA and roll back the other one", func() {
+		n := &fpgav1.N3000Node{Spec: fpgav1.N3000NodeSpec{FPGA: []fpgav1.N3000Fpga{
+			{PCIAddr: pci, UserImageURL: "http://www.test.com/fpga/image.bin"},
+		}}}
+		Expect(fpga.verifyProgrammedFPGAs(n, []string{pci})).To(BeEmpty())
+		known, err := fpga.knownGood.load(pci)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(known.BitstreamID).To(Equal("0x21000000000000"))
+
+		n.Spec.FPGA[0].BitstreamID = "0x23000410010310"
+		failures := fpga.verifyProgrammedFPGAs(n, []string{pci})
+		Expect(failures).To(HaveKey(pci))
+		Expect(flashFailureReason(failures[pci])).To(Equal(FlashRolledBack))
+	})
+	var _ = Context("rollBackInterruptedSteps", func() {
+		interrupted := &journalState{Entries: []journalEntry{
+			{Step: journalStepProgramFPGA, Device: pci, Image: "http://www.test.com/fpga/image2.bin"},
+			{Step: journalStepUpdateNIC, Device: "64:4c:36:11:1b:a8"},
+		}}
+
+		var _ = It("will restore the known-good image of the interrupted FPGA", func() {
+			Expect(fpga.knownGood.save(image, knownGoodImage{PCIAddr: pci,
+				UserImageURL: "http://www.test.com/fpga/image.bin", BitstreamID: "0x21000000000000"})).To(Succeed())
+			r := N3000NodeReconciler{fpga: fpga}
+
+			Expect(r.rollBackInterruptedSteps(&fpgav1.N3000Node{}, interrupted, false)).To(Equal([]string{
+				"FPGA 0000:1b:00.0 rolled back to http://www.test.com/fpga/image.bin (bitstream 0x21000000000000)",
+				"NIC 64:4c:36:11:1b:a8 not rolled back: previous NVM not kept",
+			}))
+		})
+		var _ = It("will restore the factory image of the FPGA without known-good image", func() {
+			r := N3000NodeReconciler{fpga: fpga}
+			Expect(r.rollBackInterruptedSteps(&fpgav1.N3000Node{}, interrupted, false)).
+				To(ContainElement("FPGA 0000:1b:00.0 rolled back to factory image"))
+		})
+		var _ = It("will not roll back the FPGA programmed again", func() {
+			fakeFpgasUpdateErrReturn = errors.New("should not be programmed")
+			r := N3000NodeReconciler{fpga: fpga}
+			Expect(r.rollBackInterruptedSteps(&fpgav1.N3000Node{}, interrupted, true)).To(BeEmpty())
+
+			n := &fpgav1.N3000Node{Spec: fpgav1.N3000NodeSpec{FPGA: []fpgav1.N3000Fpga{{PCIAddr: pci}}}}
+			Expect(r.rollBackInterruptedSteps(n, interrupted, false)).
+				To(Equal([]string{"NIC 64:4c:36:11:1b:a8 not rolled back: previous NVM not kept"}))
+		})
+	})
+})
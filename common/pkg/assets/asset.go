@@ -61,6 +61,10 @@ type Asset struct {
 	// BlockingReadiness stores polling configuration.
 	BlockingReadiness ReadinessPollConfig
 
+	// Optional asset which fails to deploy is skipped, e.g. the ServiceMonitor when the Prometheus Operator
+	// is not installed
+	Optional bool
+
 	substitutions map[string]string
 
 	objects []client.Object
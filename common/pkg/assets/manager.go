@@ -114,6 +114,10 @@ func (m *Manager) Deploy(ctx context.Context) error {
 			"objects", len(asset.objects))
 
 		if err := asset.createOrUpdate(ctx, m.Client, m.Owner, m.Scheme); err != nil {
+			if asset.Optional {
+				log.Error(err, "failed to create optional asset, skipping", "path", asset.Path)
+				continue
+			}
 			log.Error(err, "failed to create asset", "path", asset.Path)
 			return err
 		}
@@ -211,6 +211,32 @@ var _ = Describe("Asset Tests", func() {
 			err = k8sClient.Delete(context.TODO(), node)
 			Expect(err).ToNot(HaveOccurred())
 		})
+		var _ = It("Run LoadAndDeploy (skip failed optional asset)", func() {
+			var err error
+			log = klogr.New().WithName("N3000Assets-Test")
+
+			var invalidObject InvalidRuntimeType
+
+			assets := []Asset{
+				{
+					log:           log,
+					Path:          fakeAssetFile,
+					substitutions: map[string]string{"one": "two"},
+					objects: []client.Object{
+						&invalidObject},
+					Optional: true,
+				},
+			}
+
+			manager := Manager{Client: k8sClient,
+				Log:    log,
+				Assets: assets,
+				Owner:  fakeOwner,
+				Scheme: scheme.Scheme}
+
+			err = manager.LoadAndDeploy(context.TODO(), false)
+			Expect(err).ToNot(HaveOccurred())
+		})
 		var _ = It("Run Manager loadFromFile (bad file)", func() {
 			var err error
 			log = klogr.New().WithName("N3000Assets-Test")
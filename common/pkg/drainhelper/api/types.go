@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+// Package api contains the drain settings and the drain simulation status of the CRDs of the operators. The
+// package has no dependencies besides the Kubernetes API types, so the API packages of the operators can import
+// it without the DrainHelper.
+package api
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DrainPolicySpec configures the drain of the node before the devices are updated
+// +kubebuilder:object:generate=true
+type DrainPolicySpec struct {
+	// Timeout of the drain in seconds, the default of the daemon (DRAIN_TIMEOUT_SECONDS) is used if not set
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds *int64 `json:"timeoutSeconds,omitempty"`
+	// Grace period of the evicted pods in seconds, the pods' own grace period is used if not set
+	// +kubebuilder:validation:Minimum=0
+	GracePeriodSeconds *int32 `json:"gracePeriodSeconds,omitempty"`
+	// Pods matching the selector are not evicted from the node
+	ExcludePodSelector *metav1.LabelSelector `json:"excludePodSelector,omitempty"`
+	// Pods are deleted instead of evicted, PodDisruptionBudgets are not honoured
+	DisableEviction bool `json:"disableEviction,omitempty"`
+	// Allows deleting pods not managed by a controller (e.g. ReplicaSet, Job), true if not set
+	Force *bool `json:"force,omitempty"`
+	// Hooks run before cordon, after drain, before uncordon and after uncordon of the node, in order
+	Hooks []MaintenanceHookSpec `json:"hooks,omitempty"`
+}
+
+// MaintenanceHookSpec is an action run at a stage of the maintenance of the node, e.g. a handover of the workload
+// to a peer before the node is cordoned. Exactly one of http and job has to be set.
+// +kubebuilder:object:generate=true
+type MaintenanceHookSpec struct {
+	// Name of the hook, also used as a prefix of the Job name
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=52
+	Name string `json:"name"`
+	// Stage of the maintenance at which the hook is run
+	// +kubebuilder:validation:Enum=BeforeCordon;AfterDrain;BeforeUncordon;AfterUncordon
+	Stage string `json:"stage"`
+	// HTTP request sent by the daemon, the hook succeeds on 2xx response
+	HTTP *HTTPHookSpec `json:"http,omitempty"`
+	// Job created in the namespace of the operator, the hook succeeds when the Job completes.
+	// Containers of the Job get NODENAME and HOOK_STAGE env variables.
+	// The Job is deleted if it doesn't complete within the timeout.
+	// The pods of the Job run with the default ServiceAccount of the namespace without its token mounted. The Job
+	// is rejected if it sets the serviceAccountName, uses the host's network, PID or IPC namespaces, volumes other
+	// than emptyDir, configMap and downwardAPI, Secrets in the env of the containers, or privileged containers,
+	// privilege escalation or added capabilities.
+	Job *batchv1.JobSpec `json:"job,omitempty"`
+	// Timeout of the hook in seconds, 60 if not set
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds *int64 `json:"timeoutSeconds,omitempty"`
+	// Fail stops the maintenance when the hook fails, Ignore continues. Fail if not set.
+	// A failed BeforeCordon hook leaves the node uncordoned, a failed AfterDrain hook skips the update of the
+	// devices and the node is uncordoned, a failed BeforeUncordon hook leaves the node cordoned.
+	// +kubebuilder:validation:Enum=Fail;Ignore
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+}
+
+// HTTPHookSpec is an HTTP request with a JSON body containing the node, the stage and the hook name
+// +kubebuilder:object:generate=true
+type HTTPHookSpec struct {
+	URL string `json:"url"`
+	// +kubebuilder:validation:Enum=GET;POST;PUT
+	Method string `json:"method,omitempty"`
+	// PEM encoded CA certificates trusted for the https URL in addition to the CAs of the system
+	CABundle []byte `json:"caBundle,omitempty"`
+	// URL of the proxy the request is sent through, the proxy of the daemon's environment (HTTPS_PROXY,
+	// HTTP_PROXY and NO_PROXY) is used if not set
+	ProxyURL string `json:"proxyURL,omitempty"`
+}
+
+// DrainSimulationStatus is the result of the dry-run drain done before the node is cordoned
+// +kubebuilder:object:generate=true
+type DrainSimulationStatus struct {
+	// Time of the simulation
+	Time metav1.Time `json:"time"`
+	// Pods which would be evicted or deleted, as namespace/name
+	Pods []string `json:"pods,omitempty"`
+	// PodDisruptionBudgets which allow no disruption of the pods, the node is not cordoned if there are any
+	BlockingPDBs []string `json:"blockingPDBs,omitempty"`
+	// Reasons the drain would fail, e.g. pods not managed by a controller, the node is not cordoned if there are any
+	Errors []string `json:"errors,omitempty"`
+}
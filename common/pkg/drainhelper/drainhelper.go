@@ -9,11 +9,14 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/open-ness/openshift-operator/common/pkg/events"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/wait"
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/leaderelection"
@@ -26,6 +29,19 @@ const (
 	drainHelperTimeoutDefault    = int64(90)
 	leaseDurationEnvVarName      = "LEASE_DURATION_SECONDS"
 	leaseDurationDefault         = int64(600)
+	leaseNameEnvVarName          = "LEASE_NAME"
+	leaseNameDefault             = "n3000-daemon-lease"
+	leaseSlotsEnvVarName         = "LEASE_SLOTS"
+	leaseSlotsDefault            = int64(1)
+	leaseScopeLabelEnvVarName    = "LEASE_SCOPE_LABEL"
+
+	// Reasons of the events recorded on the node
+	EventDrainStarted   = "DrainStarted"
+	EventDrainFinished  = "DrainFinished"
+	EventDrainFailed    = "DrainFailed"
+	EventDrainBlocked   = "DrainBlocked"
+	EventUncordoned     = "Uncordoned"
+	EventUncordonFailed = "UncordonFailed"
 )
 
 // logWriter is a wrapper around logr's log.Info() to allow drain.Helper logging
@@ -38,60 +54,90 @@ func (w logWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
+// DrainPolicy overrides the default drain settings for a single Run. Unset fields keep the defaults.
+type DrainPolicy struct {
+	// Timeout of the drain
+	Timeout time.Duration
+	// GracePeriodSeconds of the evicted pods, -1 uses the pod's own grace period
+	GracePeriodSeconds *int
+	// ExcludePodSelector selects the pods which are not evicted
+	ExcludePodSelector labels.Selector
+	// DisableEviction deletes the pods instead of evicting them, so PodDisruptionBudgets are not honoured
+	DisableEviction bool
+	// Force allows deleting pods which are not managed by a controller
+	Force *bool
+	// OnSimulation is called with the result of the dry-run drain, before the node is cordoned.
+	// The node is not cordoned if the simulation shows that the drain would be blocked.
+	OnSimulation func(*DrainSimulation)
+	// Hooks run before cordon, after drain, before uncordon and after uncordon of the node
+	Hooks []Hook
+}
+
 type DrainHelper struct {
 	log       logr.Logger
 	clientSet *clientset.Clientset
 	nodeName  string
+	namespace string
 
-	drainer              *drain.Helper
-	leaseLock            *resourcelock.LeaseLock
+	drainer *drain.Helper
+
+	// leaseName is the name of the Lease, or the prefix of the Leases if there are multiple slots
+	leaseName string
+	// leaseSlots is the number of nodes which can be in maintenance at the same time
+	leaseSlots int
+	// leaseScopeLabel is the node label (e.g. zone or rack), nodes with different values of the label
+	// have separate slots
+	leaseScopeLabel      string
 	leaderElectionConfig leaderelection.LeaderElectionConfig
+
+	stateMu sync.Mutex
+	state   LeadershipState
+	// holders are the holders of the slots observed while waiting, by Lease name
+	holders map[string]string
+
+	recorder *events.NodeRecorder
+}
+
+// int64FromEnv returns the value of the env variable, or the default value if it's not set or invalid
+func int64FromEnv(log logr.Logger, name string, defaultValue int64) int64 {
+	str := os.Getenv(name)
+	if str == "" {
+		return defaultValue
+	}
+	val, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		log.Error(err, "failed to parse env variable to int64 - using default value", "variable", name)
+		return defaultValue
+	}
+	return val
 }
 
 func NewDrainHelper(l logr.Logger, cs *clientset.Clientset, nodeName, namespace string) *DrainHelper {
 	log := l.WithName("drainhelper")
 
-	drainTimeout := drainHelperTimeoutDefault
-	drainTimeoutStr := os.Getenv(drainHelperTimeoutEnvVarName)
-	if drainTimeoutStr != "" {
-		val, err := strconv.ParseInt(drainTimeoutStr, 10, 64)
-		if err != nil {
-			log.Error(err, "failed to parse env variable to int64 - using default value",
-				"variable", drainHelperTimeoutEnvVarName)
-		} else {
-			drainTimeout = val
-		}
-	}
+	drainTimeout := int64FromEnv(log, drainHelperTimeoutEnvVarName, drainHelperTimeoutDefault)
 	log.V(2).Info("drain settings", "timeout seconds", drainTimeout)
 
-	leaseDur := leaseDurationDefault
-	leaseDurStr := os.Getenv(leaseDurationEnvVarName)
-	if leaseDurStr != "" {
-		val, err := strconv.ParseInt(leaseDurStr, 10, 64)
-		if err != nil {
-			log.Error(err, "failed to parse env variable to int64 - using default value",
-				"variable", leaseDurationEnvVarName)
-		} else {
-			leaseDur = val
-		}
+	leaseDur := int64FromEnv(log, leaseDurationEnvVarName, leaseDurationDefault)
+	leaseName := os.Getenv(leaseNameEnvVarName)
+	if leaseName == "" {
+		leaseName = leaseNameDefault
 	}
-	log.V(2).Info("lease settings", "duration seconds", leaseDur)
-
-	lock := &resourcelock.LeaseLock{
-		LeaseMeta: metav1.ObjectMeta{
-			Name:      "n3000-daemon-lease",
-			Namespace: namespace,
-		},
-		Client: cs.CoordinationV1(),
-		LockConfig: resourcelock.ResourceLockConfig{
-			Identity: nodeName,
-		},
+	leaseSlots := int64FromEnv(log, leaseSlotsEnvVarName, leaseSlotsDefault)
+	if leaseSlots < 1 {
+		log.Info("invalid number of lease slots - using default value", "variable", leaseSlotsEnvVarName,
+			"value", leaseSlots)
+		leaseSlots = leaseSlotsDefault
 	}
+	leaseScopeLabel := os.Getenv(leaseScopeLabelEnvVarName)
+	log.V(2).Info("lease settings", "duration seconds", leaseDur, "name", leaseName, "slots", leaseSlots,
+		"scope label", leaseScopeLabel)
 
 	return &DrainHelper{
 		log:       log,
 		clientSet: cs,
 		nodeName:  nodeName,
+		namespace: namespace,
 
 		drainer: &drain.Helper{
 			Client:              cs,
@@ -112,9 +158,10 @@ func NewDrainHelper(l logr.Logger, cs *clientset.Clientset, nodeName, namespace
 			ErrOut: logWriter{log},
 		},
 
-		leaseLock: lock,
+		leaseName:       leaseName,
+		leaseSlots:      int(leaseSlots),
+		leaseScopeLabel: leaseScopeLabel,
 		leaderElectionConfig: leaderelection.LeaderElectionConfig{
-			Lock:            lock,
 			ReleaseOnCancel: true,
 			LeaseDuration:   time.Duration(leaseDur) * time.Second,
 			RenewDeadline:   15 * time.Second,
@@ -123,95 +170,170 @@ func NewDrainHelper(l logr.Logger, cs *clientset.Clientset, nodeName, namespace
 	}
 }
 
-// Run joins leader election and drains(only if drain is set) the node if becomes a leader.
+// SetEventRecorder sets the recorder of the drain and uncordon events of the node
+func (dh *DrainHelper) SetEventRecorder(recorder *events.NodeRecorder) {
+	dh.recorder = recorder
+}
+
+// drainerFor returns a copy of the default drainer with the policy applied
+func (dh *DrainHelper) drainerFor(policy *DrainPolicy) *drain.Helper {
+	drainer := *dh.drainer
+	if policy == nil {
+		return &drainer
+	}
+
+	if policy.Timeout > 0 {
+		drainer.Timeout = policy.Timeout
+	}
+	if policy.GracePeriodSeconds != nil {
+		drainer.GracePeriodSeconds = *policy.GracePeriodSeconds
+	}
+	if policy.Force != nil {
+		drainer.Force = *policy.Force
+	}
+	drainer.DisableEviction = policy.DisableEviction
+	if policy.ExcludePodSelector != nil && !policy.ExcludePodSelector.Empty() {
+		selector := policy.ExcludePodSelector
+		drainer.AdditionalFilters = append(append([]drain.PodFilter{}, drainer.AdditionalFilters...),
+			func(pod corev1.Pod) drain.PodDeleteStatus {
+				if selector.Matches(labels.Set(pod.Labels)) {
+					return drain.MakePodDeleteStatusSkip()
+				}
+				return drain.MakePodDeleteStatusOkay()
+			})
+	}
+	return &drainer
+}
+
+// Run joins leader election for one of the maintenance slots and drains(only if drain is set) the node when it
+// becomes a leader of any of them. The drain is performed according to the policy, nil policy uses the defaults.
+//
+// ctx only cancels the waiting for the slot, ErrWaitCancelled is returned in that case.
+// Once the slot is acquired, the drain and f are always completed.
 //
 // f is a function that takes a context and returns a bool.
-// It should return true if uncordon should be performed(Only applicable if drain is set to true).
+// It should return true if uncordon should be performed(Only applicable if drain is set to true) and the slot released.
 // If `f` returns false, the uncordon does not take place. This is useful in 2-step scenario like sriov-fec-daemon where
 // reboot must be performed without loosing the leadership and without the uncordon.
-func (dh *DrainHelper) Run(f func(context.Context) bool, drain bool) error {
+func (dh *DrainHelper) Run(ctx context.Context, f func(context.Context) bool, drain bool, policy *DrainPolicy) error {
 	log := dh.log.WithName("Run()")
+	names, err := dh.leaseNames(ctx)
+	if err != nil {
+		log.Error(err, "failed to get the lease names")
+		return err
+	}
+	log.V(4).Info("competing for maintenance slot", "leases", names)
 
-	defer func() {
-		// Following mitigation is needed because of the bug in the leader election's release functionality
-		// Release fails because the input (leader election record) is created incomplete (missing fields):
-		// Failed to release lock: Lease.coordination.k8s.io "n3000-daemon-lease" is invalid:
-		// ... spec.leaseDurationSeconds: Invalid value: 0: must be greater than 0
-		// When the leader election finishes (Run() ends), we need to clean up the Lease manually.
-		// See: https://github.com/kubernetes/kubernetes/pull/80954
-		// This however is not critical - if the leader will not refresh the lease,
-		// another node will take it after some time.
-
-		dh.log.V(4).Info("releasing the lock (bug mitigation)")
+	dh.startWaiting(names)
+	defer dh.setState(StateIdle)
+	waitStart := time.Now()
 
-		leaderElectionRecord, _, err := dh.leaseLock.Get(context.Background())
-		if err != nil {
-			log.Error(err, "failed to get the LeaderElectionRecord")
-			return
-		}
-		leaderElectionRecord.HolderIdentity = ""
-		if err := dh.leaseLock.Update(context.Background(), *leaderElectionRecord); err != nil {
-			log.Error(err, "failed to update the LeaderElectionRecord")
-		}
-	}()
+	var (
+		mu        sync.Mutex
+		acquired  *resourcelock.LeaseLock
+		cancelled bool
+	)
 
-	ctx, cancel := context.WithCancel(context.Background())
+	leCtx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// every slot has its own context, so the electors of the other slots can be stopped once one is acquired
+	slotCancels := make([]context.CancelFunc, len(names))
+	slotCtxs := make([]context.Context, len(names))
+	for i := range names {
+		slotCtxs[i], slotCancels[i] = context.WithCancel(leCtx)
+	}
+
 	var innerErr error
 
-	lec := dh.leaderElectionConfig
-	lec.Callbacks = leaderelection.LeaderCallbacks{
-		OnStartedLeading: func(ctx context.Context) {
-			log.V(2).Info("started leading")
-
-			uncordonAndFreeLeadership := func() {
-				// always try to uncordon the node
-				// e.g. when cordoning succeeds, but draining fails
-				log.V(4).Info("uncordoning node")
-				if err := dh.uncordon(ctx); err != nil {
-					log.Error(err, "uncordon failed")
-					innerErr = err
+	electors := make([]*leaderelection.LeaderElector, len(names))
+	for i, name := range names {
+		i := i
+		lock := dh.newLeaseLock(name)
+
+		lec := dh.leaderElectionConfig
+		lec.Lock = lock
+		lec.Callbacks = leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				mu.Lock()
+				won := acquired == nil && !cancelled
+				if won {
+					acquired = lock
 				}
-
-				log.V(4).Info("cancelling the context to finish the leadership")
-				cancel()
-			}
-
-			if drain {
-				log.V(4).Info("cordoning & draining node")
-				if err := dh.cordonAndDrain(ctx); err != nil {
-					log.Error(err, "cordonAndDrain failed")
-					innerErr = err
-					uncordonAndFreeLeadership()
+				mu.Unlock()
+				if !won {
+					// another slot was acquired at the same time or the waiting was cancelled
+					log.V(4).Info("maintenance slot not needed - leaving", "lease", name)
+					slotCancels[i]()
 					return
 				}
-			}
+				for j, slotCancel := range slotCancels {
+					if j != i {
+						slotCancel()
+					}
+				}
 
-			log.V(4).Info("worker function - start")
-			performUncordon := f(ctx)
-			log.V(4).Info("worker function - end", "performUncordon", performUncordon)
-			if drain && performUncordon {
-				uncordonAndFreeLeadership()
-			}
-		},
-		OnStoppedLeading: func() {
-			log.V(4).Info("stopped leading")
-		},
-		OnNewLeader: func(id string) {
-			if id != dh.nodeName {
-				log.V(2).Info("new leader elected", "leader", id, "this", dh.nodeName)
+				log.V(2).Info("started leading", "lease", name)
+				leaseWaitSeconds.Observe(time.Since(waitStart).Seconds())
+				dh.setState(StateLeading)
+				dh.lead(ctx, cancel, f, drain, policy, &innerErr)
+			},
+			OnStoppedLeading: func() {
+				log.V(4).Info("stopped leading", "lease", name)
+			},
+			OnNewLeader: func(id string) {
+				if id != dh.nodeName {
+					log.V(2).Info("new leader elected", "leader", id, "this", dh.nodeName, "lease", name)
+				}
+				dh.observeHolder(name, id)
+			},
+		}
+
+		le, err := leaderelection.NewLeaderElector(lec)
+		if err != nil {
+			log.Error(err, "failed to create new leader elector")
+			return err
+		}
+		electors[i] = le
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			mu.Lock()
+			if acquired == nil {
+				log.V(2).Info("waiting for maintenance slot cancelled")
+				cancelled = true
+				cancel()
 			}
-		},
+			mu.Unlock()
+		case <-done:
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i, le := range electors {
+		wg.Add(1)
+		go func(ctx context.Context, le *leaderelection.LeaderElector) {
+			defer wg.Done()
+			le.Run(ctx)
+		}(slotCtxs[i], le)
 	}
+	wg.Wait()
+	close(done)
 
-	le, err := leaderelection.NewLeaderElector(lec)
-	if err != nil {
-		log.Error(err, "failed to create new leader elector")
-		return err
+	mu.Lock()
+	defer mu.Unlock()
+
+	// the elector releases the Lease when its context is cancelled, this only covers a failed release
+	if acquired != nil {
+		dh.release(acquired)
 	}
 
-	le.Run(ctx)
+	if cancelled {
+		return ErrWaitCancelled
+	}
 
 	if innerErr != nil {
 		log.Error(innerErr, "error during (un)cordon or drain actions")
@@ -220,7 +342,116 @@ func (dh *DrainHelper) Run(f func(context.Context) bool, drain bool) error {
 	return innerErr
 }
 
-func (dh *DrainHelper) cordonAndDrain(ctx context.Context) error {
+// lead is run by the holder of the maintenance slot, cancel finishes the leadership
+func (dh *DrainHelper) lead(ctx context.Context, cancel context.CancelFunc, f func(context.Context) bool,
+	drain bool, policy *DrainPolicy, innerErr *error) {
+	log := dh.log.WithName("Run()")
+
+	drainer := dh.drainerFor(policy)
+	var (
+		onSimulation func(*DrainSimulation)
+		hooks        []Hook
+	)
+	if policy != nil {
+		onSimulation = policy.OnSimulation
+		hooks = policy.Hooks
+	}
+
+	freeLeadership := func() {
+		log.V(4).Info("cancelling the context to finish the leadership")
+		cancel()
+	}
+
+	uncordonAndFreeLeadership := func() {
+		defer freeLeadership()
+
+		if err := dh.runHooks(ctx, hooks, HookBeforeUncordon); err != nil {
+			log.Error(err, "node left cordoned")
+			*innerErr = err
+			return
+		}
+
+		// always try to uncordon the node
+		// e.g. when cordoning succeeds, but draining fails
+		log.V(4).Info("uncordoning node")
+		if err := dh.uncordon(ctx); err != nil {
+			log.Error(err, "uncordon failed")
+			dh.recorder.Eventf(nil, corev1.EventTypeWarning, EventUncordonFailed, "Failed to uncordon node: %v", err)
+			*innerErr = err
+			return
+		}
+		dh.recorder.Eventf(nil, corev1.EventTypeNormal, EventUncordoned, "Node uncordoned")
+
+		if err := dh.runHooks(ctx, hooks, HookAfterUncordon); err != nil {
+			*innerErr = err
+		}
+	}
+
+	if drain {
+		log.V(4).Info("simulating drain")
+		sim, err := dh.simulateDrain(ctx, drainer)
+		if err != nil {
+			// the drain itself reports the problem if it persists
+			log.Error(err, "drain simulation failed")
+		} else {
+			log.V(2).Info("drain simulated", "pods", sim.Pods, "blocking PDBs", sim.BlockingPDBs, "errors", sim.Errors)
+			if onSimulation != nil {
+				onSimulation(sim)
+			}
+			if sim.Blocked() {
+				err := fmt.Errorf("drain blocked: %s", sim)
+				log.Error(err, "node not cordoned")
+				dh.recorder.Eventf(nil, corev1.EventTypeWarning, EventDrainBlocked, "Drain not started: %s", sim)
+				*innerErr = err
+				freeLeadership()
+				return
+			}
+		}
+
+		if err := dh.runHooks(ctx, hooks, HookBeforeCordon); err != nil {
+			log.Error(err, "node not cordoned")
+			*innerErr = err
+			freeLeadership()
+			return
+		}
+
+		log.V(4).Info("cordoning & draining node")
+		dh.recorder.Eventf(nil, corev1.EventTypeNormal, EventDrainStarted, "Cordoning and draining node")
+		drainStart := time.Now()
+		err = dh.cordonAndDrain(ctx, drainer)
+		drainDurationSeconds.Observe(time.Since(drainStart).Seconds())
+		drainsTotal.WithLabelValues(resultLabel(err)).Inc()
+		if err != nil {
+			log.Error(err, "cordonAndDrain failed")
+			dh.recorder.Eventf(nil, corev1.EventTypeWarning, EventDrainFailed, "Failed to drain node: %v", err)
+			*innerErr = err
+			uncordonAndFreeLeadership()
+			return
+		}
+		dh.recorder.Eventf(nil, corev1.EventTypeNormal, EventDrainFinished, "Node drained")
+
+		if err := dh.runHooks(ctx, hooks, HookAfterDrain); err != nil {
+			log.Error(err, "devices not updated")
+			*innerErr = err
+			uncordonAndFreeLeadership()
+			return
+		}
+	}
+
+	log.V(4).Info("worker function - start")
+	performUncordon := f(ctx)
+	log.V(4).Info("worker function - end", "performUncordon", performUncordon)
+	if !performUncordon {
+		return
+	}
+	if drain {
+		uncordonAndFreeLeadership()
+	} else {
+		freeLeadership()
+	}
+}
+
+func (dh *DrainHelper) cordonAndDrain(ctx context.Context, drainer *drain.Helper) error {
 	log := dh.log.WithName("cordonAndDrain()")
 
 	node, nodeGetErr := dh.clientSet.CoreV1().Nodes().Get(ctx, dh.nodeName, metav1.GetOptions{})
@@ -232,13 +463,13 @@ func (dh *DrainHelper) cordonAndDrain(ctx context.Context) error {
 	var e error
 	backoff := wait.Backoff{Steps: 5, Duration: 15 * time.Second, Factor: 2}
 	f := func() (bool, error) {
-		if err := drain.RunCordonOrUncordon(dh.drainer, node, true); err != nil {
+		if err := drain.RunCordonOrUncordon(drainer, node, true); err != nil {
 			log.V(2).Info("failed to cordon the node - retrying", "nodeName", dh.nodeName, "reason", err.Error())
 			e = err
 			return false, nil
 		}
 
-		if err := drain.RunNodeDrain(dh.drainer, dh.nodeName); err != nil {
+		if err := drain.RunNodeDrain(drainer, dh.nodeName); err != nil {
 			log.V(2).Info("failed to drain the node - retrying", "nodeName", dh.nodeName, "reason", err.Error())
 			e = err
 			return false, nil
@@ -261,6 +492,11 @@ func (dh *DrainHelper) cordonAndDrain(ctx context.Context) error {
 	return nil
 }
 
+// Uncordon marks the node as schedulable, e.g. when the daemon was restarted while the node was drained
+func (dh *DrainHelper) Uncordon(ctx context.Context) error {
+	return dh.uncordon(ctx)
+}
+
 func (dh *DrainHelper) uncordon(ctx context.Context) error {
 	log := dh.log.WithName("uncordon()")
 
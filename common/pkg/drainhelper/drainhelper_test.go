@@ -14,6 +14,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	clientset "k8s.io/client-go/kubernetes"
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/klog/v2/klogr"
@@ -44,6 +45,37 @@ var _ = Describe("DrainHelper Tests", func() {
 			Expect(dh).ToNot(Equal(nil))
 		})
 
+		var _ = It("Apply DrainPolicy to the drainer", func() {
+			dh := NewDrainHelper(log, &clientSet, "node", "namespace")
+			Expect(dh.drainerFor(nil).Timeout).To(Equal(dh.drainer.Timeout))
+
+			gracePeriod := 30
+			force := false
+			selector, err := labels.Parse("app=du")
+			Expect(err).ToNot(HaveOccurred())
+
+			drainer := dh.drainerFor(&DrainPolicy{
+				Timeout:            time.Minute,
+				GracePeriodSeconds: &gracePeriod,
+				ExcludePodSelector: selector,
+				DisableEviction:    true,
+				Force:              &force,
+			})
+			Expect(drainer.Timeout).To(Equal(time.Minute))
+			Expect(drainer.GracePeriodSeconds).To(Equal(30))
+			Expect(drainer.DisableEviction).To(BeTrue())
+			Expect(drainer.Force).To(BeFalse())
+			Expect(drainer.AdditionalFilters).To(HaveLen(1))
+			Expect(drainer.AdditionalFilters[0](corev1.Pod{ObjectMeta: v1.ObjectMeta{
+				Labels: map[string]string{"app": "du"}}}).Delete).To(BeFalse())
+			Expect(drainer.AdditionalFilters[0](corev1.Pod{}).Delete).To(BeTrue())
+
+			// defaults are not changed
+			Expect(dh.drainer.Timeout).To(Equal(5 * time.Second))
+			Expect(dh.drainer.Force).To(BeTrue())
+			Expect(dh.drainer.AdditionalFilters).To(BeEmpty())
+		})
+
 		var _ = It("Create simple DrainHelper with invalid drain timeout", func() {
 			var err error
 			log = klogr.New().WithName("N3000DrainHelper-Test")
@@ -85,7 +117,7 @@ var _ = Describe("DrainHelper Tests", func() {
 			dh := NewDrainHelper(log, cset, "node", "namespace")
 			Expect(dh).ToNot(Equal(nil))
 
-			err = dh.Run(func(c context.Context) bool { return true }, true)
+			err = dh.Run(context.Background(), func(c context.Context) bool { return true }, true, nil)
 			Expect(err).To(HaveOccurred())
 		})
 
@@ -100,7 +132,7 @@ var _ = Describe("DrainHelper Tests", func() {
 			dh := NewDrainHelper(log, cset, "node", "namespace")
 			Expect(dh).ToNot(Equal(nil))
 
-			err = dh.cordonAndDrain(context.Background())
+			err = dh.cordonAndDrain(context.Background(), dh.drainer)
 			Expect(err).To(HaveOccurred())
 		})
 
@@ -176,7 +208,7 @@ var _ = Describe("DrainHelper Tests", func() {
 			dh := NewDrainHelper(log, cset, "dummy", "namespace")
 			Expect(dh).ToNot(Equal(nil))
 
-			err = dh.cordonAndDrain(context.Background())
+			err = dh.cordonAndDrain(context.Background(), dh.drainer)
 			Expect(err).ToNot(HaveOccurred())
 
 			// Cleanup
@@ -204,7 +236,7 @@ var _ = Describe("DrainHelper Tests", func() {
 			dh := NewDrainHelper(log, cset, "dummy", "namespace")
 			Expect(dh).ToNot(Equal(nil))
 
-			err = dh.cordonAndDrain(context.Background())
+			err = dh.cordonAndDrain(context.Background(), dh.drainer)
 			Expect(err).ToNot(HaveOccurred())
 
 			err = dh.uncordon(context.Background())
@@ -240,7 +272,7 @@ var _ = Describe("DrainHelper Tests", func() {
 			dh := NewDrainHelper(log, cset, "dummy", "default")
 			Expect(dh).ToNot(Equal(nil))
 
-			err = dh.Run(func(c context.Context) bool { return true }, true)
+			err = dh.Run(context.Background(), func(c context.Context) bool { return true }, true, nil)
 			Expect(err).ToNot(HaveOccurred())
 
 			// Cleanup
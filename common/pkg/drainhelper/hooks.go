@@ -0,0 +1,307 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package drainhelper
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// HookStage is the point of the maintenance at which the hook is run
+type HookStage string
+
+const (
+	HookBeforeCordon   HookStage = "BeforeCordon"
+	HookAfterDrain     HookStage = "AfterDrain"
+	HookBeforeUncordon HookStage = "BeforeUncordon"
+	HookAfterUncordon  HookStage = "AfterUncordon"
+
+	// HookTimeoutDefault is used for the hooks without timeout
+	HookTimeoutDefault = time.Minute
+
+	// Reasons of the events recorded on the node
+	EventHookSucceeded = "HookSucceeded"
+	EventHookFailed    = "HookFailed"
+
+	hookNodeEnvVarName  = "NODENAME"
+	hookStageEnvVarName = "HOOK_STAGE"
+)
+
+var (
+	// jobPollPeriod is the period of checking the status of the hook's Job
+	jobPollPeriod = 2 * time.Second
+)
+
+// HTTPAction is an HTTP request sent by the hook
+type HTTPAction struct {
+	URL string
+	// Method of the request, POST if not set
+	Method string
+	// PEM encoded CA certificates trusted in addition to the system ones
+	CABundle []byte
+	// ProxyURL of the proxy the request is sent through, the proxy of the environment is used if not set
+	ProxyURL string
+}
+
+// client returns the HTTP client sending the request of the action
+func (a *HTTPAction) client() (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if a.ProxyURL != "" {
+		proxyURL, err := url.Parse(a.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %v", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	if len(a.CABundle) != 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(a.CABundle) {
+			return nil, fmt.Errorf("no valid certificate in the CA bundle")
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// Hook is an action run at the stage of the maintenance, e.g. a handover of the workload before the node is cordoned.
+// Exactly one of HTTP and Job is set.
+type Hook struct {
+	Name  string
+	Stage HookStage
+	// Timeout of the hook, HookTimeoutDefault if not set
+	Timeout time.Duration
+	// IgnoreFailure continues the maintenance when the hook fails
+	IgnoreFailure bool
+
+	// HTTP request, the hook succeeds on 2xx response. The body is a JSON with the node, the stage and the hook name.
+	HTTP *HTTPAction
+	// Job created with the spec in the namespace of the DrainHelper, the hook succeeds when the Job completes.
+	// Containers of the Job get NODENAME and HOOK_STAGE env variables. The spec has to pass validateJobSpec,
+	// the pods run without the token of the ServiceAccount mounted.
+	Job *batchv1.JobSpec
+}
+
+// hookRequest is the body of the hook's HTTP request
+type hookRequest struct {
+	Node  string    `json:"node"`
+	Stage HookStage `json:"stage"`
+	Hook  string    `json:"hook"`
+}
+
+// runHooks runs the hooks of the stage in order. The error of the first failed hook is returned,
+// unless its failure is ignored.
+func (dh *DrainHelper) runHooks(ctx context.Context, hooks []Hook, stage HookStage) error {
+	log := dh.log.WithName("runHooks()").WithValues("stage", stage)
+
+	for _, hook := range hooks {
+		if hook.Stage != stage {
+			continue
+		}
+
+		log.V(4).Info("running hook", "hook", hook.Name)
+		err := dh.runHook(ctx, hook)
+		if err == nil {
+			log.V(2).Info("hook succeeded", "hook", hook.Name)
+			dh.recorder.Eventf(nil, corev1.EventTypeNormal, EventHookSucceeded, "Hook %s (%s) succeeded", hook.Name, stage)
+			continue
+		}
+
+		err = fmt.Errorf("hook %s (%s) failed: %v", hook.Name, stage, err)
+		if hook.IgnoreFailure {
+			log.Error(err, "hook failure ignored")
+			dh.recorder.Eventf(nil, corev1.EventTypeWarning, EventHookFailed, "%v - ignored", err)
+			continue
+		}
+		log.Error(err, "hook failed")
+		dh.recorder.Eventf(nil, corev1.EventTypeWarning, EventHookFailed, "%v", err)
+		return err
+	}
+	return nil
+}
+
+func (dh *DrainHelper) runHook(ctx context.Context, hook Hook) error {
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = HookTimeoutDefault
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch {
+	case hook.HTTP != nil:
+		return dh.runHTTPHook(ctx, hook)
+	case hook.Job != nil:
+		return dh.runJobHook(ctx, dh.clientSet, hook)
+	default:
+		return fmt.Errorf("no action")
+	}
+}
+
+func (dh *DrainHelper) runHTTPHook(ctx context.Context, hook Hook) error {
+	body, err := json.Marshal(hookRequest{Node: dh.nodeName, Stage: hook.Stage, Hook: hook.Name})
+	if err != nil {
+		return err
+	}
+
+	method := hook.HTTP.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequestWithContext(ctx, method, hook.HTTP.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client, err := hook.HTTP.client()
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s returned %s", method, hook.HTTP.URL, resp.Status)
+	}
+	return nil
+}
+
+// runJobHook creates the Job and waits until it completes. The failed Job is kept, so its logs can be inspected,
+// the succeeded one and the one not completed within the timeout of the hook are removed.
+func (dh *DrainHelper) runJobHook(ctx context.Context, cs kubernetes.Interface, hook Hook) error {
+	// the Job is created with the permissions of the daemon, so it's not allowed to gain any of them
+	if err := validateJobSpec(hook.Job); err != nil {
+		return err
+	}
+
+	namespace := dh.namespace
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s-", hook.Name),
+			Namespace:    namespace,
+		},
+		Spec: *hook.Job.DeepCopy(),
+	}
+	automountToken := false
+	job.Spec.Template.Spec.AutomountServiceAccountToken = &automountToken
+	for i := range job.Spec.Template.Spec.Containers {
+		c := &job.Spec.Template.Spec.Containers[i]
+		c.Env = append(c.Env,
+			corev1.EnvVar{Name: hookNodeEnvVarName, Value: dh.nodeName},
+			corev1.EnvVar{Name: hookStageEnvVarName, Value: string(hook.Stage)})
+	}
+
+	job, err := cs.BatchV1().Jobs(namespace).Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+
+	var jobErr error
+	err = wait.PollImmediateUntil(jobPollPeriod, func() (bool, error) {
+		current, err := cs.BatchV1().Jobs(namespace).Get(ctx, job.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		for _, c := range current.Status.Conditions {
+			if c.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch c.Type {
+			case batchv1.JobComplete:
+				return true, nil
+			case batchv1.JobFailed:
+				jobErr = fmt.Errorf("Job %s failed: %s", job.Name, c.Message)
+				return true, nil
+			}
+		}
+		return false, nil
+	}, ctx.Done())
+	if err != nil {
+		// the Job would keep running after the hook failed
+		dh.deleteJob(cs, job.Name)
+		return fmt.Errorf("Job %s not completed: %v", job.Name, err)
+	}
+	if jobErr != nil {
+		return jobErr
+	}
+
+	dh.deleteJob(cs, job.Name)
+	return nil
+}
+
+// deleteJob removes the hook's Job with its pods
+func (dh *DrainHelper) deleteJob(cs kubernetes.Interface, name string) {
+	propagation := metav1.DeletePropagationBackground
+	if err := cs.BatchV1().Jobs(dh.namespace).Delete(context.Background(), name,
+		metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil {
+		dh.log.Error(err, "failed to remove hook's Job", "job", name)
+	}
+}
+
+// validateJobSpec checks that the pods of the hook's Job don't get more privileges than the ones of a pod with
+// the default ServiceAccount of the namespace, i.e. they don't use another ServiceAccount, the host's namespaces,
+// host paths, Secrets or privileged containers
+func validateJobSpec(spec *batchv1.JobSpec) error {
+	pod := &spec.Template.Spec
+	switch {
+	case pod.ServiceAccountName != "" || pod.DeprecatedServiceAccount != "":
+		return fmt.Errorf("serviceAccountName is not allowed")
+	case pod.HostNetwork || pod.HostPID || pod.HostIPC:
+		return fmt.Errorf("hostNetwork, hostPID and hostIPC are not allowed")
+	case len(pod.EphemeralContainers) != 0:
+		return fmt.Errorf("ephemeralContainers are not allowed")
+	}
+	for _, v := range pod.Volumes {
+		if v.EmptyDir == nil && v.ConfigMap == nil && v.DownwardAPI == nil {
+			return fmt.Errorf("volume %s: only emptyDir, configMap and downwardAPI volumes are allowed", v.Name)
+		}
+	}
+
+	containers := append(append([]corev1.Container{}, pod.InitContainers...), pod.Containers...)
+	for _, c := range containers {
+		if sc := c.SecurityContext; sc != nil {
+			switch {
+			case sc.Privileged != nil && *sc.Privileged:
+				return fmt.Errorf("container %s: privileged is not allowed", c.Name)
+			case sc.AllowPrivilegeEscalation != nil && *sc.AllowPrivilegeEscalation:
+				return fmt.Errorf("container %s: allowPrivilegeEscalation is not allowed", c.Name)
+			case sc.Capabilities != nil && len(sc.Capabilities.Add) != 0:
+				return fmt.Errorf("container %s: added capabilities are not allowed", c.Name)
+			}
+		}
+		for _, e := range c.Env {
+			if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil {
+				return fmt.Errorf("container %s: env %s from a Secret is not allowed", c.Name, e.Name)
+			}
+		}
+		for _, e := range c.EnvFrom {
+			if e.SecretRef != nil {
+				return fmt.Errorf("container %s: envFrom a Secret is not allowed", c.Name)
+			}
+		}
+	}
+	return nil
+}
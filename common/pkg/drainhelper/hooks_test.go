@@ -0,0 +1,198 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package drainhelper
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/klog/v2/klogr"
+)
+
+var _ = Describe("Maintenance hooks", func() {
+	dh := &DrainHelper{log: klogr.New(), nodeName: "node1", namespace: "vran-acceleration-operators"}
+
+	var _ = Describe("HTTP hooks", func() {
+		var (
+			srv      *httptest.Server
+			requests []hookRequest
+			status   int
+		)
+
+		BeforeEach(func() {
+			requests = nil
+			status = http.StatusOK
+			srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				req := hookRequest{}
+				Expect(json.NewDecoder(r.Body).Decode(&req)).To(Succeed())
+				requests = append(requests, req)
+				w.WriteHeader(status)
+			}))
+		})
+		AfterEach(func() {
+			srv.Close()
+		})
+
+		var _ = It("will run the hooks of the stage", func() {
+			hooks := []Hook{
+				{Name: "handover", Stage: HookBeforeCordon, HTTP: &HTTPAction{URL: srv.URL + "/handover"}},
+				{Name: "register", Stage: HookAfterUncordon, HTTP: &HTTPAction{URL: srv.URL + "/register"}},
+			}
+			Expect(dh.runHooks(context.TODO(), hooks, HookBeforeCordon)).To(Succeed())
+			Expect(requests).To(Equal([]hookRequest{{Node: "node1", Stage: HookBeforeCordon, Hook: "handover"}}))
+		})
+		var _ = It("will return error of the failed hook", func() {
+			status = http.StatusServiceUnavailable
+			hooks := []Hook{
+				{Name: "handover", Stage: HookBeforeCordon, HTTP: &HTTPAction{URL: srv.URL}},
+				{Name: "notify", Stage: HookBeforeCordon, HTTP: &HTTPAction{URL: srv.URL}},
+			}
+			err := dh.runHooks(context.TODO(), hooks, HookBeforeCordon)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("hook handover (BeforeCordon) failed"))
+			Expect(requests).To(HaveLen(1))
+		})
+		var _ = It("will ignore the failure if allowed", func() {
+			status = http.StatusInternalServerError
+			hooks := []Hook{
+				{Name: "notify", Stage: HookAfterDrain, IgnoreFailure: true, HTTP: &HTTPAction{URL: srv.URL}},
+			}
+			Expect(dh.runHooks(context.TODO(), hooks, HookAfterDrain)).To(Succeed())
+		})
+		var _ = It("will fail on timeout", func() {
+			slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(200 * time.Millisecond)
+			}))
+			defer slow.Close()
+			hooks := []Hook{
+				{Name: "slow", Stage: HookBeforeUncordon, Timeout: 10 * time.Millisecond,
+					HTTP: &HTTPAction{URL: slow.URL, Method: http.MethodPut}},
+			}
+			Expect(dh.runHooks(context.TODO(), hooks, HookBeforeUncordon)).ToNot(Succeed())
+		})
+		var _ = It("will trust the CA bundle of the hook", func() {
+			tlsSrv := httptest.NewTLSServer(srv.Config.Handler)
+			defer tlsSrv.Close()
+			caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsSrv.Certificate().Raw})
+
+			hook := Hook{Name: "handover", Stage: HookBeforeCordon, HTTP: &HTTPAction{URL: tlsSrv.URL}}
+			Expect(dh.runHook(context.TODO(), hook)).ToNot(Succeed())
+			hook.HTTP.CABundle = caBundle
+			Expect(dh.runHook(context.TODO(), hook)).To(Succeed())
+			Expect(requests).To(HaveLen(1))
+		})
+		var _ = It("will send the request through the proxy of the hook", func() {
+			hook := Hook{Name: "handover", Stage: HookBeforeCordon,
+				HTTP: &HTTPAction{URL: "http://du-manager.example/handover", ProxyURL: srv.URL}}
+			Expect(dh.runHook(context.TODO(), hook)).To(Succeed())
+			Expect(requests).To(Equal([]hookRequest{{Node: "node1", Stage: HookBeforeCordon, Hook: "handover"}}))
+		})
+	})
+
+	var _ = Describe("Job hooks", func() {
+		BeforeEach(func() {
+			jobPollPeriod = 10 * time.Millisecond
+		})
+		AfterEach(func() {
+			jobPollPeriod = 2 * time.Second
+		})
+
+		hook := Hook{Name: "handover", Stage: HookBeforeCordon, Job: &batchv1.JobSpec{
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "handover", Image: "handover:latest"}},
+			}},
+		}}
+		// finishJob sets the condition of the hook's Job once it's created
+		finishJob := func(cs *fake.Clientset, condition batchv1.JobConditionType) {
+			defer GinkgoRecover()
+			Eventually(func() int {
+				jobs, _ := cs.BatchV1().Jobs(dh.namespace).List(context.TODO(), metav1.ListOptions{})
+				return len(jobs.Items)
+			}).Should(Equal(1))
+			jobs, err := cs.BatchV1().Jobs(dh.namespace).List(context.TODO(), metav1.ListOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			job := jobs.Items[0]
+			job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue,
+				Message: "BackoffLimitExceeded"}}
+			_, err = cs.BatchV1().Jobs(dh.namespace).UpdateStatus(context.TODO(), &job, metav1.UpdateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		var _ = It("will create the Job and remove it when completed", func() {
+			cs := fake.NewSimpleClientset()
+			go finishJob(cs, batchv1.JobComplete)
+			Expect(dh.runJobHook(context.TODO(), cs, hook)).To(Succeed())
+
+			jobs, err := cs.BatchV1().Jobs(dh.namespace).List(context.TODO(), metav1.ListOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(jobs.Items).To(BeEmpty())
+		})
+		var _ = It("will return error and keep the failed Job", func() {
+			cs := fake.NewSimpleClientset()
+			go finishJob(cs, batchv1.JobFailed)
+			err := dh.runJobHook(context.TODO(), cs, hook)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("BackoffLimitExceeded"))
+
+			jobs, err := cs.BatchV1().Jobs(dh.namespace).List(context.TODO(), metav1.ListOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(jobs.Items).To(HaveLen(1))
+			Expect(jobs.Items[0].Spec.Template.Spec.Containers[0].Env).To(Equal([]corev1.EnvVar{
+				{Name: "NODENAME", Value: "node1"}, {Name: "HOOK_STAGE", Value: "BeforeCordon"}}))
+			Expect(*jobs.Items[0].Spec.Template.Spec.AutomountServiceAccountToken).To(BeFalse())
+		})
+		var _ = It("will not create the Job with more privileges than the default ServiceAccount", func() {
+			privileged := true
+			for _, modify := range []func(*corev1.PodSpec){
+				func(p *corev1.PodSpec) { p.ServiceAccountName = "vran-operator" },
+				func(p *corev1.PodSpec) { p.HostNetwork = true },
+				func(p *corev1.PodSpec) {
+					p.Volumes = []corev1.Volume{{Name: "host", VolumeSource: corev1.VolumeSource{
+						HostPath: &corev1.HostPathVolumeSource{Path: "/"}}}}
+				},
+				func(p *corev1.PodSpec) {
+					p.Containers[0].SecurityContext = &corev1.SecurityContext{Privileged: &privileged}
+				},
+				func(p *corev1.PodSpec) {
+					p.Containers[0].EnvFrom = []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "token"}}}}
+				},
+			} {
+				h := hook
+				h.Job = hook.Job.DeepCopy()
+				modify(&h.Job.Template.Spec)
+
+				cs := fake.NewSimpleClientset()
+				Expect(dh.runJobHook(context.TODO(), cs, h)).ToNot(Succeed())
+				jobs, err := cs.BatchV1().Jobs(dh.namespace).List(context.TODO(), metav1.ListOptions{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(jobs.Items).To(BeEmpty())
+			}
+		})
+		var _ = It("will remove the Job not completed within the timeout", func() {
+			cs := fake.NewSimpleClientset()
+			ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+			defer cancel()
+			err := dh.runJobHook(ctx, cs, hook)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("not completed"))
+
+			jobs, err := cs.BatchV1().Jobs(dh.namespace).List(context.TODO(), metav1.ListOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(jobs.Items).To(BeEmpty())
+		})
+	})
+
+})
@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package drainhelper
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// metricsNamespace is the prefix of the metrics of the DrainHelper, shared by the daemons of the operators
+	metricsNamespace = "openshift_operator"
+
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+var (
+	leaseWaitSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "maintenance_lease_wait_seconds",
+		Help:      "Time spent waiting for the maintenance slot",
+		Buckets:   []float64{1, 10, 30, 60, 300, 600, 1800, 3600, 7200},
+	})
+	drainDurationSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_drain_duration_seconds",
+		Help:      "Duration of the cordon and drain of the node",
+		Buckets:   []float64{5, 15, 30, 60, 120, 300, 600, 1200},
+	})
+	drainsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_drains_total",
+		Help:      "Number of the drains of the node by result",
+	}, []string{"result"})
+)
+
+// RegisterMetrics registers the metrics of the DrainHelper in the metrics registry of the controller-runtime.
+// It's called once by the daemon using the DrainHelper.
+func RegisterMetrics() error {
+	for _, c := range []prometheus.Collector{leaseWaitSeconds, drainDurationSeconds, drainsTotal} {
+		if err := metrics.Registry.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func resultLabel(err error) string {
+	if err != nil {
+		return resultFailure
+	}
+	return resultSuccess
+}
@@ -0,0 +1,223 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package drainhelper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+var invalidLeaseNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// slotNames returns the names of the Leases which are the slots of the maintenance semaphore.
+// A single slot keeps the plain name, e.g. "n3000-daemon-lease", "n3000-daemon-lease-zone-a"
+// or "n3000-daemon-lease-zone-a-0", "n3000-daemon-lease-zone-a-1" for 2 slots.
+func slotNames(name, scope string, slots int) []string {
+	scope = strings.Trim(invalidLeaseNameChars.ReplaceAllString(strings.ToLower(scope), "-"), "-.")
+	if scope != "" {
+		name += "-" + scope
+	}
+	if slots <= 1 {
+		return []string{name}
+	}
+
+	names := make([]string, 0, slots)
+	for i := 0; i < slots; i++ {
+		names = append(names, fmt.Sprintf("%s-%d", name, i))
+	}
+	return names
+}
+
+// leaseNames returns the names of the slots the node competes for.
+// Nodes without the scope label share the slots of the unscoped name.
+func (dh *DrainHelper) leaseNames(ctx context.Context) ([]string, error) {
+	scope := ""
+	if dh.leaseScopeLabel != "" {
+		node, err := dh.clientSet.CoreV1().Nodes().Get(ctx, dh.nodeName, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		scope = node.Labels[dh.leaseScopeLabel]
+		if scope == "" {
+			dh.log.V(2).Info("node has no lease scope label - using the unscoped lease", "label", dh.leaseScopeLabel)
+		}
+	}
+	return slotNames(dh.leaseName, scope, dh.leaseSlots), nil
+}
+
+func (dh *DrainHelper) newLeaseLock(name string) *resourcelock.LeaseLock {
+	return &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: dh.namespace,
+		},
+		Client: dh.clientSet.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: dh.nodeName,
+		},
+	}
+}
+
+// LeadershipState tells where the DrainHelper is in the competition for a maintenance slot
+type LeadershipState string
+
+const (
+	// StateIdle - Run is not in progress
+	StateIdle LeadershipState = "Idle"
+	// StateAcquiring - Run tries to acquire a slot, which may be free
+	StateAcquiring LeadershipState = "Acquiring"
+	// StateWaiting - all the slots are held by other nodes
+	StateWaiting LeadershipState = "Waiting"
+	// StateLeading - the slot is held by this node, the node is in maintenance
+	StateLeading LeadershipState = "Leading"
+)
+
+var (
+	// ErrWaitCancelled is returned by Run if its context was cancelled before a slot was acquired
+	ErrWaitCancelled = errors.New("waiting for maintenance slot cancelled")
+
+	// waitPollPeriod is the period of checking the state by WatchWaiting
+	waitPollPeriod = 5 * time.Second
+)
+
+// State returns the current state and the nodes holding the slots, sorted by name.
+// The holders are only known while waiting.
+func (dh *DrainHelper) State() (LeadershipState, []string) {
+	dh.stateMu.Lock()
+	defer dh.stateMu.Unlock()
+
+	if dh.state == "" {
+		return StateIdle, nil
+	}
+	var holders []string
+	if dh.state == StateWaiting {
+		for _, h := range dh.holders {
+			holders = append(holders, h)
+		}
+		sort.Strings(holders)
+	}
+	return dh.state, holders
+}
+
+func (dh *DrainHelper) startWaiting(names []string) {
+	dh.stateMu.Lock()
+	defer dh.stateMu.Unlock()
+
+	dh.state = StateAcquiring
+	dh.holders = map[string]string{}
+	for _, name := range names {
+		dh.holders[name] = ""
+	}
+}
+
+func (dh *DrainHelper) setState(state LeadershipState) {
+	dh.stateMu.Lock()
+	defer dh.stateMu.Unlock()
+	dh.state = state
+}
+
+// observeHolder updates the holder of the slot, the node is waiting when all the slots are held by other nodes
+func (dh *DrainHelper) observeHolder(name, holder string) {
+	dh.stateMu.Lock()
+	defer dh.stateMu.Unlock()
+
+	// leader callbacks are asynchronous, late ones are ignored
+	if dh.state != StateAcquiring && dh.state != StateWaiting {
+		return
+	}
+	if _, ok := dh.holders[name]; !ok {
+		return
+	}
+	dh.holders[name] = holder
+
+	dh.state = StateWaiting
+	for _, h := range dh.holders {
+		if h == "" || h == dh.nodeName {
+			dh.state = StateAcquiring
+			break
+		}
+	}
+}
+
+// release frees the slot if it's still held by this node
+func (dh *DrainHelper) release(lock *resourcelock.LeaseLock) {
+	log := dh.log.WithName("release()")
+
+	record, _, err := lock.Get(context.Background())
+	if err != nil {
+		log.Error(err, "failed to get the LeaderElectionRecord", "lease", lock.LeaseMeta.Name)
+		return
+	}
+	if record.HolderIdentity != dh.nodeName {
+		return
+	}
+
+	log.V(4).Info("releasing the lease", "lease", lock.LeaseMeta.Name)
+	now := metav1.Now()
+	if err := lock.Update(context.Background(), resourcelock.LeaderElectionRecord{
+		LeaderTransitions:    record.LeaderTransitions,
+		LeaseDurationSeconds: 1,
+		AcquireTime:          now,
+		RenewTime:            now,
+	}); err != nil {
+		log.Error(err, "failed to release the lease", "lease", lock.LeaseMeta.Name)
+	}
+}
+
+// WatchWaiting watches Run while it competes for a maintenance slot. onWaiting is called once, when all the slots
+// are held by other nodes. cancel (of Run's context) is called if changed returns true before the slot is acquired,
+// e.g. when the spec of the CR was updated in the meantime.
+// The returned function stops the watching and returns true if the waiting was reported.
+func (dh *DrainHelper) WatchWaiting(cancel context.CancelFunc, onWaiting func(holders []string),
+	changed func() bool) func() bool {
+	ctx, stop := context.WithCancel(context.Background())
+	reported := false
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(waitPollPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			state, holders := dh.State()
+			if state == StateLeading {
+				return
+			}
+			if state == StateWaiting && !reported {
+				reported = true
+				onWaiting(holders)
+			}
+			if changed() {
+				dh.log.V(2).Info("spec changed while waiting for maintenance slot")
+				cancel()
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() bool {
+		once.Do(func() {
+			stop()
+			wg.Wait()
+		})
+		return reported
+	}
+}
@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package drainhelper
+
+import (
+	"context"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2/klogr"
+)
+
+var _ = Describe("Maintenance slots", func() {
+	var _ = Describe("slotNames", func() {
+		var _ = It("will return the plain name for a single slot", func() {
+			Expect(slotNames("n3000-daemon-lease", "", 1)).To(Equal([]string{"n3000-daemon-lease"}))
+		})
+		var _ = It("will return a name per slot", func() {
+			Expect(slotNames("n3000-daemon-lease", "", 3)).To(Equal([]string{
+				"n3000-daemon-lease-0", "n3000-daemon-lease-1", "n3000-daemon-lease-2"}))
+		})
+		var _ = It("will add the scope to the names", func() {
+			Expect(slotNames("sriov-fec-daemon-lease", "Rack_12", 2)).To(Equal([]string{
+				"sriov-fec-daemon-lease-rack-12-0", "sriov-fec-daemon-lease-rack-12-1"}))
+			Expect(slotNames("n3000-daemon-lease", "_", 1)).To(Equal([]string{"n3000-daemon-lease"}))
+		})
+	})
+
+	var _ = Describe("lease settings", func() {
+		AfterEach(func() {
+			Expect(os.Unsetenv(leaseNameEnvVarName)).To(Succeed())
+			Expect(os.Unsetenv(leaseSlotsEnvVarName)).To(Succeed())
+			Expect(os.Unsetenv(leaseScopeLabelEnvVarName)).To(Succeed())
+		})
+
+		var _ = It("will use the defaults", func() {
+			dh := NewDrainHelper(klogr.New(), &clientset.Clientset{}, "node", "namespace")
+			Expect(dh.leaseName).To(Equal("n3000-daemon-lease"))
+			Expect(dh.leaseSlots).To(Equal(1))
+			Expect(dh.leaseScopeLabel).To(BeEmpty())
+		})
+		var _ = It("will read the settings from env", func() {
+			Expect(os.Setenv(leaseNameEnvVarName, "sriov-fec-daemon-lease")).To(Succeed())
+			Expect(os.Setenv(leaseSlotsEnvVarName, "4")).To(Succeed())
+			Expect(os.Setenv(leaseScopeLabelEnvVarName, "topology.kubernetes.io/zone")).To(Succeed())
+
+			dh := NewDrainHelper(klogr.New(), &clientset.Clientset{}, "node", "namespace")
+			Expect(dh.leaseName).To(Equal("sriov-fec-daemon-lease"))
+			Expect(dh.leaseSlots).To(Equal(4))
+			Expect(dh.leaseScopeLabel).To(Equal("topology.kubernetes.io/zone"))
+		})
+		var _ = It("will use default number of slots for invalid value", func() {
+			Expect(os.Setenv(leaseSlotsEnvVarName, "0")).To(Succeed())
+			dh := NewDrainHelper(klogr.New(), &clientset.Clientset{}, "node", "namespace")
+			Expect(dh.leaseSlots).To(Equal(1))
+		})
+	})
+
+	var _ = Describe("leadership state", func() {
+		newDrainHelper := func() *DrainHelper {
+			return &DrainHelper{log: klogr.New(), nodeName: "node1"}
+		}
+
+		var _ = It("will be idle before Run", func() {
+			state, holders := newDrainHelper().State()
+			Expect(state).To(Equal(StateIdle))
+			Expect(holders).To(BeEmpty())
+		})
+		var _ = It("will be waiting when all the slots are held by other nodes", func() {
+			dh := newDrainHelper()
+			dh.startWaiting([]string{"lease-0", "lease-1"})
+			dh.observeHolder("lease-1", "node3")
+			state, _ := dh.State()
+			Expect(state).To(Equal(StateAcquiring))
+
+			dh.observeHolder("lease-0", "node2")
+			state, holders := dh.State()
+			Expect(state).To(Equal(StateWaiting))
+			Expect(holders).To(Equal([]string{"node2", "node3"}))
+
+			// slot released
+			dh.observeHolder("lease-0", "")
+			state, _ = dh.State()
+			Expect(state).To(Equal(StateAcquiring))
+		})
+		var _ = It("will ignore late holders", func() {
+			dh := newDrainHelper()
+			dh.startWaiting([]string{"lease"})
+			dh.setState(StateLeading)
+			dh.observeHolder("lease", "node2")
+			state, _ := dh.State()
+			Expect(state).To(Equal(StateLeading))
+		})
+	})
+
+	var _ = Describe("WatchWaiting", func() {
+		BeforeEach(func() {
+			waitPollPeriod = 10 * time.Millisecond
+		})
+		AfterEach(func() {
+			waitPollPeriod = 5 * time.Second
+		})
+
+		var _ = It("will report waiting and cancel when changed", func() {
+			dh := &DrainHelper{log: klogr.New(), nodeName: "node1"}
+			dh.startWaiting([]string{"lease"})
+			dh.observeHolder("lease", "node2")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			waiting := make(chan []string, 1)
+			changed := make(chan bool, 1)
+			stop := dh.WatchWaiting(cancel, func(holders []string) { waiting <- holders },
+				func() bool { return len(changed) > 0 })
+
+			Eventually(waiting).Should(Receive(Equal([]string{"node2"})))
+			Consistently(ctx.Done(), 50*time.Millisecond).ShouldNot(BeClosed())
+			changed <- true
+			Eventually(ctx.Done()).Should(BeClosed())
+			Expect(stop()).To(BeTrue())
+		})
+		var _ = It("will not cancel once leading", func() {
+			dh := &DrainHelper{log: klogr.New(), nodeName: "node1"}
+			dh.startWaiting([]string{"lease"})
+			dh.setState(StateLeading)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			stop := dh.WatchWaiting(cancel, func(holders []string) {}, func() bool { return true })
+			Consistently(ctx.Done(), 50*time.Millisecond).ShouldNot(BeClosed())
+			Expect(stop()).To(BeFalse())
+		})
+	})
+
+	var _ = Describe("release", func() {
+		newLease := func(holder string) *coordinationv1.Lease {
+			duration := int32(600)
+			return &coordinationv1.Lease{
+				ObjectMeta: metav1.ObjectMeta{Name: "lease", Namespace: "namespace"},
+				Spec: coordinationv1.LeaseSpec{
+					HolderIdentity:       &holder,
+					LeaseDurationSeconds: &duration,
+				},
+			}
+		}
+		newLock := func(cs *fake.Clientset) *resourcelock.LeaseLock {
+			return &resourcelock.LeaseLock{
+				LeaseMeta:  metav1.ObjectMeta{Name: "lease", Namespace: "namespace"},
+				Client:     cs.CoordinationV1(),
+				LockConfig: resourcelock.ResourceLockConfig{Identity: "node1"},
+			}
+		}
+
+		var _ = It("will release the lease held by the node", func() {
+			cs := fake.NewSimpleClientset(newLease("node1"))
+			dh := &DrainHelper{log: klogr.New(), nodeName: "node1"}
+			dh.release(newLock(cs))
+
+			lease, err := cs.CoordinationV1().Leases("namespace").Get(context.TODO(), "lease", metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*lease.Spec.HolderIdentity).To(BeEmpty())
+			Expect(*lease.Spec.LeaseDurationSeconds).To(Equal(int32(1)))
+		})
+		var _ = It("will not release the lease held by another node", func() {
+			cs := fake.NewSimpleClientset(newLease("node2"))
+			dh := &DrainHelper{log: klogr.New(), nodeName: "node1"}
+			dh.release(newLock(cs))
+
+			lease, err := cs.CoordinationV1().Leases("namespace").Get(context.TODO(), "lease", metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*lease.Spec.HolderIdentity).To(Equal("node2"))
+		})
+	})
+})
@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package drainhelper
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/kubectl/pkg/drain"
+)
+
+// DrainSimulation is the result of the dry-run drain done before the node is cordoned
+type DrainSimulation struct {
+	// Pods which would be evicted or deleted, as namespace/name
+	Pods []string
+	// BlockingPDBs are the PodDisruptionBudgets which allow no disruption of the pods to be evicted
+	BlockingPDBs []string
+	// Errors are the reasons the drain would fail, e.g. pods not managed by a controller
+	Errors []string
+}
+
+// Blocked returns true if the drain is not expected to succeed
+func (s *DrainSimulation) Blocked() bool {
+	return len(s.BlockingPDBs) > 0 || len(s.Errors) > 0
+}
+
+func (s *DrainSimulation) String() string {
+	var msgs []string
+	if len(s.BlockingPDBs) > 0 {
+		msgs = append(msgs, "blocking PodDisruptionBudgets: "+strings.Join(s.BlockingPDBs, ", "))
+	}
+	msgs = append(msgs, s.Errors...)
+	if len(msgs) == 0 {
+		return fmt.Sprintf("%d pods to be evicted", len(s.Pods))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// simulateDrain lists the pods which would be removed from the node by the drainer
+// and the PodDisruptionBudgets which would block their eviction
+func (dh *DrainHelper) simulateDrain(ctx context.Context, drainer *drain.Helper) (*DrainSimulation, error) {
+	list, errs := drainer.GetPodsForDeletion(dh.nodeName)
+	if list == nil {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+
+	sim := &DrainSimulation{}
+	for _, err := range errs {
+		sim.Errors = append(sim.Errors, err.Error())
+	}
+	sort.Strings(sim.Errors)
+
+	podsByNamespace := map[string][]corev1.Pod{}
+	for _, pod := range list.Pods() {
+		sim.Pods = append(sim.Pods, pod.Namespace+"/"+pod.Name)
+		podsByNamespace[pod.Namespace] = append(podsByNamespace[pod.Namespace], pod)
+	}
+	sort.Strings(sim.Pods)
+
+	// deleted pods are not protected by PodDisruptionBudgets
+	if drainer.DisableEviction {
+		return sim, nil
+	}
+
+	for namespace, pods := range podsByNamespace {
+		pdbs, err := drainer.Client.PolicyV1beta1().PodDisruptionBudgets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, pdb := range pdbs.Items {
+			// PodDisruptionBudget with empty selector selects no pods
+			if pdb.Spec.Selector == nil || len(pdb.Spec.Selector.MatchLabels)+len(pdb.Spec.Selector.MatchExpressions) == 0 {
+				continue
+			}
+			selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+			if err != nil {
+				continue
+			}
+
+			matching := 0
+			for _, pod := range pods {
+				if selector.Matches(labels.Set(pod.Labels)) {
+					matching++
+				}
+			}
+			if matching > 0 && pdb.Status.DisruptionsAllowed == 0 {
+				sim.BlockingPDBs = append(sim.BlockingPDBs, fmt.Sprintf("%s/%s (%d pods)", pdb.Namespace, pdb.Name,
+					matching))
+			}
+		}
+	}
+	sort.Strings(sim.BlockingPDBs)
+
+	return sim, nil
+}
@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package drainhelper
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/klog/v2/klogr"
+	"k8s.io/kubectl/pkg/drain"
+)
+
+var _ = Describe("simulateDrain", func() {
+	managedPod := func(name string, podLabels map[string]string) *corev1.Pod {
+		controller := true
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "vran",
+				Labels:    podLabels,
+				OwnerReferences: []metav1.OwnerReference{
+					{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "du", Controller: &controller},
+				},
+			},
+			Spec: corev1.PodSpec{NodeName: "node1"},
+		}
+	}
+	pdb := func(name string, allowed int32) *policyv1beta1.PodDisruptionBudget {
+		return &policyv1beta1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "vran"},
+			Spec: policyv1beta1.PodDisruptionBudgetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "du"}},
+			},
+			Status: policyv1beta1.PodDisruptionBudgetStatus{DisruptionsAllowed: allowed},
+		}
+	}
+	newDrainer := func(cs *fake.Clientset) *drain.Helper {
+		return &drain.Helper{
+			Client:              cs,
+			Force:               false,
+			IgnoreAllDaemonSets: true,
+			DeleteEmptyDirData:  true,
+		}
+	}
+	dh := &DrainHelper{log: klogr.New(), nodeName: "node1"}
+
+	var _ = It("will list the pods to be evicted", func() {
+		cs := fake.NewSimpleClientset(managedPod("du-1", map[string]string{"app": "du"}),
+			managedPod("cu-1", map[string]string{"app": "cu"}), pdb("du-pdb", 1))
+
+		sim, err := dh.simulateDrain(context.TODO(), newDrainer(cs))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sim.Pods).To(Equal([]string{"vran/cu-1", "vran/du-1"}))
+		Expect(sim.Blocked()).To(BeFalse())
+		Expect(sim.String()).To(Equal("2 pods to be evicted"))
+	})
+	var _ = It("will flag the blocking PodDisruptionBudgets", func() {
+		cs := fake.NewSimpleClientset(managedPod("du-1", map[string]string{"app": "du"}),
+			managedPod("du-2", map[string]string{"app": "du"}), pdb("du-pdb", 0))
+
+		sim, err := dh.simulateDrain(context.TODO(), newDrainer(cs))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sim.BlockingPDBs).To(Equal([]string{"vran/du-pdb (2 pods)"}))
+		Expect(sim.Blocked()).To(BeTrue())
+		Expect(sim.String()).To(Equal("blocking PodDisruptionBudgets: vran/du-pdb (2 pods)"))
+
+		// PodDisruptionBudgets don't protect deleted pods
+		drainer := newDrainer(cs)
+		drainer.DisableEviction = true
+		sim, err = dh.simulateDrain(context.TODO(), drainer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sim.Blocked()).To(BeFalse())
+	})
+	var _ = It("will report the pods which can't be deleted", func() {
+		unmanaged := managedPod("test", nil)
+		unmanaged.OwnerReferences = nil
+		cs := fake.NewSimpleClientset(unmanaged)
+
+		sim, err := dh.simulateDrain(context.TODO(), newDrainer(cs))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sim.Pods).To(BeEmpty())
+		Expect(sim.Errors).To(HaveLen(1))
+		Expect(sim.Errors[0]).To(ContainSubstring("vran/test"))
+		Expect(sim.Blocked()).To(BeTrue())
+	})
+})
@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package drainhelper
+
+import (
+	"fmt"
+	"time"
+
+	dhapi "github.com/open-ness/openshift-operator/common/pkg/drainhelper/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The functions below convert the drain settings of the CRDs of the operators to the DrainHelper's ones
+
+// NewHook converts the maintenance hook from the spec to the DrainHelper's one
+func NewHook(h dhapi.MaintenanceHookSpec) (Hook, error) {
+	if (h.HTTP == nil) == (h.Job == nil) {
+		return Hook{}, fmt.Errorf("Invalid drainPolicy hook %s: exactly one of http and job has to be set", h.Name)
+	}
+
+	hook := Hook{
+		Name:          h.Name,
+		Stage:         HookStage(h.Stage),
+		IgnoreFailure: h.FailurePolicy == "Ignore",
+		Job:           h.Job,
+	}
+	switch hook.Stage {
+	case HookBeforeCordon, HookAfterDrain, HookBeforeUncordon, HookAfterUncordon:
+	default:
+		return Hook{}, fmt.Errorf("Invalid drainPolicy hook %s: unknown stage %q", h.Name, h.Stage)
+	}
+	if h.TimeoutSeconds != nil {
+		hook.Timeout = time.Duration(*h.TimeoutSeconds) * time.Second
+	}
+	if h.HTTP != nil {
+		hook.HTTP = &HTTPAction{URL: h.HTTP.URL, Method: h.HTTP.Method, CABundle: h.HTTP.CABundle,
+			ProxyURL: h.HTTP.ProxyURL}
+		if _, err := hook.HTTP.client(); err != nil {
+			return Hook{}, fmt.Errorf("Invalid drainPolicy hook %s: %v", h.Name, err)
+		}
+	}
+	if h.Job != nil {
+		if err := validateJobSpec(h.Job); err != nil {
+			return Hook{}, fmt.Errorf("Invalid drainPolicy hook %s: %v", h.Name, err)
+		}
+	}
+	return hook, nil
+}
+
+// NewDrainPolicy converts the drain policy from the spec to the DrainHelper's one, nil if the spec is nil
+func NewDrainPolicy(p *dhapi.DrainPolicySpec) (*DrainPolicy, error) {
+	if p == nil {
+		return nil, nil
+	}
+
+	policy := &DrainPolicy{
+		DisableEviction: p.DisableEviction,
+		Force:           p.Force,
+	}
+	if p.TimeoutSeconds != nil {
+		policy.Timeout = time.Duration(*p.TimeoutSeconds) * time.Second
+	}
+	if p.GracePeriodSeconds != nil {
+		gracePeriod := int(*p.GracePeriodSeconds)
+		policy.GracePeriodSeconds = &gracePeriod
+	}
+	if p.ExcludePodSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(p.ExcludePodSelector)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid drainPolicy excludePodSelector: %v", err)
+		}
+		policy.ExcludePodSelector = selector
+	}
+	for _, h := range p.Hooks {
+		hook, err := NewHook(h)
+		if err != nil {
+			return nil, err
+		}
+		policy.Hooks = append(policy.Hooks, hook)
+	}
+	return policy, nil
+}
+
+// NewDrainSimulationStatus converts the result of the drain simulation to the status, done at the current time
+func NewDrainSimulationStatus(sim *DrainSimulation) *dhapi.DrainSimulationStatus {
+	return &dhapi.DrainSimulationStatus{
+		Time:         metav1.Now(),
+		Pods:         sim.Pods,
+		BlockingPDBs: sim.BlockingPDBs,
+		Errors:       sim.Errors,
+	}
+}
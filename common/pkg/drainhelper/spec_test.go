@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package drainhelper
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	dhapi "github.com/open-ness/openshift-operator/common/pkg/drainhelper/api"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Drain policy spec", func() {
+	var _ = Describe("NewHook", func() {
+		var _ = It("will convert the hook from the spec", func() {
+			timeout := int64(300)
+			hook, err := NewHook(dhapi.MaintenanceHookSpec{Name: "handover", Stage: "BeforeCordon", TimeoutSeconds: &timeout,
+				HTTP: &dhapi.HTTPHookSpec{URL: "http://du-manager/handover"}, FailurePolicy: "Ignore"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hook).To(Equal(Hook{Name: "handover", Stage: HookBeforeCordon, Timeout: 5 * time.Minute,
+				IgnoreFailure: true, HTTP: &HTTPAction{URL: "http://du-manager/handover"}}))
+		})
+		var _ = It("will return error for the hook without exactly one action", func() {
+			_, err := NewHook(dhapi.MaintenanceHookSpec{Name: "handover", Stage: "BeforeCordon"})
+			Expect(err).To(MatchError("Invalid drainPolicy hook handover: exactly one of http and job has to be set"))
+		})
+		var _ = It("will return error for unknown stage", func() {
+			_, err := NewHook(dhapi.MaintenanceHookSpec{Name: "handover", Stage: "AfterFlash",
+				Job: &batchv1.JobSpec{}})
+			Expect(err).To(MatchError(`Invalid drainPolicy hook handover: unknown stage "AfterFlash"`))
+		})
+		var _ = It("will convert the TLS and proxy settings of the HTTP hook", func() {
+			hook, err := NewHook(dhapi.MaintenanceHookSpec{Name: "handover", Stage: "BeforeCordon",
+				HTTP: &dhapi.HTTPHookSpec{URL: "https://du-manager/handover", ProxyURL: "http://proxy:3128"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hook.HTTP).To(Equal(&HTTPAction{URL: "https://du-manager/handover", ProxyURL: "http://proxy:3128"}))
+
+			_, err = NewHook(dhapi.MaintenanceHookSpec{Name: "handover", Stage: "BeforeCordon",
+				HTTP: &dhapi.HTTPHookSpec{URL: "https://du-manager/handover", CABundle: []byte("not a certificate")}})
+			Expect(err).To(MatchError("Invalid drainPolicy hook handover: no valid certificate in the CA bundle"))
+		})
+		var _ = It("will return error for the Job with a ServiceAccount", func() {
+			_, err := NewHook(dhapi.MaintenanceHookSpec{Name: "handover", Stage: "BeforeCordon",
+				Job: &batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+					ServiceAccountName: "vran-operator"}}}})
+			Expect(err).To(MatchError("Invalid drainPolicy hook handover: serviceAccountName is not allowed"))
+		})
+	})
+
+	var _ = Describe("NewDrainPolicy", func() {
+		var _ = It("will return nil for no policy", func() {
+			p, err := NewDrainPolicy(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p).To(BeNil())
+		})
+		var _ = It("will convert the policy", func() {
+			timeout := int64(120)
+			gracePeriod := int32(30)
+			force := false
+			p, err := NewDrainPolicy(&dhapi.DrainPolicySpec{
+				TimeoutSeconds:     &timeout,
+				GracePeriodSeconds: &gracePeriod,
+				ExcludePodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "critical"}},
+				DisableEviction:    true,
+				Force:              &force,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.Timeout).To(Equal(2 * time.Minute))
+			Expect(*p.GracePeriodSeconds).To(Equal(30))
+			Expect(p.ExcludePodSelector.String()).To(Equal("app=critical"))
+			Expect(p.DisableEviction).To(BeTrue())
+			Expect(*p.Force).To(BeFalse())
+		})
+		var _ = It("will convert the hooks", func() {
+			timeout := int64(300)
+			p, err := NewDrainPolicy(&dhapi.DrainPolicySpec{
+				Hooks: []dhapi.MaintenanceHookSpec{
+					{Name: "handover", Stage: "BeforeCordon", TimeoutSeconds: &timeout,
+						HTTP: &dhapi.HTTPHookSpec{URL: "http://du-manager/handover"}},
+					{Name: "register", Stage: "AfterUncordon", FailurePolicy: "Ignore", Job: &batchv1.JobSpec{}},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.Hooks).To(HaveLen(2))
+			Expect(p.Hooks[0].Stage).To(Equal(HookBeforeCordon))
+			Expect(p.Hooks[0].Timeout).To(Equal(5 * time.Minute))
+			Expect(p.Hooks[0].HTTP.URL).To(Equal("http://du-manager/handover"))
+			Expect(p.Hooks[0].IgnoreFailure).To(BeFalse())
+			Expect(p.Hooks[1].Job).ToNot(BeNil())
+			Expect(p.Hooks[1].IgnoreFailure).To(BeTrue())
+		})
+		var _ = It("will return error for invalid hook", func() {
+			_, err := NewDrainPolicy(&dhapi.DrainPolicySpec{
+				Hooks: []dhapi.MaintenanceHookSpec{{Name: "handover", Stage: "BeforeCordon"}},
+			})
+			Expect(err).To(HaveOccurred())
+			_, err = NewDrainPolicy(&dhapi.DrainPolicySpec{
+				Hooks: []dhapi.MaintenanceHookSpec{{Name: "handover", Stage: "AfterFlash",
+					HTTP: &dhapi.HTTPHookSpec{URL: "http://du-manager/handover"}}},
+			})
+			Expect(err).To(HaveOccurred())
+		})
+		var _ = It("will return error for invalid pod selector", func() {
+			_, err := NewDrainPolicy(&dhapi.DrainPolicySpec{
+				ExcludePodSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: "Unknown"}},
+				},
+			})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	var _ = Describe("NewDrainSimulationStatus", func() {
+		var _ = It("will convert the simulation", func() {
+			before := metav1.Now()
+			status := NewDrainSimulationStatus(&DrainSimulation{
+				Pods:         []string{"default/workload"},
+				BlockingPDBs: []string{"default/workload-pdb"},
+			})
+			Expect(status.Pods).To(Equal([]string{"default/workload"}))
+			Expect(status.BlockingPDBs).To(Equal([]string{"default/workload-pdb"}))
+			Expect(status.Errors).To(BeEmpty())
+			Expect(status.Time.Before(&before)).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,46 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package events
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+// NodeReference returns a reference to the Node in the same form as used by the kubelet,
+// so the events are shown by `kubectl describe node`
+func NodeReference(nodeName string) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		Kind: "Node",
+		Name: nodeName,
+		UID:  types.UID(nodeName),
+	}
+}
+
+// NodeRecorder records events on an object (e.g. a CR) and on the Node the object is related to.
+// All methods of a nil NodeRecorder are no-ops.
+type NodeRecorder struct {
+	recorder record.EventRecorder
+	nodeName string
+}
+
+func NewNodeRecorder(recorder record.EventRecorder, nodeName string) *NodeRecorder {
+	return &NodeRecorder{recorder: recorder, nodeName: nodeName}
+}
+
+// Eventf records the event on the object and on the Node. Event is recorded only on the Node if object is nil.
+func (r *NodeRecorder) Eventf(object runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	if r == nil || r.recorder == nil {
+		return
+	}
+
+	if object != nil {
+		r.recorder.Eventf(object, eventType, reason, messageFmt, args...)
+	}
+	if r.nodeName != "" {
+		r.recorder.Eventf(NodeReference(r.nodeName), eventType, reason, messageFmt, args...)
+	}
+}
@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package events
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestEvents(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "events suite")
+}
+
+var _ = Describe("NodeRecorder", func() {
+	var _ = It("will record the event on the object and on the node", func() {
+		fake := record.NewFakeRecorder(10)
+		r := NewNodeRecorder(fake, "node1")
+
+		r.Eventf(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod"}}, corev1.EventTypeNormal, "Flashed",
+			"FPGA %s flashed", "0000:1b:00.0")
+		r.Eventf(nil, corev1.EventTypeWarning, "DrainFailed", "timeout")
+
+		Expect(fake.Events).To(HaveLen(3))
+		Expect(<-fake.Events).To(Equal("Normal Flashed FPGA 0000:1b:00.0 flashed"))
+		Expect(<-fake.Events).To(Equal("Normal Flashed FPGA 0000:1b:00.0 flashed"))
+		Expect(<-fake.Events).To(Equal("Warning DrainFailed timeout"))
+	})
+	var _ = It("will do nothing for nil recorder", func() {
+		var r *NodeRecorder
+		r.Eventf(nil, corev1.EventTypeNormal, "Flashed", "")
+		NewNodeRecorder(nil, "node1").Eventf(nil, corev1.EventTypeNormal, "Flashed", "")
+	})
+	var _ = It("will return the node reference used by kubelet", func() {
+		ref := NodeReference("node1")
+		Expect(ref.Kind).To(Equal("Node"))
+		Expect(string(ref.UID)).To(Equal("node1"))
+	})
+})
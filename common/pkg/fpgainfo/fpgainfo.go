@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+// Package fpgainfo provides N3000 card inventory and BMC sensor readings.
+// Data is read from the intel-fpga/dfl sysfs and hwmon attributes; the text output
+// of `fpgainfo bmc` is used as a fallback when sysfs does not expose the cards.
+package fpgainfo
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+type SensorType string
+
+const (
+	Temperature SensorType = "Temperature" // Celsius
+	Voltage     SensorType = "Voltage"     // Volts
+	Current     SensorType = "Current"     // Amps
+	Power       SensorType = "Power"       // Watts
+
+	// FPGADieTemperature is a name of the FPGA die temperature sensor
+	FPGADieTemperature = "FPGA Die Temperature"
+
+	// Property names, the same as used by `fpgainfo bmc`
+	PropertyPCIAddr          = "PCIe s:b:d.f"
+	PropertyDeviceID         = "Device Id"
+	PropertyNumaNode         = "Numa Node"
+	PropertyBitstreamID      = "Bitstream Id"
+	PropertyBitstreamVersion = "Bitstream Version"
+)
+
+// Sensor is a single BMC sensor reading
+type Sensor struct {
+	// Name of the sensor, e.g. "FPGA Die Temperature" or "12V AUX Voltage"
+	Name string
+	Type SensorType
+	// Value in the base unit of the Type
+	Value float64
+	// Available is false if the sensor exists, but has no reading (N/A)
+	Available bool
+	// Min and Max are the thresholds of the reading reported by the hwmon driver, nil if not reported
+	Min *float64
+	Max *float64
+}
+
+// Device describes a single N3000 card
+type Device struct {
+	PCIAddr          string
+	DeviceID         string
+	BitstreamID      string
+	BitstreamVersion string
+	NumaNode         int
+	// Properties stores all textual values, e.g. "Bitstream Id", "Numa Node", etc.
+	Properties map[string]string
+	Sensors    []Sensor
+}
+
+// Sensor returns a sensor with the given name
+func (d *Device) Sensor(name string) (Sensor, bool) {
+	for _, s := range d.Sensors {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Sensor{}, false
+}
+
+// Reader obtains the N3000 devices from the sysfs or (as a fallback) from `fpgainfo bmc`
+type Reader struct {
+	// SysfsRoot is a mount point of the sysfs, usually "/sys"
+	SysfsRoot string
+	// FpgaInfoBMC returns an output of `fpgainfo bmc`. Optional.
+	FpgaInfoBMC func() (string, error)
+}
+
+// GetDevices returns all N3000 devices present on the host.
+// The sysfs is used first. If it does not expose any device or a device has no sensors,
+// the output of FpgaInfoBMC is parsed instead.
+func (r *Reader) GetDevices() ([]Device, error) {
+	devs, sysfsErr := readSysfs(r.SysfsRoot)
+	if sysfsErr == nil && len(devs) != 0 && allHaveSensors(devs) {
+		return devs, nil
+	}
+
+	if r.FpgaInfoBMC == nil {
+		if sysfsErr != nil {
+			return nil, sysfsErr
+		}
+		if len(devs) == 0 {
+			return nil, errors.New("no N3000 devices found in sysfs and no fallback configured")
+		}
+		return devs, nil
+	}
+
+	out, err := r.FpgaInfoBMC()
+	if err != nil {
+		return nil, err
+	}
+	return ParseBMCOutput(out), nil
+}
+
+// GetDevice returns the device with the given PCI address
+func (r *Reader) GetDevice(pciAddr string) (*Device, error) {
+	devs, err := r.GetDevices()
+	if err != nil {
+		return nil, err
+	}
+	for i := range devs {
+		if devs[i].PCIAddr == pciAddr {
+			return &devs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("Not found PCIAddr: %s", pciAddr)
+}
+
+func allHaveSensors(devs []Device) bool {
+	for _, d := range devs {
+		if len(d.Sensors) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// newDevice creates a Device and fills its typed fields from properties
+func newDevice(props map[string]string, sensors []Sensor) Device {
+	dev := Device{
+		PCIAddr:          props[PropertyPCIAddr],
+		DeviceID:         props[PropertyDeviceID],
+		BitstreamID:      props[PropertyBitstreamID],
+		BitstreamVersion: props[PropertyBitstreamVersion],
+		Properties:       props,
+		Sensors:          sensors,
+	}
+	dev.NumaNode, _ = strconv.Atoi(props[PropertyNumaNode])
+	return dev
+}
@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package fpgainfo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestFpgaInfo(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "fpgainfo suite")
+}
+
+func writeAttrs(dir string, attrs map[string]string) {
+	Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+	for name, value := range attrs {
+		Expect(ioutil.WriteFile(filepath.Join(dir, name), []byte(value+"\n"), 0644)).To(Succeed())
+	}
+}
+
+func float(f float64) *float64 {
+	return &f
+}
+
+// createFakeSysfs creates a sysfs tree with a single N3000 card (0000:1b:00.0) using the dfl driver
+func createFakeSysfs(root string, withHwmon bool) {
+	pciPath := filepath.Join(root, "devices", "pci0000:17", "0000:17:00.0", "0000:1b:00.0")
+	fmePath := filepath.Join(pciPath, "fpga_region", "region0", "dfl-fme.0")
+	writeAttrs(pciPath, map[string]string{"device": "0x0b30", "numa_node": "1"})
+	writeAttrs(fmePath, map[string]string{"bitstream_id": "0x23000410010309"})
+
+	Expect(os.MkdirAll(filepath.Join(root, "class", "fpga_region"), 0755)).To(Succeed())
+	Expect(os.Symlink(filepath.Join(pciPath, "fpga_region", "region0"),
+		filepath.Join(root, "class", "fpga_region", "region0"))).To(Succeed())
+	Expect(os.MkdirAll(filepath.Join(root, "bus", "pci", "devices"), 0755)).To(Succeed())
+	Expect(os.Symlink(pciPath, filepath.Join(root, "bus", "pci", "devices", "0000:1b:00.0"))).To(Succeed())
+
+	if withHwmon {
+		hwmonPath := filepath.Join(fmePath, "dfl_dev.1", "n3000bmc-hwmon.1.auto", "hwmon", "hwmon3")
+		writeAttrs(hwmonPath, map[string]string{
+			"name":         "n3000bmc_hwmon",
+			"temp1_input":  "61500",
+			"temp1_label":  "FPGA Die Temperature",
+			"in0_input":    "12060",
+			"in0_label":    "12V Backplane Voltage",
+			"in0_min":      "11400",
+			"in0_max":      "12600",
+			"in0_crit":     "13200",
+			"curr1_input":  "2750",
+			"curr1_crit":   "8000",
+			"curr1_label":  "12V Backplane Current",
+			"power1_input": "69240000",
+			"power1_label": "Board Power",
+			"temp2_label":  "QSFP0 Temperature",
+		})
+		Expect(os.MkdirAll(filepath.Join(root, "class", "hwmon"), 0755)).To(Succeed())
+		Expect(os.Symlink(hwmonPath, filepath.Join(root, "class", "hwmon", "hwmon3"))).To(Succeed())
+	}
+}
+
+var _ = Describe("fpgainfo", func() {
+	var (
+		sysfs     string
+		bmcOutput []byte
+	)
+
+	BeforeEach(func() {
+		var err error
+		sysfs, err = ioutil.TempDir("", "fpgainfo_sysfs")
+		Expect(err).ToNot(HaveOccurred())
+		bmcOutput, err = ioutil.ReadFile("testdata/fpgainfo_bmc.txt")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(sysfs)).To(Succeed())
+	})
+
+	var _ = Describe("ParseBMCOutput", func() {
+		var _ = It("will return all devices with their sensors", func() {
+			devs := ParseBMCOutput(string(bmcOutput))
+			Expect(devs).To(HaveLen(2))
+
+			Expect(devs[0].PCIAddr).To(Equal("0000:1b:00.0"))
+			Expect(devs[0].DeviceID).To(Equal("0x0b30"))
+			Expect(devs[0].BitstreamID).To(Equal("0x21000000000000"))
+			Expect(devs[0].BitstreamVersion).To(Equal("1.0.0"))
+			Expect(devs[0].NumaNode).To(Equal(0))
+			Expect(devs[0].Properties).To(HaveKeyWithValue("Object Id", "0xEF00000"))
+			Expect(devs[0].Sensors).To(HaveLen(6))
+
+			s, ok := devs[0].Sensor(FPGADieTemperature)
+			Expect(ok).To(BeTrue())
+			Expect(s).To(Equal(Sensor{Name: FPGADieTemperature, Type: Temperature, Value: 61.5, Available: true}))
+
+			s, ok = devs[0].Sensor("QSFP0 Supply Voltage")
+			Expect(ok).To(BeTrue())
+			Expect(s.Type).To(Equal(Voltage))
+			Expect(s.Available).To(BeFalse())
+
+			Expect(devs[1].PCIAddr).To(Equal("0000:2b:00.0"))
+			Expect(devs[1].NumaNode).To(Equal(1))
+			Expect(devs[1].Sensors).To(HaveLen(2))
+		})
+		var _ = It("will return no devices for an empty output", func() {
+			Expect(ParseBMCOutput("")).To(BeEmpty())
+		})
+	})
+
+	var _ = Describe("Reader", func() {
+		fallback := func() (string, error) {
+			return string(bmcOutput), nil
+		}
+
+		var _ = It("will read the devices from sysfs", func() {
+			createFakeSysfs(sysfs, true)
+			r := Reader{SysfsRoot: sysfs, FpgaInfoBMC: func() (string, error) {
+				return "", fmt.Errorf("fallback should not be used")
+			}}
+
+			devs, err := r.GetDevices()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(devs).To(HaveLen(1))
+			Expect(devs[0].PCIAddr).To(Equal("0000:1b:00.0"))
+			Expect(devs[0].DeviceID).To(Equal("0x0b30"))
+			Expect(devs[0].NumaNode).To(Equal(1))
+			Expect(devs[0].BitstreamID).To(Equal("0x23000410010309"))
+			Expect(devs[0].BitstreamVersion).To(Equal("0.2.3"))
+			Expect(devs[0].Sensors).To(ConsistOf(
+				Sensor{Name: "12V Backplane Current", Type: Current, Value: 2.75, Available: true, Max: float(8)},
+				Sensor{Name: "12V Backplane Voltage", Type: Voltage, Value: 12.06, Available: true,
+					Min: float(11.4), Max: float(12.6)},
+				Sensor{Name: "Board Power", Type: Power, Value: 69.24, Available: true},
+				Sensor{Name: FPGADieTemperature, Type: Temperature, Value: 61.5, Available: true},
+			))
+		})
+		var _ = It("will fall back to fpgainfo when sysfs has no devices", func() {
+			r := Reader{SysfsRoot: sysfs, FpgaInfoBMC: fallback}
+			devs, err := r.GetDevices()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(devs).To(HaveLen(2))
+		})
+		var _ = It("will fall back to fpgainfo when sysfs has no sensors", func() {
+			createFakeSysfs(sysfs, false)
+			r := Reader{SysfsRoot: sysfs, FpgaInfoBMC: fallback}
+			devs, err := r.GetDevices()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(devs).To(HaveLen(2))
+		})
+		var _ = It("will return an error when fallback fails", func() {
+			r := Reader{SysfsRoot: sysfs, FpgaInfoBMC: func() (string, error) {
+				return "", fmt.Errorf("error")
+			}}
+			_, err := r.GetDevices()
+			Expect(err).To(HaveOccurred())
+		})
+		var _ = It("will return an error when there is no device and no fallback", func() {
+			r := Reader{SysfsRoot: sysfs}
+			_, err := r.GetDevices()
+			Expect(err).To(HaveOccurred())
+		})
+		var _ = It("will return a device by PCI address", func() {
+			r := Reader{SysfsRoot: sysfs, FpgaInfoBMC: fallback}
+			d, err := r.GetDevice("0000:2b:00.0")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(d.BitstreamID).To(Equal("0x32000000000000"))
+
+			_, err = r.GetDevice("0000:xx:00.0")
+			Expect(err).To(MatchError("Not found PCIAddr: 0000:xx:00.0"))
+		})
+	})
+})
@@ -0,0 +1,190 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package fpgainfo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// FME (FPGA Management Engine) directories exposed by the drivers
+	fmeGlobs = []string{
+		"class/fpga/intel-fpga-dev.*/intel-fpga-fme.*", // intel-fpga (OPAE) driver
+		"class/fpga_region/region*/dfl-fme.*",          // upstream dfl driver
+	}
+	hwmonGlob = "class/hwmon/hwmon*"
+
+	pciAddrRegex    = regexp.MustCompile(`[a-fA-F0-9]{4}:[a-fA-F0-9]{2}:[01][a-fA-F0-9]\.[0-7]`)
+	hwmonInputRegex = regexp.MustCompile(`^(temp|in|curr|power)([0-9]+)_input$`)
+
+	// hwmon attributes are reported in milli-units (micro-watts for power)
+	hwmonTypes = map[string]struct {
+		t       SensorType
+		divisor float64
+	}{
+		"temp":  {Temperature, 1000},
+		"in":    {Voltage, 1000},
+		"curr":  {Current, 1000},
+		"power": {Power, 1000000},
+	}
+	// hwmon threshold attributes, in order of preference
+	hwmonMinAttrs = []string{"_min", "_lcrit"}
+	hwmonMaxAttrs = []string{"_max", "_crit"}
+)
+
+func readAttr(dir, name string) (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// readSysfs returns N3000 devices found in the sysfs mounted at root
+func readSysfs(root string) ([]Device, error) {
+	if root == "" {
+		root = "/sys"
+	}
+
+	var fmes []string
+	for _, g := range fmeGlobs {
+		m, err := filepath.Glob(filepath.Join(root, g))
+		if err != nil {
+			return nil, err
+		}
+		fmes = append(fmes, m...)
+	}
+	if len(fmes) == 0 {
+		return nil, nil
+	}
+
+	hwmons, err := filepath.Glob(filepath.Join(root, hwmonGlob))
+	if err != nil {
+		return nil, err
+	}
+
+	var devs []Device
+	seen := map[string]bool{}
+	for _, fme := range fmes {
+		realPath, err := filepath.EvalSymlinks(fme)
+		if err != nil {
+			return nil, err
+		}
+		// FME is placed under the PCI device of the card - the last PCI address in the path
+		addrs := pciAddrRegex.FindAllString(realPath, -1)
+		if len(addrs) == 0 {
+			continue
+		}
+		pci := addrs[len(addrs)-1]
+		if seen[pci] {
+			continue
+		}
+		seen[pci] = true
+
+		props := map[string]string{PropertyPCIAddr: pci}
+		pciDir := filepath.Join(root, "bus", "pci", "devices", pci)
+		if v, err := readAttr(pciDir, "device"); err == nil {
+			props[PropertyDeviceID] = v
+		}
+		if v, err := readAttr(pciDir, "numa_node"); err == nil {
+			props[PropertyNumaNode] = v
+		}
+		if v, err := readAttr(fme, "bitstream_id"); err == nil {
+			id, err := strconv.ParseUint(v, 0, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid bitstream_id %q in %s: %v", v, fme, err)
+			}
+			props[PropertyBitstreamID] = fmt.Sprintf("0x%X", id)
+			props[PropertyBitstreamVersion] = fmt.Sprintf("%d.%d.%d", id>>56&0xf, id>>52&0xf, id>>48&0xf)
+		}
+
+		var sensors []Sensor
+		for _, hwmon := range hwmons {
+			hwmonPath, err := filepath.EvalSymlinks(hwmon)
+			if err != nil || !strings.Contains(hwmonPath, "/"+pci+"/") {
+				continue
+			}
+			s, err := readHwmonSensors(hwmon)
+			if err != nil {
+				return nil, err
+			}
+			sensors = appendSensors(sensors, s)
+		}
+
+		devs = append(devs, newDevice(props, sensors))
+	}
+
+	return devs, nil
+}
+
+// readHwmonValue returns the first of the attributes of the sensor, which has a valid reading, in the base unit
+func readHwmonValue(dir, sensor string, attrs []string, divisor float64) (float64, bool) {
+	for _, attr := range attrs {
+		v, err := readAttr(dir, sensor+attr)
+		if err != nil {
+			continue
+		}
+		if raw, err := strconv.ParseFloat(v, 64); err == nil {
+			return raw / divisor, true
+		}
+	}
+	return 0, false
+}
+
+// readHwmonSensors reads all temperature, voltage, current and power inputs of a hwmon device
+// together with their thresholds
+func readHwmonSensors(dir string) ([]Sensor, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var sensors []Sensor
+	for _, f := range files {
+		m := hwmonInputRegex.FindStringSubmatch(f.Name())
+		if len(m) != 3 {
+			continue
+		}
+		ht := hwmonTypes[m[1]]
+
+		sensor := m[1] + m[2]
+		name, err := readAttr(dir, sensor+"_label")
+		if err != nil {
+			name = sensor
+		}
+		s := Sensor{Name: name, Type: ht.t}
+
+		s.Value, s.Available = readHwmonValue(dir, sensor, []string{"_input"}, ht.divisor)
+		if min, ok := readHwmonValue(dir, sensor, hwmonMinAttrs, ht.divisor); ok {
+			s.Min = &min
+		}
+		if max, ok := readHwmonValue(dir, sensor, hwmonMaxAttrs, ht.divisor); ok {
+			s.Max = &max
+		}
+		sensors = append(sensors, s)
+	}
+	return sensors, nil
+}
+
+// appendSensors appends sensors which names are not already present in the list
+func appendSensors(list []Sensor, sensors []Sensor) []Sensor {
+	for _, s := range sensors {
+		found := false
+		for _, l := range list {
+			if l.Name == s.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, s)
+		}
+	}
+	return list
+}
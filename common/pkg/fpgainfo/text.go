@@ -0,0 +1,77 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package fpgainfo
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	bmcSensorsBanner = "//****** BMC SENSORS ******//"
+)
+
+var (
+	bmcPropertyRegex = regexp.MustCompile(`^([a-zA-Z .:]+?)(?:\s*:\s)(.+)$`)
+	bmcSensorRegex   = regexp.MustCompile(`^\(\s*[0-9]*\)\s+(.+?)(?:\s*:\s*)(.+)$`)
+)
+
+// ParseBMCOutput parses an output of `fpgainfo bmc` command.
+// Devices without PCI address are skipped.
+func ParseBMCOutput(output string) []Device {
+	var devs []Device
+	for _, deviceBMCOutput := range strings.Split(output, bmcSensorsBanner) {
+		props := map[string]string{}
+		var sensors []Sensor
+		for _, line := range strings.Split(deviceBMCOutput, "\n") {
+			line = strings.TrimRight(line, "\r")
+			if m := bmcSensorRegex.FindStringSubmatch(line); len(m) == 3 {
+				if s, ok := parseSensor(m[1], m[2]); ok {
+					sensors = append(sensors, s)
+				}
+				continue
+			}
+			if m := bmcPropertyRegex.FindStringSubmatch(line); len(m) == 3 {
+				props[m[1]] = m[2]
+			}
+		}
+		if _, ok := props[PropertyPCIAddr]; ok {
+			devs = append(devs, newDevice(props, sensors))
+		}
+	}
+	return devs
+}
+
+// parseSensor converts the sensor's name and value (e.g. "61.50 Celsius" or "N/A") into a Sensor.
+// Type is deduced from the last word of the name, e.g. "FPGA Die Temperature".
+func parseSensor(name, value string) (Sensor, bool) {
+	words := strings.Fields(name)
+	if len(words) == 0 {
+		return Sensor{}, false
+	}
+
+	s := Sensor{Name: strings.Join(words, " ")}
+	switch SensorType(words[len(words)-1]) {
+	case Temperature, Voltage, Current, Power:
+		s.Type = SensorType(words[len(words)-1])
+	default:
+		return Sensor{}, false
+	}
+
+	if value == "N/A" {
+		return s, true
+	}
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return Sensor{}, false
+	}
+	f, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return Sensor{}, false
+	}
+	s.Value = f
+	s.Available = true
+	return s, true
+}
@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package statuswriter
+
+import (
+	"context"
+	"reflect"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// StatusWriter updates the status subresource of the objects with merge patches. The patch contains only
+// the fields changed by the caller, so the other fields (e.g. conditions of other types) are preserved.
+type StatusWriter struct {
+	client client.Client
+}
+
+func NewStatusWriter(c client.Client) *StatusWriter {
+	return &StatusWriter{client: c}
+}
+
+// Update calls mutate on obj and patches the status with the changes. The patch is rejected if obj is
+// outdated (its resourceVersion doesn't match) - then the latest version of the object is read, mutate
+// is called on it and the patch is retried. mutate is given the object to be changed (obj or its latest
+// version) and can be called multiple times, so it should only set the status fields it's responsible for.
+//
+// obj is updated with the result of the successful patch, unless the patch had to be retried.
+// Then obj keeps its spec (and generation) and resourceVersion, so the caller can detect the change.
+func (w *StatusWriter) Update(ctx context.Context, obj client.Object, mutate func(obj client.Object)) error {
+	current := obj
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		base := current.DeepCopyObject().(client.Object)
+		mutate(current)
+		// object which was not read from the API server can't be checked
+		var opts []client.MergeFromOption
+		if current.GetResourceVersion() != "" {
+			opts = append(opts, client.MergeFromWithOptimisticLock{})
+		}
+		err := w.client.Status().Patch(ctx, current, client.MergeFromWithOptions(base, opts...))
+		if !apierrors.IsConflict(err) {
+			return err
+		}
+
+		// read into an empty object, the fields missing in the response must not keep the mutated values
+		latest := reflect.New(reflect.TypeOf(obj).Elem()).Interface().(client.Object)
+		if getErr := w.client.Get(ctx, client.ObjectKeyFromObject(obj), latest); getErr != nil {
+			return getErr
+		}
+		current = latest
+		return err
+	})
+}
+
+// SetConditions sets the conditions in the list, the conditions of other types are kept
+func SetConditions(conditions *[]metav1.Condition, c ...metav1.Condition) {
+	for _, condition := range c {
+		meta.SetStatusCondition(conditions, condition)
+	}
+}
+
+// ProgressCondition returns the condition of the type with status False, reason and msg, which doesn't mark
+// the generation as observed - the observed generation of the current condition of the type is kept, so
+// the spec is handled again if the operation is interrupted (e.g. the daemon is restarted) before it finishes
+func ProgressCondition(conditions []metav1.Condition, conditionType, reason, msg string) metav1.Condition {
+	c := metav1.Condition{
+		Type:    conditionType,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: msg,
+	}
+	if current := meta.FindStatusCondition(conditions, conditionType); current != nil {
+		c.ObservedGeneration = current.ObservedGeneration
+	}
+	return c
+}
+
+// GenerationChanged returns true if the object was updated (its generation changed) or removed since obj
+// was read. Other errors of the read are treated as no change.
+func GenerationChanged(ctx context.Context, c client.Client, obj client.Object) bool {
+	current := reflect.New(reflect.TypeOf(obj).Elem()).Interface().(client.Object)
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), current); err != nil {
+		return apierrors.IsNotFound(err)
+	}
+	return current.GetGeneration() != obj.GetGeneration()
+}
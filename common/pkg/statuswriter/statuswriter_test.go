@@ -0,0 +1,105 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package statuswriter
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestStatusWriter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "statuswriter suite")
+}
+
+var _ = Describe("StatusWriter", func() {
+	var c client.Client
+	var pod *corev1.Pod
+	key := client.ObjectKey{Namespace: "default", Name: "pod"}
+
+	setMessage := func(msg string) func(client.Object) {
+		return func(obj client.Object) {
+			obj.(*corev1.Pod).Status.Message = msg
+		}
+	}
+
+	BeforeEach(func() {
+		c = fake.NewClientBuilder().Build()
+		Expect(c.Create(context.TODO(), &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod"},
+			Status:     corev1.PodStatus{PodIP: "10.0.0.1"},
+		})).To(Succeed())
+		pod = &corev1.Pod{}
+		Expect(c.Get(context.TODO(), key, pod)).To(Succeed())
+	})
+
+	var _ = It("will patch only the changed fields", func() {
+		pod.Status.PodIP = ""
+		Expect(NewStatusWriter(c).Update(context.TODO(), pod, setMessage("configured"))).To(Succeed())
+
+		current := &corev1.Pod{}
+		Expect(c.Get(context.TODO(), key, current)).To(Succeed())
+		Expect(current.Status.Message).To(Equal("configured"))
+		Expect(current.Status.PodIP).To(Equal("10.0.0.1"))
+		Expect(pod.ResourceVersion).To(Equal(current.ResourceVersion))
+	})
+	var _ = It("will retry the patch of outdated object", func() {
+		other := pod.DeepCopy()
+		other.Status.Reason = "Evicted"
+		Expect(c.Status().Update(context.TODO(), other)).To(Succeed())
+
+		calls := 0
+		Expect(NewStatusWriter(c).Update(context.TODO(), pod, func(obj client.Object) {
+			calls++
+			setMessage("configured")(obj)
+		})).To(Succeed())
+		Expect(calls).To(Equal(2))
+
+		current := &corev1.Pod{}
+		Expect(c.Get(context.TODO(), key, current)).To(Succeed())
+		Expect(current.Status.Message).To(Equal("configured"))
+		Expect(current.Status.Reason).To(Equal("Evicted"))
+		Expect(pod.ResourceVersion).ToNot(Equal(current.ResourceVersion))
+	})
+	var _ = It("will return error of missing object", func() {
+		Expect(c.Delete(context.TODO(), pod.DeepCopy())).To(Succeed())
+		Expect(NewStatusWriter(c).Update(context.TODO(), pod, setMessage("configured"))).ToNot(Succeed())
+	})
+	var _ = It("will keep the conditions of other types", func() {
+		conditions := []metav1.Condition{
+			{Type: "Configured", Status: metav1.ConditionFalse, Reason: "InProgress"},
+			{Type: "Drifted", Status: metav1.ConditionFalse, Reason: "NotDetected"},
+		}
+		SetConditions(&conditions, metav1.Condition{Type: "Configured", Status: metav1.ConditionTrue, Reason: "Succeeded"})
+		Expect(conditions).To(HaveLen(2))
+		Expect(conditions[0].Reason).To(Equal("Succeeded"))
+		Expect(conditions[1].Reason).To(Equal("NotDetected"))
+	})
+	var _ = It("will keep the observed generation of the progress condition", func() {
+		conditions := []metav1.Condition{
+			{Type: "Configured", Status: metav1.ConditionTrue, Reason: "Succeeded", ObservedGeneration: 3},
+		}
+		Expect(ProgressCondition(conditions, "Configured", "InProgress", "Configuration started")).To(Equal(
+			metav1.Condition{Type: "Configured", Status: metav1.ConditionFalse, Reason: "InProgress",
+				Message: "Configuration started", ObservedGeneration: 3}))
+		Expect(ProgressCondition(nil, "Configured", "InProgress", "").ObservedGeneration).To(BeZero())
+	})
+	var _ = It("will detect the changed generation", func() {
+		Expect(GenerationChanged(context.TODO(), c, pod)).To(BeFalse())
+
+		outdated := pod.DeepCopy()
+		outdated.Generation = pod.Generation + 1
+		Expect(GenerationChanged(context.TODO(), c, outdated)).To(BeTrue())
+
+		Expect(c.Delete(context.TODO(), pod.DeepCopy())).To(Succeed())
+		Expect(GenerationChanged(context.TODO(), c, pod)).To(BeTrue())
+	})
+})
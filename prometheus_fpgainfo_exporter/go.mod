@@ -6,6 +6,9 @@ require (
 	github.com/nxadm/tail v1.4.8 // indirect
 	github.com/onsi/ginkgo v1.15.0
 	github.com/onsi/gomega v1.10.5
+	github.com/open-ness/openshift-operator/common v0.0.0-20210331133825-661f430b5d9f
 	github.com/prometheus/client_golang v1.7.1
 	golang.org/x/sys v0.0.0-20210225091947-4ada9433c6ea // indirect
 )
+
+replace github.com/open-ness/openshift-operator/common => ../common
@@ -9,20 +9,16 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
-	"regexp"
-	"strconv"
 	"strings"
 
+	"github.com/open-ness/openshift-operator/common/pkg/fpgainfo"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 var (
-	bmcFloatRegex       = regexp.MustCompile(`^\(\s*[0-9]*\)\s+(.+?)(?:\s*:\s*)(.+)$`)
-	bmcQualitativeRegex = regexp.MustCompile(`^([a-zA-Z .:]+?)(?:\s*:\s)(.+)$`)
-	pciRegex            = regexp.MustCompile(`[a-fA-F0-9]{4}:[a-fA-F0-9]{2}:[01][a-fA-F0-9]\.[0-7]`)
-
 	fpgaInfoPath = "fpgainfo"
+	sysfsRoot    = "/sys"
 	address      = ":42222"
 
 	fpgaInfoExec = runFpgaInfo
@@ -40,7 +36,7 @@ func runFpgaInfo(command string) (string, error) {
 	return string(output), nil
 }
 
-// DeviceBMCInfo contains various metrics obtained from `fpgainfo bmc` grouped by unit families.
+// DeviceBMCInfo contains various metrics obtained from the BMC grouped by unit families.
 type DeviceBMCInfo struct {
 	// Qualitative stores textual values such as "Object ID", "Bitstream ID", etc.
 	Qualitative map[string]string
@@ -59,25 +55,9 @@ func sanitizeLabel(in string) string {
 	return r.Replace(strings.ToLower(in))
 }
 
-func extractUnit(in string) (string, string) {
-	split := strings.Split(in, " ")
-	value := strings.Join(split[:len(split)-1], " ")
-	unit := split[len(split)-1]
-
-	return value, unit
-}
-
-func extractFloatValue(in string) (float64, error) {
-	if in == "N/A" {
-		return -1, nil
-	}
-	val, _ := extractUnit(in)
-	return strconv.ParseFloat(val, 64)
-}
-
-// NewDeviceBMCInfo parses given string and returns a DeviceBMCInfo.
-// Given `output` string must contain data only for one device.
-func NewDeviceBMCInfo(output string) *DeviceBMCInfo {
+// NewDeviceBMCInfo groups properties and sensors of the given device by unit families.
+// Sensors without reading ('N/A') are converted into -1.
+func NewDeviceBMCInfo(device *fpgainfo.Device) *DeviceBMCInfo {
 	devBMC := &DeviceBMCInfo{
 		Qualitative:  map[string]string{},
 		Temperatures: map[string]float64{},
@@ -86,37 +66,29 @@ func NewDeviceBMCInfo(output string) *DeviceBMCInfo {
 		Powers:       map[string]float64{},
 	}
 
-	for _, line := range strings.Split(output, "\n") {
-		textMatches := bmcQualitativeRegex.FindStringSubmatch(line)
-		if len(textMatches) == 3 {
-			label := sanitizeLabel(textMatches[1])
-			value := textMatches[2]
-
-			if !strings.Contains(label, "pci") {
-				devBMC.Qualitative[label] = value
-			}
+	for name, value := range device.Properties {
+		label := sanitizeLabel(name)
+		if !strings.Contains(label, "pci") {
+			devBMC.Qualitative[label] = value
 		}
+	}
 
-		floatMatches := bmcFloatRegex.FindStringSubmatch(line)
-		if len(floatMatches) == 3 {
-			name, unitFamily := extractUnit(floatMatches[1])
-
-			label := sanitizeLabel(name)
-			floatVal, err := extractFloatValue(floatMatches[2])
-			if err != nil {
-				log.Printf("Failed to convert string (%s) to float. Error: %+v", floatMatches[2], err)
-				continue
-			}
+	for _, sensor := range device.Sensors {
+		label := sanitizeLabel(strings.TrimSuffix(sensor.Name, " "+string(sensor.Type)))
+		value := sensor.Value
+		if !sensor.Available {
+			value = -1
+		}
 
-			if unitFamily == "Power" {
-				devBMC.Powers[label] = floatVal
-			} else if unitFamily == "Voltage" {
-				devBMC.Voltages[label] = floatVal
-			} else if unitFamily == "Current" {
-				devBMC.Currents[label] = floatVal
-			} else if unitFamily == "Temperature" {
-				devBMC.Temperatures[label] = floatVal
-			}
+		switch sensor.Type {
+		case fpgainfo.Power:
+			devBMC.Powers[label] = value
+		case fpgainfo.Voltage:
+			devBMC.Voltages[label] = value
+		case fpgainfo.Current:
+			devBMC.Currents[label] = value
+		case fpgainfo.Temperature:
+			devBMC.Temperatures[label] = value
 		}
 	}
 
@@ -131,11 +103,17 @@ type BMCInfo struct {
 	Devices map[PCIAddress]*DeviceBMCInfo
 }
 
-// GetBMCInfo obtains and parses output of `fpgainfo bmc` command
+// GetBMCInfo obtains BMC information from the sysfs or, as a fallback, from `fpgainfo bmc` command
 func GetBMCInfo() (*BMCInfo, error) {
-	fpgaInfoBMCOutput, err := fpgaInfoExec("bmc")
+	reader := fpgainfo.Reader{
+		SysfsRoot: sysfsRoot,
+		FpgaInfoBMC: func() (string, error) {
+			return fpgaInfoExec("bmc")
+		},
+	}
+	devices, err := reader.GetDevices()
 	if err != nil {
-		log.Printf("GetBMCInfo(): Failed to get output from fpgainfo: %+v", err)
+		log.Printf("GetBMCInfo(): Failed to get devices: %+v", err)
 		return nil, err
 	}
 
@@ -143,14 +121,8 @@ func GetBMCInfo() (*BMCInfo, error) {
 		Devices: map[PCIAddress]*DeviceBMCInfo{},
 	}
 
-	for _, deviceBMCOutput := range strings.Split(fpgaInfoBMCOutput, "Board Management Controller") {
-		pciMatches := pciRegex.FindStringSubmatch(deviceBMCOutput)
-		if len(pciMatches) == 0 {
-			continue
-		}
-		pci := PCIAddress(pciMatches[0])
-
-		bmcInfo.Devices[pci] = NewDeviceBMCInfo(deviceBMCOutput)
+	for i := range devices {
+		bmcInfo.Devices[PCIAddress(devices[i].PCIAddr)] = NewDeviceBMCInfo(&devices[i])
 	}
 
 	return bmcInfo, nil
@@ -284,18 +256,19 @@ func (c *FpgaInfoCollector) Collect(ch chan<- prometheus.Metric) {
 
 func init() {
 	flag.StringVar(&fpgaInfoPath, "fpgainfo", "fpgainfo", "Path to fpgainfo utility")
+	flag.StringVar(&sysfsRoot, "sysfs", "/sys", "Path to the sysfs mount point")
 	flag.StringVar(&address, "address", ":42222", "Address on which metrics are exposed")
 }
 
 func main() {
 	flag.Parse()
 
-	_, err := fpgaInfoExec("bmc")
+	_, err := GetBMCInfo()
 	if err != nil {
-		log.Fatalf("fpgainfo bmc failed to run: %+v. Exporter will exit", err)
+		log.Fatalf("Failed to obtain BMC info: %+v. Exporter will exit", err)
 	}
 
-	log.Printf("fpgaInfoPath: %s", fpgaInfoPath)
+	log.Printf("fpgaInfoPath: %s, sysfs: %s", fpgaInfoPath, sysfsRoot)
 
 	prometheus.MustRegister(NewFpgaInfoCollector())
 
@@ -153,6 +153,8 @@ var _ = Describe("fpgainfo", func() {
 	var _ = Context("Collect ", func() {
 		BeforeEach(func() {
 			fakeFpgaInfoError = nil
+			// no devices in sysfs - output of fpgainfo is used
+			sysfsRoot = "/tmp/fpgainfo_exporter_no_sysfs"
 		})
 
 		var _ = It("will collect valid fpgainfo ", func() {
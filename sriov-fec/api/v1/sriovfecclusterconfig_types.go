@@ -6,6 +6,7 @@ package v1
 import (
 	"fmt"
 
+	dhapi "github.com/open-ness/openshift-operator/common/pkg/drainhelper/api"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -20,6 +21,19 @@ var (
 	FailedSync SyncStatus = "Failed"
 	// IgnoredSync indicates that the CR is ignored
 	IgnoredSync SyncStatus = "Ignored"
+	// ShadowedSync indicates that all the PFs selected by the CR are configured by CRs with higher precedence
+	ShadowedSync SyncStatus = "Shadowed"
+)
+
+const (
+	// MaxN3000Queues is the maximum number of the queues of N3000 per direction (uplink, downlink),
+	// shared by all the VFs
+	MaxN3000Queues = 32
+	// MaxACC100QueueGroups is the maximum number of the queue groups of ACC100 (4G and 5G, uplink and downlink)
+	MaxACC100QueueGroups = 8
+	// MaxACC200QueueGroups is the maximum number of the queue groups of ACC200 (4G and 5G, uplink and downlink,
+	// and FFT)
+	MaxACC200QueueGroups = 16
 )
 
 func (udq *UplinkDownlinkQueues) String() string {
@@ -27,6 +41,15 @@ func (udq *UplinkDownlinkQueues) String() string {
 		udq.VF4, udq.VF5, udq.VF6, udq.VF7)
 }
 
+// VF returns the number of the queues of the VF with the index, nil if the index is out of range
+func (udq *UplinkDownlinkQueues) VF(index int) *int {
+	vfs := []*int{&udq.VF0, &udq.VF1, &udq.VF2, &udq.VF3, &udq.VF4, &udq.VF5, &udq.VF6, &udq.VF7}
+	if index < 0 || index >= len(vfs) {
+		return nil
+	}
+	return vfs[index]
+}
+
 type UplinkDownlinkQueues struct {
 	// +kubebuilder:validation:Minimum=0
 	// +kubebuilder:validation:Maximum=32
@@ -100,17 +123,54 @@ type ACC100BBDevConfig struct {
 	Downlink5G   QueueGroupConfig `json:"downlink5G"`
 }
 
+// ACC200BBDevConfig specifies variables to configure ACC200 (vRAN Boost) with
+type ACC200BBDevConfig struct {
+	PFMode bool `json:"pfMode"`
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=16
+	NumVfBundles int `json:"numVfBundles"`
+	// +kubebuilder:validation:Minimum=1024
+	// +kubebuilder:validation:Maximum=1024
+	MaxQueueSize int              `json:"maxQueueSize"`
+	Uplink4G     QueueGroupConfig `json:"uplink4G"`
+	Downlink4G   QueueGroupConfig `json:"downlink4G"`
+	Uplink5G     QueueGroupConfig `json:"uplink5G"`
+	Downlink5G   QueueGroupConfig `json:"downlink5G"`
+	// QFFT is the config of the queue groups of the FFT engine
+	QFFT QueueGroupConfig `json:"qfft"`
+}
+
 // BBDevConfig is a struct containing configuration for various FEC cards
 type BBDevConfig struct {
 	N3000  *N3000BBDevConfig  `json:"n3000,omitempty"`
 	ACC100 *ACC100BBDevConfig `json:"acc100,omitempty"`
+	ACC200 *ACC200BBDevConfig `json:"acc200,omitempty"`
+}
+
+// AcceleratorSelector selects the Physical Functions reported in the inventory of the node.
+// All the set fields have to match, an empty selector matches any PF.
+type AcceleratorSelector struct {
+	// Vendor ID of the PF, e.g. 8086
+	// +kubebuilder:validation:Pattern=`^[a-fA-F0-9]{4}$`
+	VendorID string `json:"vendorID,omitempty"`
+	// Device ID of the PF, e.g. 0d5c
+	// +kubebuilder:validation:Pattern=`^[a-fA-F0-9]{4}$`
+	DeviceID string `json:"deviceID,omitempty"`
+	// Name of the device from the supported accelerators config (accelerators.json)
+	// +kubebuilder:validation:Enum=ACC100;ACC200;FPGA_5GNR;FPGA_LTE
+	DeviceName string `json:"deviceName,omitempty"`
 }
 
 // PhysicalFunctionConfig defines a possible configuration of a single Physical Function (PF), i.e. card
 type PhysicalFunctionConfig struct {
-	// PCIAdress is a Physical Functions's PCI address that will be configured according to this spec
+	// PCIAdress is a Physical Functions's PCI address that will be configured according to this spec.
+	// Required in SriovFecNodeConfig, in SriovFecClusterConfig either pciAddress or acceleratorSelector has to be set.
 	// +kubebuilder:validation:Pattern=`^[a-fA-F0-9]{4}:[a-fA-F0-9]{2}:[01][a-fA-F0-9]\.[0-7]$`
-	PCIAddress string `json:"pciAddress"`
+	// +optional
+	PCIAddress string `json:"pciAddress,omitempty"`
+	// AcceleratorSelector selects the PFs from the node's inventory that will be configured according to this spec.
+	// Used only in SriovFecClusterConfig if pciAddress is not set.
+	AcceleratorSelector *AcceleratorSelector `json:"acceleratorSelector,omitempty"`
 	// PFDriver to bound the PFs to
 	PFDriver string `json:"pfDriver"`
 	// VFDriver to bound the VFs to
@@ -120,11 +180,76 @@ type PhysicalFunctionConfig struct {
 	VFAmount int `json:"vfAmount"`
 	// BBDevConfig is a config for PF's queues
 	BBDevConfig BBDevConfig `json:"bbDevConfig"`
+	// VFs overrides the config of the PF for the individual VFs
+	// +optional
+	VFs []VFConfig `json:"vfs,omitempty"`
+}
+
+// VFConfig overrides the config of the PF for a single VF
+type VFConfig struct {
+	// Index of the VF, from 0 to vfAmount-1. The VFs are indexed in the order of their PCI addresses.
+	// +kubebuilder:validation:Minimum=0
+	Index int `json:"index"`
+	// Driver to bound the VF to, overrides the vfDriver of the PF. Besides the vfDrivers also the kernel drivers
+	// uio_pci_generic and pci-pf-stub are allowed.
+	// +optional
+	Driver string `json:"driver,omitempty"`
+	// ResourceName of the device plugin the VF is advertised by, instead of the default resource of the device
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9_]+$`
+	// +optional
+	ResourceName string `json:"resourceName,omitempty"`
+	// Queues of the VF, override the queues of the VF in the bbDevConfig (N3000 only). ACC100 and ACC200 split
+	// their queue groups evenly among numVfBundles VFs, so the per-VF queues are rejected for them.
+	// +optional
+	Queues *VFQueues `json:"queues,omitempty"`
+}
+
+// VFQueues specifies the number of the queues of a VF
+type VFQueues struct {
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=32
+	Uplink int `json:"uplink"`
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=32
+	Downlink int `json:"downlink"`
+}
+
+// GetVFDriver returns the driver of the VF with the index, the vfDriver of the PF unless overridden
+func (pf *PhysicalFunctionConfig) GetVFDriver(index int) string {
+	for _, vf := range pf.VFs {
+		if vf.Index == index && vf.Driver != "" {
+			return vf.Driver
+		}
+	}
+	return pf.VFDriver
+}
+
+// GetBBDevConfig returns the BBDevConfig with the queues of the VFs overridden by the per-VF configs
+func (pf *PhysicalFunctionConfig) GetBBDevConfig() BBDevConfig {
+	cfg := pf.BBDevConfig.DeepCopy()
+	if cfg.N3000 == nil {
+		return *cfg
+	}
+	for _, vf := range pf.VFs {
+		if vf.Queues == nil {
+			continue
+		}
+		if q := cfg.N3000.Uplink.Queues.VF(vf.Index); q != nil {
+			*q = vf.Queues.Uplink
+		}
+		if q := cfg.N3000.Downlink.Queues.VF(vf.Index); q != nil {
+			*q = vf.Queues.Downlink
+		}
+	}
+	return *cfg
 }
 
 type NodeConfig struct {
-	// Name of the node
-	NodeName string `json:"nodeName"`
+	// Name of the node, either nodeName or nodeSelector has to be set
+	NodeName string `json:"nodeName,omitempty"`
+	// Selector of the labels of the nodes to be configured, used only if nodeName is not set. An empty selector
+	// selects all the nodes with an accelerator.
+	NodeSelector *metav1.LabelSelector `json:"nodeSelector,omitempty"`
 	// List of physical functions (cards) configs
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	PhysicalFunctions []PhysicalFunctionConfig `json:"physicalFunctions"`
@@ -134,8 +259,15 @@ type NodeConfig struct {
 type SriovFecClusterConfigSpec struct {
 	// List of node configurations
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
-	Nodes     []NodeConfig `json:"nodes"`
-	DrainSkip bool         `json:"drainSkip,omitempty"`
+	Nodes []NodeConfig `json:"nodes"`
+	// Priority of the policy, the lower value takes precedence. A PF selected by several SriovFecClusterConfigs
+	// is configured by the one with the lowest priority value (and name, if the priorities are equal).
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=99
+	Priority  int  `json:"priority,omitempty"`
+	DrainSkip bool `json:"drainSkip,omitempty"`
+	// Drain settings, used unless drainSkip is set
+	DrainPolicy *dhapi.DrainPolicySpec `json:"drainPolicy,omitempty"`
 }
 
 // SriovFecClusterConfigStatus defines the observed state of SriovFecClusterConfig
@@ -144,10 +276,31 @@ type SriovFecClusterConfigStatus struct {
 	// +operator-sdk:csv:customresourcedefinitions:type=status
 	SyncStatus    SyncStatus `json:"syncStatus,omitempty"`
 	LastSyncError string     `json:"lastSyncError,omitempty"`
+	// PFs selected by the CR, but configured by the CRs with higher precedence, as <node>/<pci>: <CR name>
+	Conflicts []string `json:"conflicts,omitempty"`
+	// Status of the configuration of the nodes selected by the CR
+	Nodes []NodeSyncStatus `json:"nodes,omitempty"`
+}
+
+// NodeSyncStatus is the status of the configuration of the node's PFs selected by the SriovFecClusterConfig
+type NodeSyncStatus struct {
+	NodeName string `json:"nodeName"`
+	// Succeeded once the node applied the current config, Failed if it failed to apply it, InProgress otherwise
+	SyncStatus SyncStatus `json:"syncStatus,omitempty"`
+	// Message of the Configured condition of the node's SriovFecNodeConfig
+	Message string `json:"message,omitempty"`
+	// Number of the node's PFs configured by the CR
+	PFs int `json:"pfs"`
+	// Number of the VFs of these PFs reported in the node's inventory
+	VFs int `json:"vfs"`
+	// Configs of these PFs applied on the node, set once the node applied the current config. The bbDevConfig is
+	// the queue config saved by the daemon when it was applied to the PF with pf_bb_config.
+	AppliedConfigs []PhysicalFunctionConfig `json:"appliedConfigs,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Priority",type=integer,JSONPath=`.spec.priority`
 // +kubebuilder:printcolumn:name="SyncStatus",type=string,JSONPath=`.status.syncStatus`
 
 // SriovFecClusterConfig is the Schema for the sriovfecclusterconfigs API
@@ -4,6 +4,7 @@
 package v1
 
 import (
+	dhapi "github.com/open-ness/openshift-operator/common/pkg/drainhelper/api"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -20,6 +21,10 @@ type SriovAccelerator struct {
 	Driver     string `json:"driver"`
 	MaxVFs     int    `json:"maxVirtualFunctions"`
 	VFs        []VF   `json:"virtualFunctions"`
+	// QueueConfig is the queue configuration last applied to the PF with pf_bb_config, as saved by the daemon.
+	// pf_bb_config doesn't report the configuration of the device, so it is not set if the queues of the PF were
+	// not configured by the daemon since the last boot or if the VFs or the driver of the PF changed since.
+	QueueConfig *BBDevConfig `json:"queueConfig,omitempty"`
 }
 
 type NodeInventory struct {
@@ -32,6 +37,8 @@ type SriovFecNodeConfigSpec struct {
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	PhysicalFunctions []PhysicalFunctionConfig `json:"physicalFunctions"`
 	DrainSkip         bool                     `json:"drainSkip,omitempty"`
+	// Drain settings, used unless drainSkip is set
+	DrainPolicy *dhapi.DrainPolicySpec `json:"drainPolicy,omitempty"`
 }
 
 // SriovFecNodeConfigStatus defines the observed state of SriovFecNodeConfig
@@ -41,6 +48,8 @@ type SriovFecNodeConfigStatus struct {
 	// Provides information about FPGA inventory on the node
 	// +operator-sdk:csv:customresourcedefinitions:type=status
 	Inventory NodeInventory `json:"inventory,omitempty"`
+	// Result of the last drain simulation
+	DrainSimulation *dhapi.DrainSimulationStatus `json:"drainSimulation,omitempty"`
 }
 
 // +kubebuilder:object:root=true
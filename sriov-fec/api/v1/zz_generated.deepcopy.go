@@ -1,3 +1,4 @@
+//go:build !ignore_autogenerated
 // +build !ignore_autogenerated
 
 // SPDX-License-Identifier: Apache-2.0
@@ -8,6 +9,7 @@
 package v1
 
 import (
+	dhapi "github.com/open-ness/openshift-operator/common/pkg/drainhelper/api"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
@@ -31,6 +33,41 @@ func (in *ACC100BBDevConfig) DeepCopy() *ACC100BBDevConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACC200BBDevConfig) DeepCopyInto(out *ACC200BBDevConfig) {
+	*out = *in
+	out.Uplink4G = in.Uplink4G
+	out.Downlink4G = in.Downlink4G
+	out.Uplink5G = in.Uplink5G
+	out.Downlink5G = in.Downlink5G
+	out.QFFT = in.QFFT
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACC200BBDevConfig.
+func (in *ACC200BBDevConfig) DeepCopy() *ACC200BBDevConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ACC200BBDevConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AcceleratorSelector) DeepCopyInto(out *AcceleratorSelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AcceleratorSelector.
+func (in *AcceleratorSelector) DeepCopy() *AcceleratorSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(AcceleratorSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BBDevConfig) DeepCopyInto(out *BBDevConfig) {
 	*out = *in
@@ -44,6 +81,11 @@ func (in *BBDevConfig) DeepCopyInto(out *BBDevConfig) {
 		*out = new(ACC100BBDevConfig)
 		**out = **in
 	}
+	if in.ACC200 != nil {
+		in, out := &in.ACC200, &out.ACC200
+		*out = new(ACC200BBDevConfig)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BBDevConfig.
@@ -76,6 +118,11 @@ func (in *N3000BBDevConfig) DeepCopy() *N3000BBDevConfig {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeConfig) DeepCopyInto(out *NodeConfig) {
 	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.PhysicalFunctions != nil {
 		in, out := &in.PhysicalFunctions, &out.PhysicalFunctions
 		*out = make([]PhysicalFunctionConfig, len(*in))
@@ -117,10 +164,44 @@ func (in *NodeInventory) DeepCopy() *NodeInventory {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeSyncStatus) DeepCopyInto(out *NodeSyncStatus) {
+	*out = *in
+	if in.AppliedConfigs != nil {
+		in, out := &in.AppliedConfigs, &out.AppliedConfigs
+		*out = make([]PhysicalFunctionConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeSyncStatus.
+func (in *NodeSyncStatus) DeepCopy() *NodeSyncStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeSyncStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PhysicalFunctionConfig) DeepCopyInto(out *PhysicalFunctionConfig) {
 	*out = *in
+	if in.AcceleratorSelector != nil {
+		in, out := &in.AcceleratorSelector, &out.AcceleratorSelector
+		*out = new(AcceleratorSelector)
+		**out = **in
+	}
 	in.BBDevConfig.DeepCopyInto(&out.BBDevConfig)
+	if in.VFs != nil {
+		in, out := &in.VFs, &out.VFs
+		*out = make([]VFConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PhysicalFunctionConfig.
@@ -156,6 +237,11 @@ func (in *SriovAccelerator) DeepCopyInto(out *SriovAccelerator) {
 		*out = make([]VF, len(*in))
 		copy(*out, *in)
 	}
+	if in.QueueConfig != nil {
+		in, out := &in.QueueConfig, &out.QueueConfig
+		*out = new(BBDevConfig)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SriovAccelerator.
@@ -174,7 +260,7 @@ func (in *SriovFecClusterConfig) DeepCopyInto(out *SriovFecClusterConfig) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SriovFecClusterConfig.
@@ -237,6 +323,11 @@ func (in *SriovFecClusterConfigSpec) DeepCopyInto(out *SriovFecClusterConfigSpec
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.DrainPolicy != nil {
+		in, out := &in.DrainPolicy, &out.DrainPolicy
+		*out = new(dhapi.DrainPolicySpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SriovFecClusterConfigSpec.
@@ -252,6 +343,18 @@ func (in *SriovFecClusterConfigSpec) DeepCopy() *SriovFecClusterConfigSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SriovFecClusterConfigStatus) DeepCopyInto(out *SriovFecClusterConfigStatus) {
 	*out = *in
+	if in.Conflicts != nil {
+		in, out := &in.Conflicts, &out.Conflicts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]NodeSyncStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SriovFecClusterConfigStatus.
@@ -333,6 +436,11 @@ func (in *SriovFecNodeConfigSpec) DeepCopyInto(out *SriovFecNodeConfigSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.DrainPolicy != nil {
+		in, out := &in.DrainPolicy, &out.DrainPolicy
+		*out = new(dhapi.DrainPolicySpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SriovFecNodeConfigSpec.
@@ -356,6 +464,11 @@ func (in *SriovFecNodeConfigStatus) DeepCopyInto(out *SriovFecNodeConfigStatus)
 		}
 	}
 	in.Inventory.DeepCopyInto(&out.Inventory)
+	if in.DrainSimulation != nil {
+		in, out := &in.DrainSimulation, &out.DrainSimulation
+		*out = new(dhapi.DrainSimulationStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SriovFecNodeConfigStatus.
@@ -413,3 +526,38 @@ func (in *VF) DeepCopy() *VF {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VFConfig) DeepCopyInto(out *VFConfig) {
+	*out = *in
+	if in.Queues != nil {
+		in, out := &in.Queues, &out.Queues
+		*out = new(VFQueues)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VFConfig.
+func (in *VFConfig) DeepCopy() *VFConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(VFConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VFQueues) DeepCopyInto(out *VFQueues) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VFQueues.
+func (in *VFQueues) DeepCopy() *VFQueues {
+	if in == nil {
+		return nil
+	}
+	out := new(VFQueues)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -7,6 +7,7 @@ import (
 	"flag"
 	"os"
 
+	"github.com/open-ness/openshift-operator/common/pkg/drainhelper"
 	sriovv1 "github.com/open-ness/openshift-operator/sriov-fec/api/v1"
 	"github.com/open-ness/openshift-operator/sriov-fec/pkg/daemon"
 
@@ -30,6 +31,8 @@ func init() {
 }
 
 func main() {
+	var metricsAddr string
+	flag.StringVar(&metricsAddr, "metrics-bind-address", ":42224", "The address the metric endpoint binds to.")
 	opts := zap.Options{}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
@@ -48,6 +51,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := drainhelper.RegisterMetrics(); err != nil {
+		setupLog.Error(err, "failed to register drain metrics")
+		os.Exit(1)
+	}
+
 	config := ctrl.GetConfigOrDie()
 	directClient, err := client.New(config, client.Options{Scheme: scheme})
 	if err != nil {
@@ -63,7 +71,7 @@ func main() {
 
 	mgr, err := ctrl.NewManager(config, ctrl.Options{
 		Scheme:             scheme,
-		MetricsBindAddress: "0",
+		MetricsBindAddress: metricsAddr,
 		LeaderElection:     false,
 		Namespace:          ns,
 	})
@@ -0,0 +1,146 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/open-ness/openshift-operator/common/pkg/utils"
+	sriovfecv1 "github.com/open-ness/openshift-operator/sriov-fec/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// ConfigMap with the supported accelerators config (accelerators.json), deployed with the labeler
+	supportedAcceleratorsConfigMap = "supported-accelerators"
+	supportedAcceleratorsKey       = "accelerators.json"
+)
+
+// nodeSelected returns true if the node is configured by the nodeConfig - by name or, if the name is not set,
+// by the node's labels. A nodeConfig without name and selector, or with an invalid selector, selects no node.
+func nodeSelected(nodeConfig sriovfecv1.NodeConfig, node *corev1.Node) bool {
+	if nodeConfig.NodeName != "" {
+		return nodeConfig.NodeName == node.Name
+	}
+	if nodeConfig.NodeSelector == nil {
+		return false
+	}
+	selector, err := v1.LabelSelectorAsSelector(nodeConfig.NodeSelector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(node.Labels))
+}
+
+// acceleratorSelected returns true if the PF from the node's inventory matches the selector.
+// deviceNames maps the device IDs to the device names from the supported accelerators config.
+func acceleratorSelected(selector *sriovfecv1.AcceleratorSelector, acc sriovfecv1.SriovAccelerator,
+	deviceNames map[string]string) bool {
+	if selector.VendorID != "" && !strings.EqualFold(selector.VendorID, acc.VendorID) {
+		return false
+	}
+	if selector.DeviceID != "" && !strings.EqualFold(selector.DeviceID, acc.DeviceID) {
+		return false
+	}
+	if selector.DeviceName != "" && deviceNames[strings.ToLower(acc.DeviceID)] != selector.DeviceName {
+		return false
+	}
+	return true
+}
+
+// usesDeviceName returns true if any PF of the cluster config is selected by the device name
+func usesDeviceName(clusterConfig *sriovfecv1.SriovFecClusterConfig) bool {
+	for _, nodeConfig := range clusterConfig.Spec.Nodes {
+		for _, pf := range nodeConfig.PhysicalFunctions {
+			if pf.PCIAddress == "" && pf.AcceleratorSelector != nil && pf.AcceleratorSelector.DeviceName != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inventoryReported returns true if the daemon of the node reported its inventory in the NodeConfig. The NodeConfig
+// created by the daemon may be missing it until the daemon reconciles it.
+func inventoryReported(nc *sriovfecv1.SriovFecNodeConfig) bool {
+	return len(nc.Status.Inventory.SriovAccelerators) != 0 || len(nc.Status.Conditions) != 0
+}
+
+// resolvePhysicalFunctions returns the PF configs for the node with the inventory. The PFs with PCI address
+// are used as they are and take precedence over the selectors. Each selector is resolved to all the matching
+// PFs from the inventory, which were not configured by the previous PF configs.
+func resolvePhysicalFunctions(pfs []sriovfecv1.PhysicalFunctionConfig, inventory sriovfecv1.NodeInventory,
+	deviceNames map[string]string) []sriovfecv1.PhysicalFunctionConfig {
+
+	resolved := []sriovfecv1.PhysicalFunctionConfig{}
+	configured := map[string]bool{}
+	for _, pf := range pfs {
+		if pf.PCIAddress == "" || configured[pf.PCIAddress] {
+			continue
+		}
+		pf.AcceleratorSelector = nil
+		resolved = append(resolved, pf)
+		configured[pf.PCIAddress] = true
+	}
+
+	for _, pf := range pfs {
+		if pf.PCIAddress != "" || pf.AcceleratorSelector == nil {
+			continue
+		}
+		for _, acc := range inventory.SriovAccelerators {
+			if configured[acc.PCIAddress] || !acceleratorSelected(pf.AcceleratorSelector, acc, deviceNames) {
+				continue
+			}
+			pfCfg := *pf.DeepCopy()
+			pfCfg.PCIAddress = acc.PCIAddress
+			pfCfg.AcceleratorSelector = nil
+			resolved = append(resolved, pfCfg)
+			configured[acc.PCIAddress] = true
+		}
+	}
+
+	return resolved
+}
+
+// getDeviceNames returns the device names of the supported accelerators by their device IDs
+func (r *SriovFecClusterConfigReconciler) getDeviceNames() (map[string]string, error) {
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(context.TODO(),
+		types.NamespacedName{Namespace: NAMESPACE, Name: supportedAcceleratorsConfigMap}, cm); err != nil {
+		return nil, err
+	}
+
+	var cfg utils.AcceleratorDiscoveryConfig
+	if err := json.Unmarshal([]byte(cm.Data[supportedAcceleratorsKey]), &cfg); err != nil {
+		return nil, fmt.Errorf("invalid %s in ConfigMap %s: %v", supportedAcceleratorsKey, supportedAcceleratorsConfigMap, err)
+	}
+
+	deviceNames := map[string]string{}
+	for id, name := range cfg.Devices {
+		deviceNames[strings.ToLower(id)] = name
+	}
+	return deviceNames, nil
+}
+
+// getNodeConfigs returns the SriovFecNodeConfigs from the namespace of the operator, by node name
+func (r *SriovFecClusterConfigReconciler) getNodeConfigs() (map[string]sriovfecv1.SriovFecNodeConfig, error) {
+	ncList := &sriovfecv1.SriovFecNodeConfigList{}
+	if err := r.List(context.TODO(), ncList); err != nil {
+		return nil, err
+	}
+
+	nodeConfigs := map[string]sriovfecv1.SriovFecNodeConfig{}
+	for _, nc := range ncList.Items {
+		if nc.GetNamespace() == NAMESPACE {
+			nodeConfigs[nc.GetName()] = nc
+		}
+	}
+	return nodeConfigs, nil
+}
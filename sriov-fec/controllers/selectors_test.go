@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	sriovv1 "github.com/open-ness/openshift-operator/sriov-fec/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Selectors", func() {
+	deviceNames := map[string]string{"0d5c": "ACC100", "0d8f": "FPGA_5GNR"}
+	inventory := sriovv1.NodeInventory{
+		SriovAccelerators: []sriovv1.SriovAccelerator{
+			{VendorID: "8086", DeviceID: "0d5c", PCIAddress: "0000:14:00.1"},
+			{VendorID: "8086", DeviceID: "0d8f", PCIAddress: "0000:1b:00.0"},
+			{VendorID: "8086", DeviceID: "0d5c", PCIAddress: "0000:3b:00.0"},
+		},
+	}
+	pfConfig := func(pci string, selector *sriovv1.AcceleratorSelector, vfs int) sriovv1.PhysicalFunctionConfig {
+		return sriovv1.PhysicalFunctionConfig{PCIAddress: pci, AcceleratorSelector: selector, VFAmount: vfs}
+	}
+
+	var _ = Describe("nodeSelected", func() {
+		node := &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "node1", Labels: map[string]string{"sku": "a"}}}
+
+		var _ = It("will select the node by name", func() {
+			Expect(nodeSelected(sriovv1.NodeConfig{NodeName: "node1"}, node)).To(BeTrue())
+			Expect(nodeSelected(sriovv1.NodeConfig{NodeName: "node2", NodeSelector: &v1.LabelSelector{
+				MatchLabels: map[string]string{"sku": "a"}}}, node)).To(BeFalse())
+		})
+		var _ = It("will select the node by labels", func() {
+			Expect(nodeSelected(sriovv1.NodeConfig{NodeSelector: &v1.LabelSelector{
+				MatchLabels: map[string]string{"sku": "a"}}}, node)).To(BeTrue())
+			Expect(nodeSelected(sriovv1.NodeConfig{NodeSelector: &v1.LabelSelector{
+				MatchLabels: map[string]string{"sku": "b"}}}, node)).To(BeFalse())
+			Expect(nodeSelected(sriovv1.NodeConfig{NodeSelector: &v1.LabelSelector{}}, node)).To(BeTrue())
+		})
+		var _ = It("will select the node by label expressions", func() {
+			Expect(nodeSelected(sriovv1.NodeConfig{NodeSelector: &v1.LabelSelector{
+				MatchExpressions: []v1.LabelSelectorRequirement{
+					{Key: "sku", Operator: v1.LabelSelectorOpIn, Values: []string{"a", "b"}}}}}, node)).To(BeTrue())
+			Expect(nodeSelected(sriovv1.NodeConfig{NodeSelector: &v1.LabelSelector{
+				MatchExpressions: []v1.LabelSelectorRequirement{
+					{Key: "sku", Operator: v1.LabelSelectorOpNotIn, Values: []string{"a"}}}}}, node)).To(BeFalse())
+		})
+		var _ = It("will not select any node with invalid selector", func() {
+			Expect(nodeSelected(sriovv1.NodeConfig{NodeSelector: &v1.LabelSelector{
+				MatchExpressions: []v1.LabelSelectorRequirement{{Key: "sku", Operator: "Like"}}}}, node)).To(BeFalse())
+		})
+		var _ = It("will not select any node without name and selector", func() {
+			Expect(nodeSelected(sriovv1.NodeConfig{}, node)).To(BeFalse())
+		})
+	})
+
+	var _ = Describe("resolvePhysicalFunctions", func() {
+		var _ = It("will resolve the selector by device name", func() {
+			pfs := resolvePhysicalFunctions([]sriovv1.PhysicalFunctionConfig{
+				pfConfig("", &sriovv1.AcceleratorSelector{DeviceName: "ACC100"}, 2),
+			}, inventory, deviceNames)
+			Expect(pfs).To(Equal([]sriovv1.PhysicalFunctionConfig{
+				pfConfig("0000:14:00.1", nil, 2),
+				pfConfig("0000:3b:00.0", nil, 2),
+			}))
+		})
+		var _ = It("will resolve the selector by vendor and device ID", func() {
+			pfs := resolvePhysicalFunctions([]sriovv1.PhysicalFunctionConfig{
+				pfConfig("", &sriovv1.AcceleratorSelector{VendorID: "8086", DeviceID: "0D8F"}, 2),
+			}, inventory, deviceNames)
+			Expect(pfs).To(Equal([]sriovv1.PhysicalFunctionConfig{pfConfig("0000:1b:00.0", nil, 2)}))
+		})
+		var _ = It("will prefer the PCI address and the previous selectors", func() {
+			pfs := resolvePhysicalFunctions([]sriovv1.PhysicalFunctionConfig{
+				pfConfig("", &sriovv1.AcceleratorSelector{DeviceName: "ACC100"}, 1),
+				pfConfig("", &sriovv1.AcceleratorSelector{}, 2),
+				pfConfig("0000:3b:00.0", nil, 3),
+			}, inventory, deviceNames)
+			Expect(pfs).To(Equal([]sriovv1.PhysicalFunctionConfig{
+				pfConfig("0000:3b:00.0", nil, 3),
+				pfConfig("0000:14:00.1", nil, 1),
+				pfConfig("0000:1b:00.0", nil, 2),
+			}))
+		})
+		var _ = It("will not resolve the selector without inventory", func() {
+			pfs := resolvePhysicalFunctions([]sriovv1.PhysicalFunctionConfig{
+				pfConfig("", &sriovv1.AcceleratorSelector{}, 2),
+			}, sriovv1.NodeInventory{}, deviceNames)
+			Expect(pfs).To(BeEmpty())
+		})
+	})
+})
@@ -23,6 +23,10 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
@@ -30,15 +34,32 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	"github.com/open-ness/openshift-operator/common/pkg/events"
+	"github.com/open-ness/openshift-operator/common/pkg/statuswriter"
 	sriovfecv1 "github.com/open-ness/openshift-operator/sriov-fec/api/v1"
 )
 
 const (
-	DEFAULT_CLUSTER_CONFIG_NAME = "config"
+	// inventoryRequeuePeriod is the period of the reconciliation while there are selected nodes without
+	// the inventory reported
+	inventoryRequeuePeriod = 30 * time.Second
+
+	// Reasons of the events recorded on the SriovFecClusterConfig and the Nodes
+	EventNodeConfigCreated = "NodeConfigCreated"
+	EventNodeConfigUpdated = "NodeConfigUpdated"
+	EventNodeConfigDeleted = "NodeConfigDeleted"
+	EventSyncFailed        = "SyncFailed"
+	EventSyncIgnored       = "SyncIgnored"
 )
 
 var NAMESPACE = os.Getenv("SRIOV_FEC_NAMESPACE")
@@ -46,8 +67,23 @@ var NAMESPACE = os.Getenv("SRIOV_FEC_NAMESPACE")
 // SriovFecClusterConfigReconciler reconciles a SriovFecClusterConfig object
 type SriovFecClusterConfigReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// recordEvent records the event on the object (SriovFecClusterConfig or SriovFecNodeConfig) and on the Node,
+// if nodeName is set
+func (r *SriovFecClusterConfigReconciler) recordEvent(obj runtime.Object, nodeName string,
+	eventType, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder == nil {
+		return
+	}
+	if nodeName == "" {
+		r.Recorder.Eventf(obj, eventType, reason, messageFmt, args...)
+		return
+	}
+	events.NewNodeRecorder(r.Recorder, nodeName).Eventf(obj, eventType, reason, messageFmt, args...)
 }
 
 // +kubebuilder:rbac:groups=sriovfec.intel.com,resources=sriovfecclusterconfigs,verbs=get;list;watch;create;update;patch;delete
@@ -55,50 +91,62 @@ type SriovFecClusterConfigReconciler struct {
 // +kubebuilder:rbac:groups=sriovfec.intel.com,resources=sriovfecnodeconfigs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=sriovfec.intel.com,resources=sriovfecnodeconfigs/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups="",resources=nodes,verbs=list;watch
-// +kubebuilder:rbac:groups="",resources=namespaces;serviceaccounts;configmaps,verbs=*
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=namespaces;serviceaccounts;configmaps;services,verbs=*
 // +kubebuilder:rbac:groups=apps,resources=daemonsets;deployments;deployments/finalizers,verbs=*
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings;clusterroles;clusterrolebindings,verbs=*
 // +kubebuilder:rbac:groups=security.openshift.io,resources=securitycontextconstraints,verbs=*
+// +kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;create;update
 
 func (r *SriovFecClusterConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("sriovfecclusterconfig", req.NamespacedName)
 	log.V(2).Info("Reconciling SriovFecClusterConfig")
 
-	clusterConfig := &sriovfecv1.SriovFecClusterConfig{}
-	if err := r.Get(context.TODO(), req.NamespacedName, clusterConfig); err != nil {
-		if errors.IsNotFound(err) {
-			log.V(2).Info("SriovFecClusterConfig not found", "namespacedName", req.NamespacedName)
-			return reconcile.Result{}, nil
-		}
-		// Error reading the object - requeue the request.
-		return reconcile.Result{}, err
-	}
-
-	updateStatus := func(status sriovfecv1.SyncStatus, reason string) {
-		clusterConfig.Status.SyncStatus = status
-		clusterConfig.Status.LastSyncError = reason
-		if err := r.Status().Update(context.TODO(), clusterConfig, &client.UpdateOptions{}); err != nil {
-			log.Error(err, "failed to update cluster config's status")
+	// Only the SriovFecClusterConfigs in the namespace of the operator are honored, any other is ignored
+	if req.Namespace != NAMESPACE {
+		clusterConfig := &sriovfecv1.SriovFecClusterConfig{}
+		if err := r.Get(context.TODO(), req.NamespacedName, clusterConfig); err != nil {
+			if errors.IsNotFound(err) {
+				log.V(2).Info("SriovFecClusterConfig not found", "namespacedName", req.NamespacedName)
+				return reconcile.Result{}, nil
+			}
+			// Error reading the object - requeue the request.
+			return reconcile.Result{}, err
 		}
-	}
 
-	// To simplify things, only specific CR is honored (Name: DEFAULT_CLUSTER_CONFIG_NAME, Namespace: NAMESPACE)
-	// Any other SriovFecClusterConfig is ignored
-	if req.Namespace != NAMESPACE || req.Name != DEFAULT_CLUSTER_CONFIG_NAME {
 		log.V(2).Info("received ClusterConfig, but it not an expected one - it'll be ignored",
-			"expectedNamespace", NAMESPACE, "expectedName", DEFAULT_CLUSTER_CONFIG_NAME)
+			"expectedNamespace", NAMESPACE)
 
-		updateStatus(sriovfecv1.IgnoredSync, fmt.Sprintf(
-			"Only SriovFecClusterConfig with name '%s' and namespace '%s' are handled",
-			DEFAULT_CLUSTER_CONFIG_NAME, NAMESPACE))
+		msg := fmt.Sprintf("Only SriovFecClusterConfigs in namespace '%s' are handled", NAMESPACE)
+		r.updateStatus(clusterConfig, sriovfecv1.SriovFecClusterConfigStatus{SyncStatus: sriovfecv1.IgnoredSync,
+			LastSyncError: msg})
+		r.recordEvent(clusterConfig, "", corev1.EventTypeWarning, EventSyncIgnored, "%s", msg)
 
 		return reconcile.Result{}, nil
 	}
 
+	// all the cluster configs are merged, so a change (or removal) of any of them is handled as a change of all
+	clusterConfigs, err := r.getClusterConfigs()
+	if err != nil {
+		log.Error(err, "failed to get SriovFecClusterConfigs")
+		return reconcile.Result{}, err
+	}
+
+	failSync := func(reason, eventFmt string, err error) {
+		for i := range clusterConfigs {
+			status := clusterConfigs[i].Status.DeepCopy()
+			status.SyncStatus = sriovfecv1.FailedSync
+			status.LastSyncError = reason
+			r.updateStatus(&clusterConfigs[i], *status)
+			r.recordEvent(&clusterConfigs[i], "", corev1.EventTypeWarning, EventSyncFailed, eventFmt, err)
+		}
+	}
+
 	nodeList, err := r.getNodesWithIntelAccelerator()
 	if err != nil {
 		log.Error(err, "failed to obtain nodes with Intel accelerator")
-		updateStatus(sriovfecv1.FailedSync, "nfd error: failed to obtain nodes with Intel accelerator - check logs")
+		failSync("nfd error: failed to obtain nodes with Intel accelerator - check logs",
+			"Failed to obtain nodes with Intel accelerator: %v", err)
 		return reconcile.Result{}, err
 	}
 
@@ -110,25 +158,160 @@ func (r *SriovFecClusterConfigReconciler) Reconcile(ctx context.Context, req ctr
 		return names
 	}())
 
-	nodeConfigs := r.renderNodeConfigs(clusterConfig, nodeList)
-	if err := r.syncNodeConfigs(nodeConfigs); err != nil {
+	rendered, err := r.renderNodeConfigs(clusterConfigs, nodeList)
+	if err != nil {
+		failSync("failed to render NodeConfigs - check logs", "Failed to render NodeConfigs: %v", err)
+		return reconcile.Result{}, err
+	}
+
+	// the cluster configs are validated also here, as the validating webhook may not be deployed. The NodeConfigs
+	// are not updated while any of the cluster configs is invalid, so the nodes keep the last valid configuration
+	if r.failInvalidClusterConfigs(clusterConfigs, rendered) {
+		return reconcile.Result{}, nil
+	}
+	generations, err := r.syncNodeConfigs(rendered.owners, rendered.nodeConfigs, rendered.pending)
+	if err != nil {
 		log.Error(err, "syncNodeConfigs failed")
-		updateStatus(sriovfecv1.FailedSync, "failed to create NodeConfigs - check logs")
+		failSync("failed to create NodeConfigs - check logs", "Failed to sync NodeConfigs: %v", err)
+		return reconcile.Result{}, err
+	}
+
+	// the statuses are rolled up from the NodeConfigs as updated by the daemons, the cache may not contain the
+	// NodeConfigs written above yet, so their conditions are checked against the written generations
+	nodeConfigs, err := r.getNodeConfigs()
+	if err != nil {
+		log.Error(err, "failed to get NodeConfigs")
+		failSync("failed to get NodeConfigs - check logs", "Failed to get NodeConfigs: %v", err)
 		return reconcile.Result{}, err
 	}
+	for i := range clusterConfigs {
+		name := clusterConfigs[i].Name
+		status := sriovfecv1.SriovFecClusterConfigStatus{Conflicts: rendered.conflicts[name]}
+		for _, nodeName := range rendered.selected[name] {
+			pciAddresses := rendered.configured[name][nodeName]
+			nc, ok := nodeConfigs[nodeName]
+			switch {
+			case !ok || !inventoryReported(&nc):
+				status.Nodes = append(status.Nodes, nodeSyncStatus(nodeName, pciAddresses, nil, 0))
+			case len(pciAddresses) != 0:
+				status.Nodes = append(status.Nodes, nodeSyncStatus(nodeName, pciAddresses, &nc, generations[nodeName]))
+			}
+		}
 
-	updateStatus(sriovfecv1.SucceededSync, "")
+		if len(status.Conflicts) != 0 && len(rendered.configured[name]) == 0 {
+			status.SyncStatus = sriovfecv1.ShadowedSync
+			status.LastSyncError = "all the selected PFs are configured by SriovFecClusterConfigs with higher precedence"
+		} else {
+			status.SyncStatus, status.LastSyncError = clusterSyncStatus(status.Nodes)
+		}
+		r.updateStatus(&clusterConfigs[i], status)
+	}
 
+	if len(rendered.pending) != 0 {
+		log.V(2).Info("inventory of the nodes not reported yet - requeuing", "nodes", rendered.pending)
+		return reconcile.Result{RequeueAfter: inventoryRequeuePeriod}, nil
+	}
 	return reconcile.Result{}, nil
 }
 
+// failInvalidClusterConfigs fails the sync of the cluster configs violating the validation rules of the validating
+// webhook. Returns true if any of the cluster configs is invalid.
+func (r *SriovFecClusterConfigReconciler) failInvalidClusterConfigs(clusterConfigs []sriovfecv1.SriovFecClusterConfig,
+	rendered *renderedNodeConfigs) bool {
+	invalid := false
+	for i := range clusterConfigs {
+		errs := append(validateClusterConfig(&clusterConfigs[i]), rendered.invalid[clusterConfigs[i].Name]...)
+		if len(errs) == 0 {
+			continue
+		}
+		invalid = true
+		msg := fmt.Sprintf("invalid config: %s", strings.Join(errs, "; "))
+		r.Log.Info("SriovFecClusterConfig is invalid - NodeConfigs will not be updated",
+			"clusterConfig", clusterConfigs[i].Name, "errors", errs)
+		status := clusterConfigs[i].Status.DeepCopy()
+		status.SyncStatus = sriovfecv1.FailedSync
+		status.LastSyncError = msg
+		r.updateStatus(&clusterConfigs[i], *status)
+		r.recordEvent(&clusterConfigs[i], "", corev1.EventTypeWarning, EventSyncFailed, "%s", msg)
+	}
+	return invalid
+}
+
+func (r *SriovFecClusterConfigReconciler) updateStatus(clusterConfig *sriovfecv1.SriovFecClusterConfig,
+	status sriovfecv1.SriovFecClusterConfigStatus) {
+	err := statuswriter.NewStatusWriter(r.Client).Update(context.TODO(), clusterConfig, func(obj client.Object) {
+		obj.(*sriovfecv1.SriovFecClusterConfig).Status = status
+	})
+	if err != nil {
+		r.Log.WithName("updateStatus").Error(err, "failed to update cluster config's status", "name", clusterConfig.Name)
+	}
+}
+
 func (r *SriovFecClusterConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	// Add NodeConfigs & DaemonSet
+	// the accelerator and node selectors are resolved again when the inventory or the labels change,
+	// the status is rolled up again when the Configured condition of a node changes
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&sriovfecv1.SriovFecClusterConfig{}).
+		For(&sriovfecv1.SriovFecClusterConfig{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&source.Kind{Type: &sriovfecv1.SriovFecNodeConfig{}},
+			handler.EnqueueRequestsFromMapFunc(r.clusterConfigRequests),
+			builder.WithPredicates(predicate.Funcs{
+				UpdateFunc: func(e event.UpdateEvent) bool {
+					oldNC, okOld := e.ObjectOld.(*sriovfecv1.SriovFecNodeConfig)
+					newNC, okNew := e.ObjectNew.(*sriovfecv1.SriovFecNodeConfig)
+					return okOld && okNew && (!reflect.DeepEqual(oldNC.Status.Inventory, newNC.Status.Inventory) ||
+						!reflect.DeepEqual(oldNC.Status.Conditions, newNC.Status.Conditions))
+				},
+				DeleteFunc: func(e event.DeleteEvent) bool { return false },
+			})).
+		Watches(&source.Kind{Type: &corev1.Node{}},
+			handler.EnqueueRequestsFromMapFunc(r.clusterConfigRequests),
+			builder.WithPredicates(predicate.Funcs{
+				UpdateFunc: func(e event.UpdateEvent) bool {
+					return !reflect.DeepEqual(e.ObjectOld.GetLabels(), e.ObjectNew.GetLabels())
+				},
+			})).
 		Complete(r)
 }
 
+// clusterConfigRequests maps the changes of the other objects to a request of the cluster config with the highest
+// precedence - all the cluster configs are handled together
+func (r *SriovFecClusterConfigReconciler) clusterConfigRequests(_ client.Object) []reconcile.Request {
+	clusterConfigs, err := r.getClusterConfigs()
+	if err != nil {
+		r.Log.WithName("clusterConfigRequests").Error(err, "failed to get SriovFecClusterConfigs")
+		return nil
+	}
+	if len(clusterConfigs) == 0 {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: NAMESPACE, Name: clusterConfigs[0].Name}},
+	}
+}
+
+// getClusterConfigs returns the SriovFecClusterConfigs from the namespace of the operator, sorted by precedence:
+// by priority and by name if the priorities are equal
+func (r *SriovFecClusterConfigReconciler) getClusterConfigs() ([]sriovfecv1.SriovFecClusterConfig, error) {
+	ccList := &sriovfecv1.SriovFecClusterConfigList{}
+	if err := r.List(context.TODO(), ccList, client.InNamespace(NAMESPACE)); err != nil {
+		return nil, err
+	}
+
+	sortClusterConfigs(ccList.Items)
+	return ccList.Items, nil
+}
+
+// sortClusterConfigs sorts the cluster configs by precedence
+func sortClusterConfigs(clusterConfigs []sriovfecv1.SriovFecClusterConfig) {
+	sort.Slice(clusterConfigs, func(i, j int) bool {
+		if clusterConfigs[i].Spec.Priority != clusterConfigs[j].Spec.Priority {
+			return clusterConfigs[i].Spec.Priority < clusterConfigs[j].Spec.Priority
+		}
+		return clusterConfigs[i].Name < clusterConfigs[j].Name
+	})
+}
+
 func (r *SriovFecClusterConfigReconciler) getNodesWithIntelAccelerator() (*corev1.NodeList, error) {
 	nodeList := &corev1.NodeList{}
 
@@ -143,29 +326,137 @@ func (r *SriovFecClusterConfigReconciler) getNodesWithIntelAccelerator() (*corev
 	return nodeList, nil
 }
 
-func (r *SriovFecClusterConfigReconciler) renderNodeConfigs(clusterConfig *sriovfecv1.SriovFecClusterConfig,
-	nodeList *corev1.NodeList) []sriovfecv1.SriovFecNodeConfig {
+// renderedNodeConfigs are the NodeConfigs merged from the cluster configs
+type renderedNodeConfigs struct {
+	nodeConfigs []sriovfecv1.SriovFecNodeConfig
+	// cluster config with the highest precedence selecting the node, by node name
+	owners map[string]*sriovfecv1.SriovFecClusterConfig
+	// PFs selected by the cluster config, but configured by the cluster configs with higher precedence,
+	// by cluster config name
+	conflicts map[string][]string
+	// nodes selected by the cluster config, by cluster config name
+	selected map[string][]string
+	// PCI addresses of the PFs configured by the cluster config, by cluster config and node name
+	configured map[string]map[string][]string
+	// selected nodes without the inventory reported, their NodeConfigs are not rendered yet
+	pending []string
+	// violations of the capabilities of the PFs configured by the cluster config, by cluster config name
+	invalid map[string][]string
+}
+
+// renderNodeConfigs renders the NodeConfigs of the nodes with accelerator selected by the cluster configs,
+// sorted by precedence. The PFs of all the node configs of a cluster config selecting the node are merged and
+// their accelerator selectors are resolved against the inventory reported in the node's SriovFecNodeConfig,
+// the node without the inventory is skipped until it's reported. A PF selected by several cluster configs is
+// configured by the one with the highest precedence, the drain settings of the node are taken from the cluster
+// config with the highest precedence selecting the node.
+func (r *SriovFecClusterConfigReconciler) renderNodeConfigs(clusterConfigs []sriovfecv1.SriovFecClusterConfig,
+	nodeList *corev1.NodeList) (*renderedNodeConfigs, error) {
 
 	log := r.Log.WithName("renderNodeConfigs")
 	log.V(2).Info("rendering new node configs")
 
-	nodeConfigs := []sriovfecv1.SriovFecNodeConfig{}
+	nodeConfigs, err := r.getNodeConfigs()
+	if err != nil {
+		log.Error(err, "failed to get inventories of the nodes")
+		return nil, err
+	}
+	var deviceNames map[string]string
+	for i := range clusterConfigs {
+		if usesDeviceName(&clusterConfigs[i]) {
+			if deviceNames, err = r.getDeviceNames(); err != nil {
+				log.Error(err, "failed to get device names of supported accelerators")
+				return nil, err
+			}
+			break
+		}
+	}
 
-	nodeHasAccelerator := func(nodeName string) bool {
-		// check user-provided NodeName against list of nodes with accelerators according to the NFD
-		for _, node := range nodeList.Items {
-			if node.Name == nodeName {
-				return true
+	for _, clusterConfig := range clusterConfigs {
+		for _, nodeConfigSpec := range clusterConfig.Spec.Nodes {
+			if nodeConfigSpec.NodeName == "" {
+				continue
+			}
+			found := false
+			for i := range nodeList.Items {
+				if nodeList.Items[i].Name == nodeConfigSpec.NodeName {
+					found = true
+					break
+				}
+			}
+			if !found {
+				log.V(2).Info("received config for node that has no accelerator - NodeConfig spec will not be generated",
+					"nodeName", nodeConfigSpec.NodeName, "clusterConfig", clusterConfig.Name)
 			}
 		}
+	}
 
-		return false
+	rendered := &renderedNodeConfigs{
+		nodeConfigs: []sriovfecv1.SriovFecNodeConfig{},
+		owners:      map[string]*sriovfecv1.SriovFecClusterConfig{},
+		conflicts:   map[string][]string{},
+		selected:    map[string][]string{},
+		configured:  map[string]map[string][]string{},
+		invalid:     map[string][]string{},
 	}
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+
+		nc, ok := nodeConfigs[node.Name]
+		inventory := nc.Status.Inventory
+		reported := ok && inventoryReported(&nc)
+
+		var owner *sriovfecv1.SriovFecClusterConfig
+		configuredBy := map[string]string{}
+		pfs := []sriovfecv1.PhysicalFunctionConfig{}
+		for j := range clusterConfigs {
+			clusterConfig := &clusterConfigs[j]
+
+			selected := false
+			clusterConfigPFs := []sriovfecv1.PhysicalFunctionConfig{}
+			for _, nodeConfigSpec := range clusterConfig.Spec.Nodes {
+				if nodeSelected(nodeConfigSpec, node) {
+					selected = true
+					clusterConfigPFs = append(clusterConfigPFs, nodeConfigSpec.PhysicalFunctions...)
+				}
+			}
+			if !selected {
+				continue
+			}
+			if owner == nil {
+				owner = clusterConfig
+			}
+			rendered.selected[clusterConfig.Name] = append(rendered.selected[clusterConfig.Name], node.Name)
+			if !reported {
+				continue
+			}
 
-	for _, nodeConfigSpec := range clusterConfig.Spec.Nodes {
-		if !nodeHasAccelerator(nodeConfigSpec.NodeName) {
-			log.V(2).Info("received config for node that has no accelerator - NodeConfig spec will not be generated",
-				"nodeName", nodeConfigSpec.NodeName)
+			for _, pf := range resolvePhysicalFunctions(clusterConfigPFs, inventory, deviceNames) {
+				if configuredBy[pf.PCIAddress] != "" {
+					rendered.conflicts[clusterConfig.Name] = append(rendered.conflicts[clusterConfig.Name],
+						fmt.Sprintf("%s/%s: %s", node.Name, pf.PCIAddress, configuredBy[pf.PCIAddress]))
+					continue
+				}
+				configuredBy[pf.PCIAddress] = clusterConfig.Name
+				if rendered.configured[clusterConfig.Name] == nil {
+					rendered.configured[clusterConfig.Name] = map[string][]string{}
+				}
+				rendered.configured[clusterConfig.Name][node.Name] =
+					append(rendered.configured[clusterConfig.Name][node.Name], pf.PCIAddress)
+				if len(inventory.SriovAccelerators) != 0 {
+					rendered.invalid[clusterConfig.Name] = append(rendered.invalid[clusterConfig.Name],
+						validateCapabilities(node.Name, pf, inventory)...)
+				}
+				pfs = append(pfs, pf)
+			}
+		}
+		if owner == nil {
+			continue
+		}
+		if !reported {
+			log.V(2).Info("inventory of the node not reported yet - NodeConfig spec will not be generated",
+				"nodeName", node.Name)
+			rendered.pending = append(rendered.pending, node.Name)
 			continue
 		}
 
@@ -175,39 +466,50 @@ func (r *SriovFecClusterConfigReconciler) renderNodeConfigs(clusterConfig *sriov
 				Kind:       "SriovFecNodeConfig",
 			},
 			Spec: sriovfecv1.SriovFecNodeConfigSpec{
-				PhysicalFunctions: nodeConfigSpec.PhysicalFunctions,
-				DrainSkip:         clusterConfig.Spec.DrainSkip,
+				PhysicalFunctions: pfs,
+				DrainSkip:         owner.Spec.DrainSkip,
+				DrainPolicy:       owner.Spec.DrainPolicy,
 			},
 		}
-		nodeCfg.SetName(nodeConfigSpec.NodeName)
+		nodeCfg.SetName(node.Name)
 		nodeCfg.SetNamespace(NAMESPACE)
 
-		log.V(2).Info("creating nodeConfig", "nodeName", nodeConfigSpec.NodeName)
+		log.V(2).Info("creating nodeConfig", "nodeName", node.Name)
 
-		nodeConfigs = append(nodeConfigs, nodeCfg)
+		rendered.nodeConfigs = append(rendered.nodeConfigs, nodeCfg)
+		rendered.owners[node.Name] = owner
 	}
 
-	return nodeConfigs
+	return rendered, nil
 }
 
-func (r *SriovFecClusterConfigReconciler) syncNodeConfigs(nodeCfgs []sriovfecv1.SriovFecNodeConfig) error {
+// syncNodeConfigs creates or updates the NodeConfigs and removes the old ones, except the NodeConfigs of the pending
+// nodes. The events are recorded on the owners of the NodeConfigs (by node name). Returns the generations of the
+// written NodeConfigs by node name.
+func (r *SriovFecClusterConfigReconciler) syncNodeConfigs(owners map[string]*sriovfecv1.SriovFecClusterConfig,
+	nodeCfgs []sriovfecv1.SriovFecNodeConfig, pending []string) (map[string]int64, error) {
 	log := r.Log.WithName("syncNodeConfigs")
 	log.V(4).Info("syncing node configs")
 
-	if err := r.removeOldNodeConfigs(nodeCfgs); err != nil {
-		return err
+	if err := r.removeOldNodeConfigs(nodeCfgs, pending); err != nil {
+		return nil, err
 	}
 
+	generations := map[string]int64{}
 	for _, nodeCfg := range nodeCfgs {
-		if err := r.updateOrCreateNodeConfig(nodeCfg); err != nil {
-			return err
+		generation, err := r.updateOrCreateNodeConfig(owners[nodeCfg.Name], nodeCfg)
+		if err != nil {
+			return nil, err
 		}
+		generations[nodeCfg.Name] = generation
 	}
 
-	return nil
+	return generations, nil
 }
 
-func (r *SriovFecClusterConfigReconciler) updateOrCreateNodeConfig(nodeCfg sriovfecv1.SriovFecNodeConfig) error {
+// updateOrCreateNodeConfig writes the NodeConfig and returns its generation
+func (r *SriovFecClusterConfigReconciler) updateOrCreateNodeConfig(clusterConfig *sriovfecv1.SriovFecClusterConfig,
+	nodeCfg sriovfecv1.SriovFecNodeConfig) (int64, error) {
 	log := r.Log.WithName("updateOrCreateNodeConfig")
 	log.V(2).Info("syncing node config", "name", nodeCfg.Name)
 
@@ -221,30 +523,38 @@ func (r *SriovFecClusterConfigReconciler) updateOrCreateNodeConfig(nodeCfg sriov
 			log.V(4).Info("old NodeConfig not found - creating", "name", nodeCfg.Name)
 			if err := r.Create(context.TODO(), &nodeCfg); err != nil {
 				log.Error(err, "failed to create NodeConfig", "name", nodeCfg.Name)
-				return err
+				return 0, err
 			}
-		} else {
-			log.Error(err, "previous NodeConfig Get failed", "name", nodeCfg.Name)
-			return err
+			r.recordEvent(clusterConfig, nodeCfg.Name, corev1.EventTypeNormal, EventNodeConfigCreated,
+				"SriovFecNodeConfig %s created", nodeCfg.Name)
+			return nodeCfg.GetGeneration(), nil
 		}
-	} else {
-		log.V(4).Info("previous NodeConfig found - updating", "name", nodeCfg.Name)
+		log.Error(err, "previous NodeConfig Get failed", "name", nodeCfg.Name)
+		return 0, err
+	}
 
-		prev.Spec = nodeCfg.Spec
-		if err := r.Update(context.TODO(), prev); err != nil {
-			log.Error(err, "failed to update NodeConfig", "name", nodeCfg.Name)
-			return err
-		}
+	log.V(4).Info("previous NodeConfig found - updating", "name", nodeCfg.Name)
+
+	prev.Spec = nodeCfg.Spec
+	generation := prev.GetGeneration()
+	if err := r.Update(context.TODO(), prev); err != nil {
+		log.Error(err, "failed to update NodeConfig", "name", nodeCfg.Name)
+		return 0, err
+	}
+	if prev.GetGeneration() != generation {
+		r.recordEvent(clusterConfig, nodeCfg.Name, corev1.EventTypeNormal, EventNodeConfigUpdated,
+			"SriovFecNodeConfig %s updated", nodeCfg.Name)
 	}
 
-	return nil
+	return prev.GetGeneration(), nil
 }
 
-func (r *SriovFecClusterConfigReconciler) removeOldNodeConfigs(newNodeCfgs []sriovfecv1.SriovFecNodeConfig) error {
+func (r *SriovFecClusterConfigReconciler) removeOldNodeConfigs(newNodeCfgs []sriovfecv1.SriovFecNodeConfig,
+	pending []string) error {
 	log := r.Log.WithName("removeOldNodeConfigs")
 
 	// existing NodeConfigs which are not part of the new ClusterConfig are removed
-	// daemons will deconfigure devices and recreate NodeConfigs with empty spec and filled status
+	// daemons recreate NodeConfigs with empty spec and filled status, and deconfigure the PFs they configured
 
 	ncList := &sriovfecv1.SriovFecNodeConfigList{}
 	if err := r.List(context.TODO(), ncList, &client.ListOptions{}); err != nil && !errors.IsNotFound(err) {
@@ -253,7 +563,19 @@ func (r *SriovFecClusterConfigReconciler) removeOldNodeConfigs(newNodeCfgs []sri
 	}
 
 	for _, nc := range ncList.Items {
+		// the NodeConfigs with empty spec have nothing to deconfigure, they are kept with the inventory
+		// of the node, which is needed to resolve the selectors
+		if len(nc.Spec.PhysicalFunctions) == 0 {
+			continue
+		}
+
 		deleteNC := true
+		for _, name := range pending {
+			if nc.GetName() == name {
+				deleteNC = false
+				break
+			}
+		}
 		for _, nNC := range newNodeCfgs {
 			if nc.GetName() == nNC.GetName() {
 				deleteNC = false
@@ -267,6 +589,8 @@ func (r *SriovFecClusterConfigReconciler) removeOldNodeConfigs(newNodeCfgs []sri
 				log.Error(err, "failed to delete existing NodeConfig", "name", nc.GetName())
 				return err
 			}
+			r.recordEvent(&nc, nc.GetName(), corev1.EventTypeNormal, EventNodeConfigDeleted,
+				"SriovFecNodeConfig %s deleted", nc.GetName())
 		}
 	}
 
@@ -27,9 +27,13 @@ import (
 	sriovv1 "github.com/open-ness/openshift-operator/sriov-fec/api/v1"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 var _ = Describe("SriovControllerTest", func() {
@@ -43,12 +47,26 @@ var _ = Describe("SriovControllerTest", func() {
 			doDeconf       = true
 			removeCluster  = true
 			nodeName       = "node-dummy"
+			configName     = "config"
 			namespacedName = types.NamespacedName{
-				Name:      DEFAULT_CLUSTER_CONFIG_NAME,
+				Name:      configName,
 				Namespace: NAMESPACE,
 			}
 		)
 
+		// reportInventory simulates the daemon of the node creating the NodeConfig with the inventory
+		reportInventory := func(name string) {
+			nc := &sriovv1.SriovFecNodeConfig{
+				ObjectMeta: v1.ObjectMeta{Name: name, Namespace: NAMESPACE},
+				Spec:       sriovv1.SriovFecNodeConfigSpec{PhysicalFunctions: []sriovv1.PhysicalFunctionConfig{}},
+			}
+			Expect(k8sClient.Create(context.TODO(), nc)).To(Succeed())
+			nc.Status.Inventory = sriovv1.NodeInventory{SriovAccelerators: []sriovv1.SriovAccelerator{
+				{VendorID: "8086", DeviceID: "0d5c", PCIAddress: "0000:14:00.1", MaxVFs: 16},
+			}}
+			Expect(k8sClient.Status().Update(context.TODO(), nc)).To(Succeed())
+		}
+
 		BeforeEach(func() {
 			doDeconf = true
 			removeCluster = true
@@ -62,7 +80,7 @@ var _ = Describe("SriovControllerTest", func() {
 			}
 			clusterConfig = &sriovv1.SriovFecClusterConfig{
 				ObjectMeta: v1.ObjectMeta{
-					Name:      DEFAULT_CLUSTER_CONFIG_NAME,
+					Name:      configName,
 					Namespace: NAMESPACE,
 				},
 				Spec: sriovv1.SriovFecClusterConfigSpec{
@@ -72,9 +90,9 @@ var _ = Describe("SriovControllerTest", func() {
 							PhysicalFunctions: []sriovv1.PhysicalFunctionConfig{
 								{
 									PCIAddress: "0000:14:00.1",
-									PFDriver:   "d",
-									VFDriver:   "v",
-									VFAmount:   5,
+									PFDriver:   "pci-pf-stub",
+									VFDriver:   "vfio-pci",
+									VFAmount:   7,
 									BBDevConfig: sriovv1.BBDevConfig{
 										N3000: &sriovv1.N3000BBDevConfig{
 											NetworkType: "FPGA_LTE",
@@ -144,6 +162,7 @@ var _ = Describe("SriovControllerTest", func() {
 			// envtest is empty, create fake node
 			err := k8sClient.Create(context.TODO(), node)
 			Expect(err).ToNot(HaveOccurred())
+			reportInventory(nodeName)
 
 			// simulate creation of cluster config by the user
 			err = k8sClient.Create(context.TODO(), clusterConfig)
@@ -158,7 +177,7 @@ var _ = Describe("SriovControllerTest", func() {
 			request = ctrl.Request{
 				NamespacedName: types.NamespacedName{
 					Namespace: NAMESPACE,
-					Name:      DEFAULT_CLUSTER_CONFIG_NAME,
+					Name:      configName,
 				},
 			}
 
@@ -192,6 +211,8 @@ var _ = Describe("SriovControllerTest", func() {
 			Expect(err).ToNot(HaveOccurred())
 			err = k8sClient.Create(context.TODO(), node2)
 			Expect(err).ToNot(HaveOccurred())
+			reportInventory(nodeName)
+			reportInventory("dummynode2")
 			nodes := &corev1.NodeList{}
 			err = k8sClient.List(context.TODO(), nodes)
 			Expect(err).ToNot(HaveOccurred())
@@ -210,7 +231,7 @@ var _ = Describe("SriovControllerTest", func() {
 			request = ctrl.Request{
 				NamespacedName: types.NamespacedName{
 					Namespace: NAMESPACE,
-					Name:      DEFAULT_CLUSTER_CONFIG_NAME,
+					Name:      configName,
 				},
 			}
 
@@ -222,8 +243,11 @@ var _ = Describe("SriovControllerTest", func() {
 			err = k8sClient.List(context.TODO(), nodeConfigs)
 			Expect(err).ToNot(HaveOccurred())
 
-			Expect(len(nodeConfigs.Items)).To(Equal(1))
-			Expect(nodeConfigs.Items[0].ObjectMeta.Name).To(Equal(nodeName))
+			Expect(len(nodeConfigs.Items)).To(Equal(2))
+			Expect(nodeConfigs.Items[0].ObjectMeta.Name).To(Equal("dummynode2"))
+			Expect(nodeConfigs.Items[0].Spec.PhysicalFunctions).To(BeEmpty())
+			Expect(nodeConfigs.Items[1].ObjectMeta.Name).To(Equal(nodeName))
+			Expect(nodeConfigs.Items[1].Spec.PhysicalFunctions).To(HaveLen(1))
 
 			// switch nodes
 			err = k8sClient.Get(context.TODO(), namespacedName, clusterConfig)
@@ -252,6 +276,7 @@ var _ = Describe("SriovControllerTest", func() {
 
 			err = k8sClient.Create(context.TODO(), node)
 			Expect(err).ToNot(HaveOccurred())
+			reportInventory(nodeName)
 			nodes := &corev1.NodeList{}
 			err = k8sClient.List(context.TODO(), nodes)
 			Expect(err).ToNot(HaveOccurred())
@@ -270,7 +295,7 @@ var _ = Describe("SriovControllerTest", func() {
 			request = ctrl.Request{
 				NamespacedName: types.NamespacedName{
 					Namespace: NAMESPACE,
-					Name:      DEFAULT_CLUSTER_CONFIG_NAME,
+					Name:      configName,
 				},
 			}
 
@@ -319,7 +344,7 @@ var _ = Describe("SriovControllerTest", func() {
 			request = ctrl.Request{
 				NamespacedName: types.NamespacedName{
 					Namespace: NAMESPACE,
-					Name:      DEFAULT_CLUSTER_CONFIG_NAME,
+					Name:      configName,
 				},
 			}
 
@@ -355,7 +380,7 @@ var _ = Describe("SriovControllerTest", func() {
 			request = ctrl.Request{
 				NamespacedName: types.NamespacedName{
 					Namespace: "wrongNamespace",
-					Name:      DEFAULT_CLUSTER_CONFIG_NAME,
+					Name:      configName,
 				},
 			}
 
@@ -369,11 +394,12 @@ var _ = Describe("SriovControllerTest", func() {
 			Expect(len(nodeConfigs.Items)).To(Equal(0))
 		})
 
-		var _ = It("will not create a node because of wrong cluster config name", func() {
+		var _ = It("will handle all cluster configs on request of any cluster config", func() {
 
 			// envtest is empty, create fake node
 			err := k8sClient.Create(context.TODO(), node)
 			Expect(err).ToNot(HaveOccurred())
+			reportInventory(nodeName)
 
 			// simulate creation of cluster config by the user
 			err = k8sClient.Create(context.TODO(), clusterConfig)
@@ -388,7 +414,7 @@ var _ = Describe("SriovControllerTest", func() {
 			request = ctrl.Request{
 				NamespacedName: types.NamespacedName{
 					Namespace: NAMESPACE,
-					Name:      "wrongName",
+					Name:      "other-config",
 				},
 			}
 
@@ -399,7 +425,7 @@ var _ = Describe("SriovControllerTest", func() {
 			nodeConfigs := &sriovv1.SriovFecNodeConfigList{}
 			err = k8sClient.List(context.TODO(), nodeConfigs)
 			Expect(err).ToNot(HaveOccurred())
-			Expect(len(nodeConfigs.Items)).To(Equal(0))
+			Expect(len(nodeConfigs.Items)).To(Equal(1))
 		})
 
 		var _ = It("will 0 nodes", func() {
@@ -422,7 +448,7 @@ var _ = Describe("SriovControllerTest", func() {
 			request = ctrl.Request{
 				NamespacedName: types.NamespacedName{
 					Namespace: NAMESPACE,
-					Name:      DEFAULT_CLUSTER_CONFIG_NAME,
+					Name:      configName,
 				},
 			}
 
@@ -443,6 +469,7 @@ var _ = Describe("SriovControllerTest", func() {
 			// envtest is empty, create fake node
 			err := k8sClient.Create(context.TODO(), node)
 			Expect(err).ToNot(HaveOccurred())
+			reportInventory(nodeName)
 
 			// simulate creation of cluster config by the user
 			err = k8sClient.Create(context.TODO(), clusterConfig)
@@ -457,7 +484,7 @@ var _ = Describe("SriovControllerTest", func() {
 			request = ctrl.Request{
 				NamespacedName: types.NamespacedName{
 					Namespace: NAMESPACE,
-					Name:      DEFAULT_CLUSTER_CONFIG_NAME,
+					Name:      configName,
 				},
 			}
 
@@ -482,6 +509,233 @@ var _ = Describe("SriovControllerTest", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 	})
+	var _ = Describe("Prioritized cluster configs", func() {
+		var (
+			c          client.Client
+			reconciler SriovFecClusterConfigReconciler
+			node       = &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: "node1", Labels: map[string]string{
+				"fpga.intel.com/intel-accelerator-present": "", "sku": "du"}}}
+		)
+		clusterConfig := func(name string, priority int, nodeConfig sriovv1.NodeConfig) *sriovv1.SriovFecClusterConfig {
+			return &sriovv1.SriovFecClusterConfig{
+				ObjectMeta: v1.ObjectMeta{Name: name, Namespace: NAMESPACE},
+				Spec:       sriovv1.SriovFecClusterConfigSpec{Priority: priority, Nodes: []sriovv1.NodeConfig{nodeConfig}},
+			}
+		}
+		getClusterConfig := func(name string) *sriovv1.SriovFecClusterConfig {
+			cc := &sriovv1.SriovFecClusterConfig{}
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: name}, cc)).To(Succeed())
+			return cc
+		}
+
+		BeforeEach(func() {
+			s := runtime.NewScheme()
+			Expect(scheme.AddToScheme(s)).To(Succeed())
+			Expect(sriovv1.AddToScheme(s)).To(Succeed())
+			c = fake.NewClientBuilder().WithScheme(s).Build()
+			reconciler = SriovFecClusterConfigReconciler{Client: c, Scheme: s, Log: ctrl.Log.WithName("SriovController-test")}
+
+			Expect(c.Create(context.TODO(), node.DeepCopy())).To(Succeed())
+			Expect(c.Create(context.TODO(), &sriovv1.SriovFecNodeConfig{
+				ObjectMeta: v1.ObjectMeta{Name: "node1", Namespace: NAMESPACE},
+				Status: sriovv1.SriovFecNodeConfigStatus{Inventory: sriovv1.NodeInventory{
+					SriovAccelerators: []sriovv1.SriovAccelerator{
+						{VendorID: "8086", DeviceID: "0d5c", PCIAddress: "0000:14:00.1", MaxVFs: 16},
+						{VendorID: "8086", DeviceID: "0d5c", PCIAddress: "0000:1b:00.0", MaxVFs: 16},
+					},
+				}},
+			})).To(Succeed())
+		})
+
+		var _ = It("will merge the cluster configs by priority and report the conflicts", func() {
+			anyPF := sriovv1.NodeConfig{NodeSelector: &v1.LabelSelector{MatchLabels: map[string]string{"sku": "du"}},
+				PhysicalFunctions: []sriovv1.PhysicalFunctionConfig{
+					{AcceleratorSelector: &sriovv1.AcceleratorSelector{}, PFDriver: "pci-pf-stub", VFDriver: "vfio-pci",
+						VFAmount: 2},
+				}}
+			onePF := sriovv1.NodeConfig{NodeName: "node1",
+				PhysicalFunctions: []sriovv1.PhysicalFunctionConfig{{PCIAddress: "0000:14:00.1", PFDriver: "pci-pf-stub",
+					VFDriver: "vfio-pci", VFAmount: 1}}}
+
+			Expect(c.Create(context.TODO(), clusterConfig("fleet", 10, anyPF))).To(Succeed())
+			Expect(c.Create(context.TODO(), clusterConfig("node1", 0, onePF))).To(Succeed())
+			Expect(c.Create(context.TODO(), clusterConfig("shadowed", 20, onePF))).To(Succeed())
+
+			_, err := reconciler.Reconcile(context.TODO(),
+				ctrl.Request{NamespacedName: types.NamespacedName{Namespace: NAMESPACE, Name: "fleet"}})
+			Expect(err).ToNot(HaveOccurred())
+
+			nc := &sriovv1.SriovFecNodeConfig{}
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: "node1"}, nc)).To(Succeed())
+			Expect(nc.Spec.PhysicalFunctions).To(Equal([]sriovv1.PhysicalFunctionConfig{
+				{PCIAddress: "0000:14:00.1", PFDriver: "pci-pf-stub", VFDriver: "vfio-pci", VFAmount: 1},
+				{PCIAddress: "0000:1b:00.0", PFDriver: "pci-pf-stub", VFDriver: "vfio-pci", VFAmount: 2},
+			}))
+
+			Expect(getClusterConfig("node1").Status.SyncStatus).To(Equal(sriovv1.InProgressSync))
+			Expect(getClusterConfig("node1").Status.Conflicts).To(BeEmpty())
+			Expect(getClusterConfig("fleet").Status.SyncStatus).To(Equal(sriovv1.InProgressSync))
+			Expect(getClusterConfig("fleet").Status.Conflicts).To(Equal([]string{"node1/0000:14:00.1: node1"}))
+			Expect(getClusterConfig("shadowed").Status.SyncStatus).To(Equal(sriovv1.ShadowedSync))
+			Expect(getClusterConfig("shadowed").Status.Conflicts).To(Equal([]string{"node1/0000:14:00.1: node1"}))
+		})
+		var _ = It("will order the cluster configs of equal priority by name", func() {
+			Expect(c.Create(context.TODO(), clusterConfig("b", 1, sriovv1.NodeConfig{}))).To(Succeed())
+			Expect(c.Create(context.TODO(), clusterConfig("a", 1, sriovv1.NodeConfig{}))).To(Succeed())
+			Expect(c.Create(context.TODO(), clusterConfig("c", 0, sriovv1.NodeConfig{}))).To(Succeed())
+
+			clusterConfigs, err := reconciler.getClusterConfigs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(clusterConfigs).To(HaveLen(3))
+			Expect([]string{clusterConfigs[0].Name, clusterConfigs[1].Name, clusterConfigs[2].Name}).
+				To(Equal([]string{"c", "a", "b"}))
+			Expect(reconciler.clusterConfigRequests(node)).To(Equal([]reconcile.Request{
+				{NamespacedName: types.NamespacedName{Namespace: NAMESPACE, Name: "c"}},
+			}))
+		})
+	})
+	var _ = Describe("Cluster config status", func() {
+		var (
+			c          client.Client
+			reconciler SriovFecClusterConfigReconciler
+			request    = ctrl.Request{NamespacedName: types.NamespacedName{Namespace: NAMESPACE, Name: "config"}}
+			pf         = sriovv1.PhysicalFunctionConfig{PCIAddress: "0000:14:00.1", PFDriver: "pci-pf-stub",
+				VFDriver: "vfio-pci", VFAmount: 2}
+		)
+		createNode := func(name string) {
+			Expect(c.Create(context.TODO(), &corev1.Node{ObjectMeta: v1.ObjectMeta{Name: name,
+				Labels: map[string]string{"fpga.intel.com/intel-accelerator-present": ""}}})).To(Succeed())
+			Expect(c.Create(context.TODO(), &sriovv1.SriovFecNodeConfig{
+				ObjectMeta: v1.ObjectMeta{Name: name, Namespace: NAMESPACE},
+				Status: sriovv1.SriovFecNodeConfigStatus{Inventory: sriovv1.NodeInventory{
+					SriovAccelerators: []sriovv1.SriovAccelerator{{PCIAddress: "0000:14:00.1", MaxVFs: 16,
+						VFs: []sriovv1.VF{{PCIAddress: "0000:15:00.0"}, {PCIAddress: "0000:15:00.1"}}}},
+				}},
+			})).To(Succeed())
+		}
+		// setConfigured simulates the daemon setting the Configured condition
+		setConfigured := func(name, reason string) {
+			nc := &sriovv1.SriovFecNodeConfig{}
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: name}, nc)).To(Succeed())
+			nc.Status.Conditions = []v1.Condition{{Type: "Configured", Status: v1.ConditionFalse, Reason: reason,
+				Message: reason, ObservedGeneration: nc.GetGeneration(), LastTransitionTime: v1.Now()}}
+			Expect(c.Status().Update(context.TODO(), nc)).To(Succeed())
+		}
+		getStatus := func() sriovv1.SriovFecClusterConfigStatus {
+			_, err := reconciler.Reconcile(context.TODO(), request)
+			Expect(err).ToNot(HaveOccurred())
+			cc := &sriovv1.SriovFecClusterConfig{}
+			Expect(c.Get(context.TODO(), request.NamespacedName, cc)).To(Succeed())
+			return cc.Status
+		}
+
+		BeforeEach(func() {
+			s := runtime.NewScheme()
+			Expect(scheme.AddToScheme(s)).To(Succeed())
+			Expect(sriovv1.AddToScheme(s)).To(Succeed())
+			c = fake.NewClientBuilder().WithScheme(s).Build()
+			reconciler = SriovFecClusterConfigReconciler{Client: c, Scheme: s, Log: ctrl.Log.WithName("SriovController-test")}
+
+			createNode("node1")
+			createNode("node2")
+			Expect(c.Create(context.TODO(), &sriovv1.SriovFecClusterConfig{
+				ObjectMeta: v1.ObjectMeta{Name: "config", Namespace: NAMESPACE},
+				Spec: sriovv1.SriovFecClusterConfigSpec{Nodes: []sriovv1.NodeConfig{
+					{NodeName: "node1", PhysicalFunctions: []sriovv1.PhysicalFunctionConfig{pf}},
+					{NodeName: "node2", PhysicalFunctions: []sriovv1.PhysicalFunctionConfig{pf}},
+				}},
+			})).To(Succeed())
+		})
+
+		var _ = It("will succeed once all the nodes applied the config", func() {
+			status := getStatus()
+			Expect(status.SyncStatus).To(Equal(sriovv1.InProgressSync))
+			Expect(status.Nodes).To(HaveLen(2))
+
+			setConfigured("node1", "Succeeded")
+			status = getStatus()
+			Expect(status.SyncStatus).To(Equal(sriovv1.InProgressSync))
+			Expect(status.Nodes[0]).To(Equal(sriovv1.NodeSyncStatus{NodeName: "node1", SyncStatus: sriovv1.SucceededSync,
+				Message: "Succeeded", PFs: 1, VFs: 2, AppliedConfigs: []sriovv1.PhysicalFunctionConfig{pf}}))
+			Expect(status.Nodes[1].SyncStatus).To(Equal(sriovv1.InProgressSync))
+
+			setConfigured("node2", "Succeeded")
+			Expect(getStatus().SyncStatus).To(Equal(sriovv1.SucceededSync))
+		})
+		var _ = It("will fail if any node failed to apply the config", func() {
+			setConfigured("node1", "Succeeded")
+			setConfigured("node2", "Failed")
+			status := getStatus()
+			Expect(status.SyncStatus).To(Equal(sriovv1.FailedSync))
+			Expect(status.LastSyncError).To(Equal("configuration failed on nodes: node2"))
+			Expect(status.Nodes[1].AppliedConfigs).To(BeEmpty())
+		})
+		var _ = It("will requeue until the node reported its inventory", func() {
+			nc := &sriovv1.SriovFecNodeConfig{}
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: "node2"}, nc)).To(Succeed())
+			nc.Status.Inventory = sriovv1.NodeInventory{}
+			Expect(c.Status().Update(context.TODO(), nc)).To(Succeed())
+
+			result, err := reconciler.Reconcile(context.TODO(), request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(reconcile.Result{RequeueAfter: inventoryRequeuePeriod}))
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: "node2"}, nc)).To(Succeed())
+			Expect(nc.Spec.PhysicalFunctions).To(BeEmpty())
+			status := getStatus()
+			Expect(status.Nodes[0].SyncStatus).To(Equal(sriovv1.InProgressSync))
+			Expect(status.Nodes[1]).To(Equal(sriovv1.NodeSyncStatus{NodeName: "node2", SyncStatus: sriovv1.InProgressSync,
+				Message: "inventory of the node not reported yet"}))
+
+			nc.Status.Inventory = sriovv1.NodeInventory{
+				SriovAccelerators: []sriovv1.SriovAccelerator{{PCIAddress: "0000:14:00.1", MaxVFs: 16}}}
+			Expect(c.Status().Update(context.TODO(), nc)).To(Succeed())
+			result, err = reconciler.Reconcile(context.TODO(), request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(reconcile.Result{}))
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: "node2"}, nc)).To(Succeed())
+			Expect(nc.Spec.PhysicalFunctions).To(Equal([]sriovv1.PhysicalFunctionConfig{pf}))
+		})
+		var _ = It("will fail the invalid config and keep the node configs", func() {
+			Expect(getStatus().SyncStatus).To(Equal(sriovv1.InProgressSync))
+
+			cc := &sriovv1.SriovFecClusterConfig{}
+			Expect(c.Get(context.TODO(), request.NamespacedName, cc)).To(Succeed())
+			cc.Spec.Nodes[0].PhysicalFunctions[0].VFDriver = "v"
+			cc.Spec.Nodes[1].PhysicalFunctions[0].VFAmount = 32
+			Expect(c.Update(context.TODO(), cc)).To(Succeed())
+
+			status := getStatus()
+			Expect(status.SyncStatus).To(Equal(sriovv1.FailedSync))
+			Expect(status.LastSyncError).To(Equal(`invalid config: vfDriver "v" is not one of [vfio-pci igb_uio]; ` +
+				"node2/0000:14:00.1: vfAmount (32) exceeds the maximum number of VFs of the PF (16)"))
+			for _, name := range []string{"node1", "node2"} {
+				nc := &sriovv1.SriovFecNodeConfig{}
+				Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: name}, nc)).To(Succeed())
+				Expect(nc.Spec.PhysicalFunctions).To(Equal([]sriovv1.PhysicalFunctionConfig{pf}))
+			}
+		})
+		var _ = It("will not report the condition of the previous spec", func() {
+			nc := &sriovv1.SriovFecNodeConfig{}
+			setConfigured("node1", "Succeeded")
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: "node1"}, nc)).To(Succeed())
+			Expect(nodeSyncStatus("node1", []string{pf.PCIAddress}, nc, nc.GetGeneration()+1).SyncStatus).
+				To(Equal(sriovv1.InProgressSync))
+		})
+		var _ = It("will report the queue config applied to the PF", func() {
+			nc := &sriovv1.SriovFecNodeConfig{}
+			setConfigured("node1", "Succeeded")
+			Expect(c.Get(context.TODO(), types.NamespacedName{Namespace: NAMESPACE, Name: "node1"}, nc)).To(Succeed())
+			nc.Spec.PhysicalFunctions = []sriovv1.PhysicalFunctionConfig{pf}
+			nc.Spec.PhysicalFunctions[0].BBDevConfig.ACC100 = &sriovv1.ACC100BBDevConfig{NumVfBundles: 16}
+			applied := sriovv1.BBDevConfig{ACC100: &sriovv1.ACC100BBDevConfig{NumVfBundles: 16, MaxQueueSize: 1024}}
+			nc.Status.Inventory.SriovAccelerators[0].QueueConfig = &applied
+
+			status := nodeSyncStatus("node1", []string{pf.PCIAddress}, nc, nc.GetGeneration())
+			Expect(status.SyncStatus).To(Equal(sriovv1.SucceededSync))
+			Expect(status.AppliedConfigs).To(HaveLen(1))
+			Expect(status.AppliedConfigs[0].BBDevConfig).To(Equal(applied))
+		})
+	})
 	var _ = Describe("Reconciler manager", func() {
 		var _ = It("setup with invalid manager", func() {
 			var m ctrl.Manager
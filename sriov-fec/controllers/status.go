@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	sriovfecv1 "github.com/open-ness/openshift-operator/sriov-fec/api/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+const (
+	// Condition of the SriovFecNodeConfig set by the daemon and its reasons
+	nodeConfiguredCondition    = "Configured"
+	nodeConfiguredSucceeded    = "Succeeded"
+	nodeConfiguredNotRequested = "NotRequested"
+	nodeConfiguredFailed       = "Failed"
+)
+
+// nodeSyncStatus returns the status of the configuration of the PFs (by PCI address) on the node, nc is nil if
+// the daemon didn't report the node's inventory yet. The condition of nc is taken into account only if the daemon
+// observed the generation written by the controller, nc may be a stale copy from the cache.
+func nodeSyncStatus(nodeName string, pciAddresses []string, nc *sriovfecv1.SriovFecNodeConfig,
+	generation int64) sriovfecv1.NodeSyncStatus {
+	status := sriovfecv1.NodeSyncStatus{
+		NodeName:   nodeName,
+		SyncStatus: sriovfecv1.InProgressSync,
+		PFs:        len(pciAddresses),
+	}
+	if nc == nil {
+		status.Message = "inventory of the node not reported yet"
+		return status
+	}
+
+	configured := map[string]bool{}
+	for _, pci := range pciAddresses {
+		configured[pci] = true
+	}
+	inventory := map[string]sriovfecv1.SriovAccelerator{}
+	for _, acc := range nc.Status.Inventory.SriovAccelerators {
+		if configured[acc.PCIAddress] {
+			status.VFs += len(acc.VFs)
+			inventory[acc.PCIAddress] = acc
+		}
+	}
+
+	// the condition is for the current spec only if its generation was observed
+	condition := meta.FindStatusCondition(nc.Status.Conditions, nodeConfiguredCondition)
+	if condition == nil {
+		return status
+	}
+	status.Message = condition.Message
+	if condition.ObservedGeneration != generation {
+		return status
+	}
+
+	switch condition.Reason {
+	case nodeConfiguredSucceeded, nodeConfiguredNotRequested:
+		status.SyncStatus = sriovfecv1.SucceededSync
+		// the queue config is the one saved by the daemon when it was applied with pf_bb_config, not the requested one
+		for _, pf := range nc.Spec.PhysicalFunctions {
+			acc, ok := inventory[pf.PCIAddress]
+			if !ok {
+				continue
+			}
+			pf.BBDevConfig = sriovfecv1.BBDevConfig{}
+			if acc.QueueConfig != nil {
+				pf.BBDevConfig = *acc.QueueConfig.DeepCopy()
+			}
+			status.AppliedConfigs = append(status.AppliedConfigs, pf)
+		}
+	case nodeConfiguredFailed:
+		status.SyncStatus = sriovfecv1.FailedSync
+	}
+	return status
+}
+
+// clusterSyncStatus returns the status of the cluster config and the error: Succeeded only if all the nodes
+// applied the config, Failed if any of the nodes failed, InProgress otherwise
+func clusterSyncStatus(nodes []sriovfecv1.NodeSyncStatus) (sriovfecv1.SyncStatus, string) {
+	failed := []string{}
+	inProgress := false
+	for _, node := range nodes {
+		switch node.SyncStatus {
+		case sriovfecv1.FailedSync:
+			failed = append(failed, node.NodeName)
+		case sriovfecv1.InProgressSync:
+			inProgress = true
+		}
+	}
+
+	if len(failed) != 0 {
+		return sriovfecv1.FailedSync, fmt.Sprintf("configuration failed on nodes: %s", strings.Join(failed, ", "))
+	}
+	if inProgress {
+		return sriovfecv1.InProgressSync, ""
+	}
+	return sriovfecv1.SucceededSync, ""
+}
@@ -0,0 +1,205 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package controllers
+
+import (
+	"fmt"
+
+	sriovfecv1 "github.com/open-ness/openshift-operator/sriov-fec/api/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	// drivers the PFs and VFs can be bound to
+	allowedPFDrivers = []string{"pci-pf-stub", "pci_pf_stub", "igb_uio", "vfio-pci"}
+	allowedVFDrivers = []string{"vfio-pci", "igb_uio"}
+	// drivers the individual VFs can be bound to, besides the vfDrivers also the kernel drivers
+	allowedPerVFDrivers = []string{"vfio-pci", "igb_uio", "uio_pci_generic", "pci-pf-stub"}
+)
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func queuesPerVF(q sriovfecv1.UplinkDownlinkQueues) []int {
+	return []int{q.VF0, q.VF1, q.VF2, q.VF3, q.VF4, q.VF5, q.VF6, q.VF7}
+}
+
+// validateNodeConfig checks that either the node's name or a valid selector is set
+func validateNodeConfig(nodeConfig sriovfecv1.NodeConfig) []string {
+	if nodeConfig.NodeName == "" && nodeConfig.NodeSelector == nil {
+		return []string{"either nodeName or nodeSelector has to be set"}
+	}
+	if nodeConfig.NodeSelector != nil {
+		if _, err := v1.LabelSelectorAsSelector(nodeConfig.NodeSelector); err != nil {
+			return []string{fmt.Sprintf("invalid nodeSelector: %v", err)}
+		}
+	}
+	return nil
+}
+
+// validateClusterConfig checks the node configs and the PF configs of the cluster config, which don't depend on
+// the nodes. Returns the list of the violations.
+func validateClusterConfig(clusterConfig *sriovfecv1.SriovFecClusterConfig) []string {
+	errs := []string{}
+	for _, nodeConfig := range clusterConfig.Spec.Nodes {
+		errs = append(errs, validateNodeConfig(nodeConfig)...)
+		for _, pf := range nodeConfig.PhysicalFunctions {
+			errs = append(errs, validatePhysicalFunction(pf)...)
+		}
+	}
+	return errs
+}
+
+// validatePhysicalFunction checks the drivers and the queues of the PF config, which don't depend on the device.
+// Returns the list of the violations.
+func validatePhysicalFunction(pf sriovfecv1.PhysicalFunctionConfig) []string {
+	errs := []string{}
+	if pf.PCIAddress == "" && pf.AcceleratorSelector == nil {
+		errs = append(errs, "either pciAddress or acceleratorSelector has to be set")
+	}
+	if !containsString(allowedPFDrivers, pf.PFDriver) {
+		errs = append(errs, fmt.Sprintf("pfDriver %q is not one of %v", pf.PFDriver, allowedPFDrivers))
+	}
+	if !containsString(allowedVFDrivers, pf.VFDriver) {
+		errs = append(errs, fmt.Sprintf("vfDriver %q is not one of %v", pf.VFDriver, allowedVFDrivers))
+	}
+
+	errs = append(errs, validateVFConfigs(pf)...)
+
+	// the queues of N3000 are validated with the per-VF queues applied
+	bbDevConfig := pf.GetBBDevConfig()
+	configs := 0
+	if bbDevConfig.N3000 != nil {
+		configs++
+		errs = append(errs, validateN3000BBDevConfig(bbDevConfig.N3000, pf.VFAmount)...)
+	}
+	if bbDevConfig.ACC100 != nil {
+		configs++
+		errs = append(errs, validateACC100BBDevConfig(bbDevConfig.ACC100, pf.VFAmount)...)
+	}
+	if bbDevConfig.ACC200 != nil {
+		configs++
+		errs = append(errs, validateACC200BBDevConfig(bbDevConfig.ACC200, pf.VFAmount)...)
+	}
+	if configs > 1 {
+		errs = append(errs, "at most one of bbDevConfig.n3000, bbDevConfig.acc100 and bbDevConfig.acc200 can be set")
+	}
+	return errs
+}
+
+// validateVFConfigs checks that the per-VF configs refer to distinct VFs of the PF, their drivers are allowed
+// and the per-VF queues are set only for N3000, which has queues assigned to the individual VFs. ACC100 and
+// ACC200 assign the queue groups to the VF bundles evenly, so their VFs can't have own queues.
+func validateVFConfigs(pf sriovfecv1.PhysicalFunctionConfig) []string {
+	errs := []string{}
+	indexes := map[int]bool{}
+	for _, vf := range pf.VFs {
+		if vf.Index < 0 || vf.Index >= pf.VFAmount {
+			errs = append(errs, fmt.Sprintf("vfs: index %d is out of range of vfAmount (%d)", vf.Index, pf.VFAmount))
+		}
+		if indexes[vf.Index] {
+			errs = append(errs, fmt.Sprintf("vfs: index %d is configured more than once", vf.Index))
+		}
+		indexes[vf.Index] = true
+
+		if vf.Driver != "" && !containsString(allowedPerVFDrivers, vf.Driver) {
+			errs = append(errs, fmt.Sprintf("vfs[%d]: driver %q is not one of %v", vf.Index, vf.Driver,
+				allowedPerVFDrivers))
+		}
+		if vf.Queues != nil {
+			if pf.BBDevConfig.N3000 == nil {
+				errs = append(errs, fmt.Sprintf("vfs[%d]: queues can be set only for N3000", vf.Index))
+			} else if vf.Index >= len(queuesPerVF(pf.BBDevConfig.N3000.Uplink.Queues)) {
+				errs = append(errs, fmt.Sprintf("vfs[%d]: queues can be set only for the first %d VFs of N3000",
+					vf.Index, len(queuesPerVF(pf.BBDevConfig.N3000.Uplink.Queues))))
+			}
+		}
+	}
+	return errs
+}
+
+// validateN3000BBDevConfig checks that the queues fit into the device and (unless in PF mode)
+// that the number of the VFs with queues matches vfAmount
+func validateN3000BBDevConfig(cfg *sriovfecv1.N3000BBDevConfig, vfAmount int) []string {
+	errs := []string{}
+	uplink, downlink := queuesPerVF(cfg.Uplink.Queues), queuesPerVF(cfg.Downlink.Queues)
+
+	uplinkTotal, downlinkTotal, vfsWithQueues := 0, 0, 0
+	for i := range uplink {
+		uplinkTotal += uplink[i]
+		downlinkTotal += downlink[i]
+		if uplink[i]+downlink[i] > 0 {
+			vfsWithQueues++
+		}
+	}
+	if uplinkTotal > sriovfecv1.MaxN3000Queues {
+		errs = append(errs, fmt.Sprintf("total number of uplink queues (%d) exceeds the maximum (%d)",
+			uplinkTotal, sriovfecv1.MaxN3000Queues))
+	}
+	if downlinkTotal > sriovfecv1.MaxN3000Queues {
+		errs = append(errs, fmt.Sprintf("total number of downlink queues (%d) exceeds the maximum (%d)",
+			downlinkTotal, sriovfecv1.MaxN3000Queues))
+	}
+	if !cfg.PFMode && vfsWithQueues != vfAmount {
+		errs = append(errs, fmt.Sprintf("number of VFs with queues (%d) doesn't match vfAmount (%d)",
+			vfsWithQueues, vfAmount))
+	}
+	return errs
+}
+
+// validateACC100BBDevConfig checks that the queue groups fit into the device and (unless in PF mode)
+// that there is a VF bundle for every VF
+func validateACC100BBDevConfig(cfg *sriovfecv1.ACC100BBDevConfig, vfAmount int) []string {
+	errs := []string{}
+	total := cfg.Uplink4G.NumQueueGroups + cfg.Downlink4G.NumQueueGroups +
+		cfg.Uplink5G.NumQueueGroups + cfg.Downlink5G.NumQueueGroups
+	if total > sriovfecv1.MaxACC100QueueGroups {
+		errs = append(errs, fmt.Sprintf("total number of queue groups (%d) exceeds the maximum (%d)",
+			total, sriovfecv1.MaxACC100QueueGroups))
+	}
+	if !cfg.PFMode && vfAmount > cfg.NumVfBundles {
+		errs = append(errs, fmt.Sprintf("vfAmount (%d) exceeds the number of VF bundles (%d)",
+			vfAmount, cfg.NumVfBundles))
+	}
+	return errs
+}
+
+// validateACC200BBDevConfig checks that the queue groups fit into the device and (unless in PF mode)
+// that there is a VF bundle for every VF
+func validateACC200BBDevConfig(cfg *sriovfecv1.ACC200BBDevConfig, vfAmount int) []string {
+	errs := []string{}
+	total := cfg.Uplink4G.NumQueueGroups + cfg.Downlink4G.NumQueueGroups +
+		cfg.Uplink5G.NumQueueGroups + cfg.Downlink5G.NumQueueGroups + cfg.QFFT.NumQueueGroups
+	if total > sriovfecv1.MaxACC200QueueGroups {
+		errs = append(errs, fmt.Sprintf("total number of queue groups (%d) exceeds the maximum (%d)",
+			total, sriovfecv1.MaxACC200QueueGroups))
+	}
+	if !cfg.PFMode && vfAmount > cfg.NumVfBundles {
+		errs = append(errs, fmt.Sprintf("vfAmount (%d) exceeds the number of VF bundles (%d)",
+			vfAmount, cfg.NumVfBundles))
+	}
+	return errs
+}
+
+// validateCapabilities checks the PF config against the PF reported in the node's inventory
+func validateCapabilities(nodeName string, pf sriovfecv1.PhysicalFunctionConfig,
+	inventory sriovfecv1.NodeInventory) []string {
+	for _, acc := range inventory.SriovAccelerators {
+		if acc.PCIAddress != pf.PCIAddress {
+			continue
+		}
+		if pf.VFAmount > acc.MaxVFs {
+			return []string{fmt.Sprintf("%s/%s: vfAmount (%d) exceeds the maximum number of VFs of the PF (%d)",
+				nodeName, pf.PCIAddress, pf.VFAmount, acc.MaxVFs)}
+		}
+		return nil
+	}
+	return []string{fmt.Sprintf("%s/%s: PF not found in the inventory of the node", nodeName, pf.PCIAddress)}
+}
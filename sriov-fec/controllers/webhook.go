@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/go-logr/logr"
+	sriovfecv1 "github.com/open-ness/openshift-operator/sriov-fec/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	ValidateClusterConfigPath = "/validate-sriovfec-intel-com-v1-sriovfecclusterconfig"
+	ValidateNodeConfigPath    = "/validate-sriovfec-intel-com-v1-sriovfecnodeconfig"
+)
+
+// +kubebuilder:webhook:path=/validate-sriovfec-intel-com-v1-sriovfecclusterconfig,mutating=false,failurePolicy=fail,sideEffects=None,groups=sriovfec.intel.com,resources=sriovfecclusterconfigs,verbs=create;update,versions=v1,name=vsriovfecclusterconfig.kb.io,admissionReviewVersions={v1,v1beta1}
+
+// SriovFecClusterConfigValidator validates the SriovFecClusterConfigs against the capabilities of the devices
+type SriovFecClusterConfigValidator struct {
+	client.Client
+	Log     logr.Logger
+	decoder *admission.Decoder
+}
+
+// InjectDecoder injects the decoder of the admission requests
+func (v *SriovFecClusterConfigValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// Handle validates the PF configs of the cluster config. The PFs configured by the cluster config (merged with
+// the other cluster configs by precedence) are also validated against the inventories reported by the nodes.
+func (v *SriovFecClusterConfigValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	log := v.Log.WithValues("sriovfecclusterconfig", req.Namespace+"/"+req.Name)
+
+	clusterConfig := &sriovfecv1.SriovFecClusterConfig{}
+	if err := v.decoder.Decode(req, clusterConfig); err != nil {
+		log.Error(err, "failed to decode the request")
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if errs := validateClusterConfig(clusterConfig); len(errs) != 0 {
+		return admission.Denied(strings.Join(errs, "; "))
+	}
+
+	// cluster configs from other namespaces are not applied
+	if req.Namespace != NAMESPACE {
+		return admission.Allowed("")
+	}
+
+	capabilityErrs, err := v.validateCapabilities(clusterConfig)
+	if err != nil {
+		log.Error(err, "failed to validate the cluster config against the inventories of the nodes")
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(capabilityErrs) != 0 {
+		return admission.Denied(strings.Join(capabilityErrs, "; "))
+	}
+	return admission.Allowed("")
+}
+
+// validateCapabilities renders the node configs with the cluster config replacing its current version and
+// validates the PFs configured by the cluster config against the inventories of the nodes. The PFs of the
+// nodes, which didn't report the inventory yet, are not validated.
+func (v *SriovFecClusterConfigValidator) validateCapabilities(
+	clusterConfig *sriovfecv1.SriovFecClusterConfig) ([]string, error) {
+
+	r := &SriovFecClusterConfigReconciler{Client: v.Client, Log: v.Log}
+
+	clusterConfigs, err := r.getClusterConfigs()
+	if err != nil {
+		return nil, err
+	}
+	replaced := false
+	for i := range clusterConfigs {
+		if clusterConfigs[i].Name == clusterConfig.Name {
+			clusterConfigs[i] = *clusterConfig
+			replaced = true
+		}
+	}
+	if !replaced {
+		clusterConfigs = append(clusterConfigs, *clusterConfig)
+	}
+	sortClusterConfigs(clusterConfigs)
+
+	nodeList, err := r.getNodesWithIntelAccelerator()
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := r.renderNodeConfigs(clusterConfigs, nodeList)
+	if err != nil {
+		return nil, err
+	}
+	return rendered.invalid[clusterConfig.Name], nil
+}
+
+// +kubebuilder:webhook:path=/validate-sriovfec-intel-com-v1-sriovfecnodeconfig,mutating=false,failurePolicy=fail,sideEffects=None,groups=sriovfec.intel.com,resources=sriovfecnodeconfigs,verbs=create;update,versions=v1,name=vsriovfecnodeconfig.kb.io,admissionReviewVersions={v1,v1beta1}
+
+// SriovFecNodeConfigValidator validates the SriovFecNodeConfigs against the capabilities of the devices
+type SriovFecNodeConfigValidator struct {
+	Log     logr.Logger
+	decoder *admission.Decoder
+}
+
+// InjectDecoder injects the decoder of the admission requests
+func (v *SriovFecNodeConfigValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// Handle validates the PF configs of the node config, also against the inventory of the node if it's reported
+func (v *SriovFecNodeConfigValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	log := v.Log.WithValues("sriovfecnodeconfig", req.Namespace+"/"+req.Name)
+
+	nodeConfig := &sriovfecv1.SriovFecNodeConfig{}
+	if err := v.decoder.Decode(req, nodeConfig); err != nil {
+		log.Error(err, "failed to decode the request")
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	errs := []string{}
+	inventory := nodeConfig.Status.Inventory
+	for _, pf := range nodeConfig.Spec.PhysicalFunctions {
+		if pf.PCIAddress == "" {
+			errs = append(errs, "pciAddress has to be set")
+			continue
+		}
+		errs = append(errs, validatePhysicalFunction(pf)...)
+		if len(inventory.SriovAccelerators) != 0 {
+			errs = append(errs, validateCapabilities(nodeConfig.Name, pf, inventory)...)
+		}
+	}
+	if len(errs) != 0 {
+		return admission.Denied(strings.Join(errs, "; "))
+	}
+	return admission.Allowed("")
+}
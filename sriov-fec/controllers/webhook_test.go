@@ -0,0 +1,236 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	sriovv1 "github.com/open-ness/openshift-operator/sriov-fec/api/v1"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("Validating webhooks", func() {
+	var c client.Client
+	var decoder *admission.Decoder
+
+	n3000PF := func(pci string, vfs int, uplinkVF0 int) sriovv1.PhysicalFunctionConfig {
+		return sriovv1.PhysicalFunctionConfig{
+			PCIAddress: pci,
+			PFDriver:   "pci-pf-stub",
+			VFDriver:   "vfio-pci",
+			VFAmount:   vfs,
+			BBDevConfig: sriovv1.BBDevConfig{N3000: &sriovv1.N3000BBDevConfig{
+				NetworkType: "FPGA_5GNR",
+				Uplink:      sriovv1.UplinkDownlink{Bandwidth: 8, LoadBalance: 128, Queues: sriovv1.UplinkDownlinkQueues{VF0: uplinkVF0, VF1: 16}},
+				Downlink:    sriovv1.UplinkDownlink{Bandwidth: 8, LoadBalance: 128, Queues: sriovv1.UplinkDownlinkQueues{VF0: 16, VF1: 16}},
+			}},
+		}
+	}
+	request := func(obj runtime.Object, name string) admission.Request {
+		raw, err := json.Marshal(obj)
+		Expect(err).ToNot(HaveOccurred())
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Name:      name,
+			Namespace: NAMESPACE,
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: raw},
+		}}
+	}
+
+	BeforeEach(func() {
+		s := runtime.NewScheme()
+		Expect(scheme.AddToScheme(s)).To(Succeed())
+		Expect(sriovv1.AddToScheme(s)).To(Succeed())
+		c = fake.NewClientBuilder().WithScheme(s).Build()
+
+		var err error
+		decoder, err = admission.NewDecoder(s)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(c.Create(context.TODO(), &corev1.Node{ObjectMeta: v1.ObjectMeta{
+			Name:   "node1",
+			Labels: map[string]string{"fpga.intel.com/intel-accelerator-present": ""},
+		}})).To(Succeed())
+		Expect(c.Create(context.TODO(), &sriovv1.SriovFecNodeConfig{
+			ObjectMeta: v1.ObjectMeta{Name: "node1", Namespace: NAMESPACE},
+			Status: sriovv1.SriovFecNodeConfigStatus{Inventory: sriovv1.NodeInventory{
+				SriovAccelerators: []sriovv1.SriovAccelerator{
+					{VendorID: "8086", DeviceID: "0d8f", PCIAddress: "0000:1b:00.0", MaxVFs: 2},
+				},
+			}},
+		})).To(Succeed())
+	})
+
+	var _ = Describe("SriovFecClusterConfigValidator", func() {
+		validate := func(pf sriovv1.PhysicalFunctionConfig) admission.Response {
+			v := &SriovFecClusterConfigValidator{Client: c, Log: ctrl.Log.WithName("webhook")}
+			Expect(v.InjectDecoder(decoder)).To(Succeed())
+			cc := &sriovv1.SriovFecClusterConfig{
+				TypeMeta:   v1.TypeMeta{APIVersion: "sriovfec.intel.com/v1", Kind: "SriovFecClusterConfig"},
+				ObjectMeta: v1.ObjectMeta{Name: "config", Namespace: NAMESPACE},
+				Spec: sriovv1.SriovFecClusterConfigSpec{Nodes: []sriovv1.NodeConfig{
+					{NodeName: "node1", PhysicalFunctions: []sriovv1.PhysicalFunctionConfig{pf}},
+				}},
+			}
+			return v.Handle(context.TODO(), request(cc, cc.Name))
+		}
+
+		var _ = It("will allow the valid config", func() {
+			Expect(validate(n3000PF("0000:1b:00.0", 2, 16)).Allowed).To(BeTrue())
+		})
+		var _ = It("will allow the PF without queue config", func() {
+			pf := n3000PF("0000:1b:00.0", 2, 16)
+			pf.BBDevConfig = sriovv1.BBDevConfig{}
+			Expect(validate(pf).Allowed).To(BeTrue())
+		})
+		var _ = It("will deny several queue configs", func() {
+			pf := n3000PF("0000:1b:00.0", 2, 16)
+			pf.BBDevConfig.ACC100 = &sriovv1.ACC100BBDevConfig{NumVfBundles: 16}
+			resp := validate(pf)
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(string(resp.Result.Reason)).To(ContainSubstring("at most one of bbDevConfig.n3000"))
+		})
+		var _ = It("will deny the drivers not allowed", func() {
+			pf := n3000PF("0000:1b:00.0", 2, 16)
+			pf.VFDriver = "i40evf"
+			resp := validate(pf)
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(string(resp.Result.Reason)).To(ContainSubstring(`vfDriver "i40evf"`))
+		})
+		var _ = It("will deny the queues exceeding the device", func() {
+			resp := validate(n3000PF("0000:1b:00.0", 2, 17))
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(string(resp.Result.Reason)).To(ContainSubstring("total number of uplink queues (33)"))
+		})
+		var _ = It("will deny the VFs without queues", func() {
+			resp := validate(n3000PF("0000:1b:00.0", 1, 16))
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(string(resp.Result.Reason)).To(ContainSubstring("number of VFs with queues (2) doesn't match vfAmount (1)"))
+		})
+		var _ = It("will deny more VFs than supported by the PF of the node", func() {
+			pf := n3000PF("", 3, 8)
+			pf.AcceleratorSelector = &sriovv1.AcceleratorSelector{DeviceID: "0d8f"}
+			pf.BBDevConfig.N3000.Uplink.Queues.VF2 = 8
+			resp := validate(pf)
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(string(resp.Result.Reason)).To(ContainSubstring(
+				"node1/0000:1b:00.0: vfAmount (3) exceeds the maximum number of VFs of the PF (2)"))
+		})
+		var _ = It("will validate the queues with the per-VF queues applied", func() {
+			pf := n3000PF("0000:1b:00.0", 2, 16)
+			pf.VFs = []sriovv1.VFConfig{{Index: 1, Driver: "igb_uio", Queues: &sriovv1.VFQueues{Uplink: 16, Downlink: 8}}}
+			Expect(validate(pf).Allowed).To(BeTrue())
+
+			pf.VFs[0].Queues.Uplink = 17
+			resp := validate(pf)
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(string(resp.Result.Reason)).To(ContainSubstring("total number of uplink queues (33)"))
+		})
+		var _ = It("will deny invalid per-VF configs", func() {
+			pf := n3000PF("0000:1b:00.0", 2, 16)
+			pf.VFs = []sriovv1.VFConfig{
+				{Index: 2, ResourceName: "intel_fec_du"},
+				{Index: 0, Driver: "i40evf"},
+				{Index: 0},
+			}
+			resp := validate(pf)
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(string(resp.Result.Reason)).To(And(
+				ContainSubstring("vfs: index 2 is out of range of vfAmount (2)"),
+				ContainSubstring(`vfs[0]: driver "i40evf"`),
+				ContainSubstring("vfs: index 0 is configured more than once"),
+			))
+		})
+		var _ = It("will allow the kernel drivers for the individual VFs", func() {
+			pf := n3000PF("0000:1b:00.0", 2, 16)
+			pf.VFs = []sriovv1.VFConfig{{Index: 0, Driver: "uio_pci_generic"}, {Index: 1, Driver: "pci-pf-stub"}}
+			Expect(validate(pf).Allowed).To(BeTrue())
+
+			pf.VFDriver = "uio_pci_generic"
+			Expect(validate(pf).Allowed).To(BeFalse())
+		})
+		var _ = It("will deny the per-VF queues of ACC100", func() {
+			pf := sriovv1.PhysicalFunctionConfig{
+				PCIAddress: "0000:1b:00.0",
+				PFDriver:   "pci-pf-stub",
+				VFDriver:   "vfio-pci",
+				VFAmount:   2,
+				BBDevConfig: sriovv1.BBDevConfig{ACC100: &sriovv1.ACC100BBDevConfig{
+					NumVfBundles: 16,
+					MaxQueueSize: 1024,
+				}},
+				VFs: []sriovv1.VFConfig{{Index: 0, Queues: &sriovv1.VFQueues{Uplink: 1}}},
+			}
+			resp := validate(pf)
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(string(resp.Result.Reason)).To(ContainSubstring("vfs[0]: queues can be set only for N3000"))
+		})
+		var _ = It("will deny the node config without valid node selector", func() {
+			v := &SriovFecClusterConfigValidator{Client: c, Log: ctrl.Log.WithName("webhook")}
+			Expect(v.InjectDecoder(decoder)).To(Succeed())
+			cc := &sriovv1.SriovFecClusterConfig{
+				TypeMeta:   v1.TypeMeta{APIVersion: "sriovfec.intel.com/v1", Kind: "SriovFecClusterConfig"},
+				ObjectMeta: v1.ObjectMeta{Name: "config", Namespace: NAMESPACE},
+				Spec: sriovv1.SriovFecClusterConfigSpec{Nodes: []sriovv1.NodeConfig{
+					{PhysicalFunctions: []sriovv1.PhysicalFunctionConfig{n3000PF("0000:1b:00.0", 2, 16)}},
+					{NodeSelector: &v1.LabelSelector{MatchExpressions: []v1.LabelSelectorRequirement{
+						{Key: "sku", Operator: v1.LabelSelectorOpIn}}}},
+				}},
+			}
+			resp := v.Handle(context.TODO(), request(cc, cc.Name))
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(string(resp.Result.Reason)).To(And(
+				ContainSubstring("either nodeName or nodeSelector has to be set"),
+				ContainSubstring("invalid nodeSelector"),
+			))
+		})
+		var _ = It("will deny the PF missing in the inventory of the node", func() {
+			resp := validate(n3000PF("0000:1c:00.0", 2, 16))
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(string(resp.Result.Reason)).To(ContainSubstring("PF not found in the inventory of the node"))
+		})
+	})
+
+	var _ = Describe("SriovFecNodeConfigValidator", func() {
+		validate := func(nc *sriovv1.SriovFecNodeConfig) admission.Response {
+			v := &SriovFecNodeConfigValidator{Log: ctrl.Log.WithName("webhook")}
+			Expect(v.InjectDecoder(decoder)).To(Succeed())
+			nc.TypeMeta = v1.TypeMeta{APIVersion: "sriovfec.intel.com/v1", Kind: "SriovFecNodeConfig"}
+			return v.Handle(context.TODO(), request(nc, nc.Name))
+		}
+
+		var _ = It("will validate the PFs against the inventory of the node", func() {
+			nc := &sriovv1.SriovFecNodeConfig{}
+			Expect(c.Get(context.TODO(), client.ObjectKey{Namespace: NAMESPACE, Name: "node1"}, nc)).To(Succeed())
+			nc.Spec.PhysicalFunctions = []sriovv1.PhysicalFunctionConfig{n3000PF("0000:1b:00.0", 2, 16)}
+			Expect(validate(nc).Allowed).To(BeTrue())
+
+			pf := n3000PF("0000:1b:00.0", 3, 8)
+			pf.BBDevConfig.N3000.Uplink.Queues.VF2 = 8
+			nc.Spec.PhysicalFunctions = []sriovv1.PhysicalFunctionConfig{pf}
+			Expect(validate(nc).Allowed).To(BeFalse())
+		})
+		var _ = It("will deny the PF without PCI address", func() {
+			resp := validate(&sriovv1.SriovFecNodeConfig{
+				ObjectMeta: v1.ObjectMeta{Name: "node2", Namespace: NAMESPACE},
+				Spec: sriovv1.SriovFecNodeConfigSpec{
+					PhysicalFunctions: []sriovv1.PhysicalFunctionConfig{n3000PF("", 2, 16)},
+				},
+			})
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(string(resp.Result.Reason)).To(ContainSubstring("pciAddress has to be set"))
+		})
+	})
+})
@@ -11,6 +11,8 @@ require (
 	github.com/openshift/api v3.9.0+incompatible
 	github.com/open-ness/openshift-operator/common v0.0.0-20210331133825-661f430b5d9f
 	github.com/pkg/errors v0.9.1
+	github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring v0.45.0
+	github.com/prometheus/client_golang v1.7.1
 	gopkg.in/ini.v1 v1.62.0
 	k8s.io/api v0.20.2
 	k8s.io/apimachinery v0.20.2
@@ -19,3 +21,5 @@ require (
 )
 
 replace github.com/k8snetworkplumbingwg/sriov-network-device-plugin => github.com/openshift/sriov-network-device-plugin v0.0.0-20201204004339-6d9de398bc37 //4.7
+
+replace github.com/open-ness/openshift-operator/common => ../common
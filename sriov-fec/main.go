@@ -27,6 +27,7 @@ import (
 	"time"
 
 	secv1 "github.com/openshift/api/security/v1"
+	promv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -36,6 +37,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	"github.com/open-ness/openshift-operator/common/pkg/assets"
 	sriovfecv1 "github.com/open-ness/openshift-operator/sriov-fec/api/v1"
@@ -53,6 +55,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(secv1.AddToScheme(scheme))
+	utilruntime.Must(promv1.AddToScheme(scheme))
 	utilruntime.Must(sriovfecv1.AddToScheme(scheme))
 
 	n := os.Getenv("NAME")
@@ -90,13 +93,27 @@ func main() {
 	}
 
 	if err = (&controllers.SriovFecClusterConfigReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("SriovFecClusterConfig"),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("SriovFecClusterConfig"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("sriov-fec-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "SriovFecClusterConfig")
 		os.Exit(1)
 	}
+	if os.Getenv("ENABLE_WEBHOOKS") == "true" {
+		mgr.GetWebhookServer().Register(controllers.ValidateClusterConfigPath, &webhook.Admission{
+			Handler: &controllers.SriovFecClusterConfigValidator{
+				Client: mgr.GetClient(),
+				Log:    ctrl.Log.WithName("webhooks").WithName("SriovFecClusterConfig"),
+			},
+		})
+		mgr.GetWebhookServer().Register(controllers.ValidateNodeConfigPath, &webhook.Admission{
+			Handler: &controllers.SriovFecNodeConfigValidator{
+				Log: ctrl.Log.WithName("webhooks").WithName("SriovFecNodeConfig"),
+			},
+		})
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("health", healthz.Ping); err != nil {
@@ -142,6 +159,10 @@ func main() {
 				Path:              "assets/300-daemon.yaml",
 				BlockingReadiness: assets.ReadinessPollConfig{Retries: 30, Delay: 20 * time.Second},
 			},
+			{
+				Path:     "assets/400-monitoring.yaml",
+				Optional: true,
+			},
 		},
 	}).LoadAndDeploy(context.Background(), false); err != nil {
 		setupLog.Error(err, "failed to deploy the assets")
@@ -4,9 +4,13 @@
 package daemon
 
 import (
-	"errors"
+	"encoding/json"
 	"fmt"
-	"strconv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	sriovv1 "github.com/open-ness/openshift-operator/sriov-fec/api/v1"
@@ -14,138 +18,238 @@ import (
 )
 
 const (
-	ul           = "UL"
-	dl           = "DL"
-	flr          = "FLR"
-	bandwidth    = "bandwidth"
-	load_balance = "load_balance"
-	vfqmap       = "vfqmap"
-	flr_time_out = "flr_time_out"
-
-	vfbundles          = "VFBUNDLES"
-	maxqsize           = "MAXQSIZE"
-	uplink4g           = "QUL4G"
-	downlink4g         = "QDL4G"
-	uplink5g           = "QUL5G"
-	downlink5g         = "QDL5G"
-	num_vf_bundles     = "num_vf_bundles"
-	max_queue_size     = "max_queue_size"
-	num_qgroups        = "num_qgroups"
-	num_aqs_per_groups = "num_aqs_per_groups"
-	aq_depth_log2      = "aq_depth_log2"
-	maxQueueGroups     = 8
-
 	mode                = "MODE"
 	pf_mode_en          = "pf_mode_en"
 	pfConfigAppFilepath = "/sriov_workdir/pf_bb_config"
 )
 
-func generateN3000BBDevConfigFile(nc *sriovv1.N3000BBDevConfig, file string) error {
-	if nc == nil {
-		return errors.New("received nil N3000BBDevConfig")
+var (
+	bootIDFilepath = "/proc/sys/kernel/random/boot_id"
+)
+
+// BBDevConfigurator handles the device specific member of the BBDevConfig of the devices configured
+// with pf_bb_config
+type BBDevConfigurator interface {
+	// Name returns the name of the device specific config, e.g. ACC100
+	Name() string
+	// IsSet returns true if the device specific config is set in the BBDevConfig
+	IsSet(cfg sriovv1.BBDevConfig) bool
+	// Validate checks that the device specific config is set and fits into the device
+	Validate(cfg sriovv1.BBDevConfig) error
+	// Render writes the device specific config to the pf_bb_config's INI file
+	Render(cfg sriovv1.BBDevConfig, file string) error
+	// Parse reads the device specific config from the pf_bb_config's INI file
+	Parse(file string) (sriovv1.BBDevConfig, error)
+}
+
+// bbDevConfigurators are the registered BBDevConfigurators, by the device name from the supported
+// accelerators config (accelerators.json)
+var bbDevConfigurators = map[string]BBDevConfigurator{}
+
+// RegisterBBDevConfigurator registers the BBDevConfigurator of the device with the name from the supported
+// accelerators config. The device name is passed to pf_bb_config, so it has to be supported by the tool.
+func RegisterBBDevConfigurator(deviceName string, c BBDevConfigurator) {
+	if _, ok := bbDevConfigurators[deviceName]; ok {
+		panic(fmt.Sprintf("BBDevConfigurator for device %s already registered", deviceName))
+	}
+	bbDevConfigurators[deviceName] = c
+}
+
+// getBBDevConfigurator returns the BBDevConfigurator registered for the device
+func getBBDevConfigurator(deviceName string) (BBDevConfigurator, error) {
+	c, ok := bbDevConfigurators[deviceName]
+	if !ok {
+		return nil, fmt.Errorf("no BBDevConfigurator registered for device: %q", deviceName)
+	}
+	return c, nil
+}
+
+// hasBBDevConfig returns true if any of the device specific configs is set in the BBDevConfig
+func hasBBDevConfig(pfCfg sriovv1.BBDevConfig) bool {
+	for _, c := range bbDevConfigurators {
+		if c.IsSet(pfCfg) {
+			return true
+		}
+	}
+	return false
+}
+
+// generateBBDevConfigFile validates the BBDevConfig and writes it to the pf_bb_config's INI file.
+// Only the config of the configurator's device can be set.
+func generateBBDevConfigFile(c BBDevConfigurator, pfCfg sriovv1.BBDevConfig, file string) error {
+	for _, other := range bbDevConfigurators {
+		if other.Name() != c.Name() && other.IsSet(pfCfg) {
+			return fmt.Errorf("Received %s config for %s device", other.Name(), c.Name())
+		}
+	}
+	if err := c.Validate(pfCfg); err != nil {
+		return fmt.Errorf("%s config is invalid, %s", c.Name(), err)
+	}
+	if err := c.Render(pfCfg, file); err != nil {
+		return fmt.Errorf("%s config file creation failed, %s", c.Name(), err)
+	}
+	return nil
+}
+
+// runPFConfig executes a pf-bb-config tool
+// deviceName is the name of the device with registered BBDevConfigurator, e.g. FPGA_LTE, FPGA_5GNR or ACC100
+// cfgFilepath is a filepath to the config
+// pciAddress points to a specific PF device
+func runPFConfig(log logr.Logger, deviceName, cfgFilepath, pciAddress string) error {
+	if _, ok := bbDevConfigurators[deviceName]; !ok {
+		return fmt.Errorf("incorrect deviceName for pf config: %s", deviceName)
 	}
+	start := time.Now()
+	_, err := runExecCmd([]string{pfConfigAppFilepath, deviceName, "-c", cfgFilepath, "-p", pciAddress}, log)
+	pfBBConfigSeconds.WithLabelValues(pciAddress).Observe(time.Since(start).Seconds())
+	return err
+}
 
-	cfg := ini.Empty()
-	err := cfg.NewSections(mode, ul, dl, flr)
+// appliedConfigState is the state of the PF when its queues were configured with pf_bb_config. The queues are
+// reset with a reboot of the node, a rebind of the PF or a change of its VFs, then the saved config is not active.
+type appliedConfigState struct {
+	BootID string `json:"bootID"`
+	Driver string `json:"driver"`
+	VFs    int    `json:"vfs"`
+}
+
+// appliedConfigFilepath returns the path of the config last applied to the PF with pf_bb_config. It's kept in
+// the statedir, so the applied config is reported after the restart of the daemon.
+func appliedConfigFilepath(pciAddress string) string {
+	return filepath.Join(statedir, fmt.Sprintf("%s.applied.ini", pciAddress))
+}
+
+// appliedStateFilepath returns the path of the state of the PF when the config was applied
+func appliedStateFilepath(pciAddress string) string {
+	return filepath.Join(statedir, fmt.Sprintf("%s.applied.json", pciAddress))
+}
+
+// getBootID returns the ID of the current boot of the node
+func getBootID() (string, error) {
+	data, err := ioutil.ReadFile(bootIDFilepath)
 	if err != nil {
-		return fmt.Errorf("Unable to create sections in bbdevconfig")
+		return "", fmt.Errorf("failed to read boot ID: %w", err)
 	}
+	return strings.TrimSpace(string(data)), nil
+}
 
-	var modeValue string
-	if nc.PFMode {
-		modeValue = "1"
-	} else {
-		modeValue = "0"
+// saveAppliedBBDevConfig saves the config applied to the PF with pf_bb_config together with the state of the PF.
+// The config is the one passed to pf_bb_config, pf_bb_config doesn't report the config of the device.
+func saveAppliedBBDevConfig(c BBDevConfigurator, pf sriovv1.PhysicalFunctionConfig) error {
+	bootID, err := getBootID()
+	if err != nil {
+		return err
 	}
-	cfg.Section(mode).Key(pf_mode_en).SetValue(modeValue)
-	cfg.Section(ul).Key(bandwidth).SetValue(strconv.Itoa(nc.Uplink.Bandwidth))
-	cfg.Section(ul).Key(load_balance).SetValue(strconv.Itoa(nc.Uplink.LoadBalance))
-	cfg.Section(ul).Key(vfqmap).SetValue(nc.Uplink.Queues.String())
-	cfg.Section(dl).Key(bandwidth).SetValue(strconv.Itoa(nc.Downlink.Bandwidth))
-	cfg.Section(dl).Key(load_balance).SetValue(strconv.Itoa(nc.Downlink.LoadBalance))
-	cfg.Section(dl).Key(vfqmap).SetValue(nc.Downlink.Queues.String())
-	cfg.Section(flr).Key(flr_time_out).SetValue(strconv.Itoa(nc.FLRTimeOut))
-
-	err = cfg.SaveTo(file)
+	data, err := json.Marshal(appliedConfigState{BootID: bootID, Driver: pf.PFDriver, VFs: pf.VFAmount})
 	if err != nil {
-		return fmt.Errorf("Unable to write config to file: %s", file)
+		return err
+	}
+	if err := ioutil.WriteFile(appliedStateFilepath(pf.PCIAddress), data, 0644); err != nil {
+		return fmt.Errorf("failed to write applied config state: %w", err)
+	}
+	return generateBBDevConfigFile(c, pf.GetBBDevConfig(), appliedConfigFilepath(pf.PCIAddress))
+}
+
+// removeAppliedBBDevConfig removes the config saved for the PF, once its queues are reset
+func removeAppliedBBDevConfig(pciAddress string) error {
+	for _, file := range []string{appliedConfigFilepath(pciAddress), appliedStateFilepath(pciAddress)} {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
 	}
 	return nil
 }
 
-func generateACC100BBDevConfigFile(nc *sriovv1.ACC100BBDevConfig, file string) error {
-	if nc == nil {
-		return errors.New("received nil ACC100BBDevConfig")
+// getAppliedBBDevConfig returns the config last applied to the PF of the device with pf_bb_config, nil if
+// the queues of the PF were not configured. The saved config is removed if the node was rebooted, or the driver
+// or the number of the VFs of the PF changed since the config was applied.
+func getAppliedBBDevConfig(deviceName string, acc sriovv1.SriovAccelerator) (*sriovv1.BBDevConfig, error) {
+	c, ok := bbDevConfigurators[deviceName]
+	if !ok {
+		return nil, nil
 	}
 
-	total4GQueueGroups := nc.Uplink4G.NumQueueGroups + nc.Downlink4G.NumQueueGroups
-	total5GQueueGroups := nc.Uplink5G.NumQueueGroups + nc.Downlink5G.NumQueueGroups
-	totalQueueGroups := total4GQueueGroups + total5GQueueGroups
-	if totalQueueGroups > maxQueueGroups {
-		return fmt.Errorf("Total number of requested queue groups (4G/5G) exceeds the maximum (%d)", maxQueueGroups)
+	file := appliedConfigFilepath(acc.PCIAddress)
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return nil, nil
 	}
 
-	cfg := ini.Empty()
-	err := cfg.NewSections(mode, vfbundles, maxqsize, uplink4g, downlink4g, uplink5g, downlink5g)
+	active, err := appliedConfigActive(acc)
 	if err != nil {
-		return fmt.Errorf("Unable to create sections in bbdevconfig")
+		return nil, err
+	}
+	if !active {
+		return nil, removeAppliedBBDevConfig(acc.PCIAddress)
 	}
 
+	cfg, err := c.Parse(file)
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// appliedConfigActive returns true if the PF is in the state in which the saved config was applied
+func appliedConfigActive(acc sriovv1.SriovAccelerator) (bool, error) {
+	data, err := ioutil.ReadFile(appliedStateFilepath(acc.PCIAddress))
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to read applied config state: %w", err)
+	}
+	state := appliedConfigState{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return false, nil
+	}
+
+	bootID, err := getBootID()
+	if err != nil {
+		return false, err
+	}
+	return state.BootID == bootID && driverMatches(acc.Driver, state.Driver) && len(acc.VFs) == state.VFs, nil
+}
+
+// setPFMode sets the programming mode in the INI file
+func setPFMode(cfg *ini.File, pfMode bool) {
 	var modeValue string
-	if nc.PFMode {
+	if pfMode {
 		modeValue = "1"
 	} else {
 		modeValue = "0"
 	}
 	cfg.Section(mode).Key(pf_mode_en).SetValue(modeValue)
-	cfg.Section(vfbundles).Key(num_vf_bundles).SetValue(strconv.Itoa(nc.NumVfBundles))
-	cfg.Section(maxqsize).Key(max_queue_size).SetValue(strconv.Itoa(nc.MaxQueueSize))
-	cfg.Section(uplink4g).Key(num_qgroups).SetValue(strconv.Itoa(nc.Uplink4G.NumQueueGroups))
-	cfg.Section(uplink4g).Key(num_aqs_per_groups).SetValue(strconv.Itoa(nc.Uplink4G.NumAqsPerGroups))
-	cfg.Section(uplink4g).Key(aq_depth_log2).SetValue(strconv.Itoa(nc.Uplink4G.AqDepthLog2))
-	cfg.Section(downlink4g).Key(num_qgroups).SetValue(strconv.Itoa(nc.Downlink4G.NumQueueGroups))
-	cfg.Section(downlink4g).Key(num_aqs_per_groups).SetValue(strconv.Itoa(nc.Downlink4G.NumAqsPerGroups))
-	cfg.Section(downlink4g).Key(aq_depth_log2).SetValue(strconv.Itoa(nc.Downlink4G.AqDepthLog2))
-	cfg.Section(uplink5g).Key(num_qgroups).SetValue(strconv.Itoa(nc.Uplink5G.NumQueueGroups))
-	cfg.Section(uplink5g).Key(num_aqs_per_groups).SetValue(strconv.Itoa(nc.Uplink5G.NumAqsPerGroups))
-	cfg.Section(uplink5g).Key(aq_depth_log2).SetValue(strconv.Itoa(nc.Uplink5G.AqDepthLog2))
-	cfg.Section(downlink5g).Key(num_qgroups).SetValue(strconv.Itoa(nc.Downlink5G.NumQueueGroups))
-	cfg.Section(downlink5g).Key(num_aqs_per_groups).SetValue(strconv.Itoa(nc.Downlink5G.NumAqsPerGroups))
-	cfg.Section(downlink5g).Key(aq_depth_log2).SetValue(strconv.Itoa(nc.Downlink5G.AqDepthLog2))
-
-	err = cfg.SaveTo(file)
+}
+
+// intParser parses the integer values of the INI file, keeping the first error
+type intParser struct {
+	cfg *ini.File
+	err error
+}
+
+func (p *intParser) get(section, key string) int {
+	if p.err != nil {
+		return 0
+	}
+	value, err := p.cfg.Section(section).Key(key).Int()
 	if err != nil {
-		return fmt.Errorf("Unable to write config to file: %s", file)
+		p.err = fmt.Errorf("invalid %s.%s: %v", section, key, err)
 	}
-	return nil
+	return value
 }
 
-func generateBBDevConfigFile(pfCfg sriovv1.BBDevConfig, file string) error {
-	if pfCfg.ACC100 != nil {
-		if err := generateACC100BBDevConfigFile(pfCfg.ACC100, file); err != nil {
-			return fmt.Errorf("ACC100 config file creation failed, %s", err)
-		}
-	} else if pfCfg.N3000 != nil {
-		if err := generateN3000BBDevConfigFile(pfCfg.N3000, file); err != nil {
-			return fmt.Errorf("N3000 config file creation failed, %s", err)
-		}
-	} else {
-		return fmt.Errorf("Received nil configs")
+// loadConfig reads the INI file
+func loadConfig(file string) (*ini.File, error) {
+	cfg, err := ini.Load(file)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read config from file: %s", file)
 	}
-
-	return nil
+	return cfg, nil
 }
 
-// runPFConfig executes a pf-bb-config tool
-// deviceName is one of: FPGA_LTE or FPGA_5GNR or ACC100
-// cfgFilepath is a filepath to the config
-// pciAddress points to a specific PF device
-func runPFConfig(log logr.Logger, deviceName, cfgFilepath, pciAddress string) error {
-	switch deviceName {
-	case "FPGA_LTE", "FPGA_5GNR", "ACC100":
-	default:
-		return fmt.Errorf("incorrect deviceName for pf config: %s", deviceName)
+// saveConfig writes the INI file
+func saveConfig(cfg *ini.File, file string) error {
+	if err := cfg.SaveTo(file); err != nil {
+		return fmt.Errorf("Unable to write config to file: %s", file)
 	}
-	_, err := runExecCmd([]string{pfConfigAppFilepath, deviceName, "-c", cfgFilepath, "-p", pciAddress}, log)
-	return err
+	return nil
 }
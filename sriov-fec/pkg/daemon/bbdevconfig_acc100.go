@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2021 Intel Corporation
+
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	sriovv1 "github.com/open-ness/openshift-operator/sriov-fec/api/v1"
+	"gopkg.in/ini.v1"
+)
+
+const (
+	vfbundles          = "VFBUNDLES"
+	maxqsize           = "MAXQSIZE"
+	uplink4g           = "QUL4G"
+	downlink4g         = "QDL4G"
+	uplink5g           = "QUL5G"
+	downlink5g         = "QDL5G"
+	num_vf_bundles     = "num_vf_bundles"
+	max_queue_size     = "max_queue_size"
+	num_qgroups        = "num_qgroups"
+	num_aqs_per_groups = "num_aqs_per_groups"
+	aq_depth_log2      = "aq_depth_log2"
+)
+
+func init() {
+	RegisterBBDevConfigurator("ACC100", &acc100Configurator{})
+}
+
+// acc100Configurator configures the ACC100
+type acc100Configurator struct{}
+
+func (a *acc100Configurator) Name() string {
+	return "ACC100"
+}
+
+func (a *acc100Configurator) IsSet(cfg sriovv1.BBDevConfig) bool {
+	return cfg.ACC100 != nil
+}
+
+func (a *acc100Configurator) Validate(cfg sriovv1.BBDevConfig) error {
+	nc := cfg.ACC100
+	if nc == nil {
+		return errors.New("received nil ACC100BBDevConfig")
+	}
+
+	total4GQueueGroups := nc.Uplink4G.NumQueueGroups + nc.Downlink4G.NumQueueGroups
+	total5GQueueGroups := nc.Uplink5G.NumQueueGroups + nc.Downlink5G.NumQueueGroups
+	totalQueueGroups := total4GQueueGroups + total5GQueueGroups
+	if totalQueueGroups > sriovv1.MaxACC100QueueGroups {
+		return fmt.Errorf("Total number of requested queue groups (4G/5G) exceeds the maximum (%d)",
+			sriovv1.MaxACC100QueueGroups)
+	}
+	return nil
+}
+
+func (a *acc100Configurator) Render(cfg sriovv1.BBDevConfig, file string) error {
+	nc := cfg.ACC100
+	if nc == nil {
+		return errors.New("received nil ACC100BBDevConfig")
+	}
+
+	iniCfg := ini.Empty()
+	if err := iniCfg.NewSections(mode, vfbundles, maxqsize, uplink4g, downlink4g, uplink5g, downlink5g); err != nil {
+		return fmt.Errorf("Unable to create sections in bbdevconfig")
+	}
+
+	setPFMode(iniCfg, nc.PFMode)
+	iniCfg.Section(vfbundles).Key(num_vf_bundles).SetValue(strconv.Itoa(nc.NumVfBundles))
+	iniCfg.Section(maxqsize).Key(max_queue_size).SetValue(strconv.Itoa(nc.MaxQueueSize))
+	setQueueGroups(iniCfg.Section(uplink4g), nc.Uplink4G)
+	setQueueGroups(iniCfg.Section(downlink4g), nc.Downlink4G)
+	setQueueGroups(iniCfg.Section(uplink5g), nc.Uplink5G)
+	setQueueGroups(iniCfg.Section(downlink5g), nc.Downlink5G)
+
+	return saveConfig(iniCfg, file)
+}
+
+func (a *acc100Configurator) Parse(file string) (sriovv1.BBDevConfig, error) {
+	iniCfg, err := loadConfig(file)
+	if err != nil {
+		return sriovv1.BBDevConfig{}, err
+	}
+
+	p := &intParser{cfg: iniCfg}
+	nc := &sriovv1.ACC100BBDevConfig{
+		PFMode:       p.get(mode, pf_mode_en) == 1,
+		NumVfBundles: p.get(vfbundles, num_vf_bundles),
+		MaxQueueSize: p.get(maxqsize, max_queue_size),
+		Uplink4G:     getQueueGroups(p, uplink4g),
+		Downlink4G:   getQueueGroups(p, downlink4g),
+		Uplink5G:     getQueueGroups(p, uplink5g),
+		Downlink5G:   getQueueGroups(p, downlink5g),
+	}
+	if p.err != nil {
+		return sriovv1.BBDevConfig{}, p.err
+	}
+	return sriovv1.BBDevConfig{ACC100: nc}, nil
+}
+
+// setQueueGroups sets the keys of the queue groups section of the ACC100 (and its successors) config
+func setQueueGroups(section *ini.Section, qg sriovv1.QueueGroupConfig) {
+	section.Key(num_qgroups).SetValue(strconv.Itoa(qg.NumQueueGroups))
+	section.Key(num_aqs_per_groups).SetValue(strconv.Itoa(qg.NumAqsPerGroups))
+	section.Key(aq_depth_log2).SetValue(strconv.Itoa(qg.AqDepthLog2))
+}
+
+// getQueueGroups returns the queue groups from the section of the ACC100 (and its successors) config
+func getQueueGroups(p *intParser, section string) sriovv1.QueueGroupConfig {
+	return sriovv1.QueueGroupConfig{
+		NumQueueGroups:  p.get(section, num_qgroups),
+		NumAqsPerGroups: p.get(section, num_aqs_per_groups),
+		AqDepthLog2:     p.get(section, aq_depth_log2),
+	}
+}
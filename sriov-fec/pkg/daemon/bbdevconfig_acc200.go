@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	sriovv1 "github.com/open-ness/openshift-operator/sriov-fec/api/v1"
+	"gopkg.in/ini.v1"
+)
+
+const (
+	qfft = "QFFT"
+)
+
+func init() {
+	RegisterBBDevConfigurator("ACC200", &acc200Configurator{})
+}
+
+// acc200Configurator configures the ACC200 (vRAN Boost), its config is the ACC100's one extended by
+// the queue groups of the FFT engine
+type acc200Configurator struct{}
+
+func (a *acc200Configurator) Name() string {
+	return "ACC200"
+}
+
+func (a *acc200Configurator) IsSet(cfg sriovv1.BBDevConfig) bool {
+	return cfg.ACC200 != nil
+}
+
+func (a *acc200Configurator) Validate(cfg sriovv1.BBDevConfig) error {
+	nc := cfg.ACC200
+	if nc == nil {
+		return errors.New("received nil ACC200BBDevConfig")
+	}
+
+	totalQueueGroups := nc.Uplink4G.NumQueueGroups + nc.Downlink4G.NumQueueGroups +
+		nc.Uplink5G.NumQueueGroups + nc.Downlink5G.NumQueueGroups + nc.QFFT.NumQueueGroups
+	if totalQueueGroups > sriovv1.MaxACC200QueueGroups {
+		return fmt.Errorf("Total number of requested queue groups (4G/5G/FFT) exceeds the maximum (%d)",
+			sriovv1.MaxACC200QueueGroups)
+	}
+	return nil
+}
+
+func (a *acc200Configurator) Render(cfg sriovv1.BBDevConfig, file string) error {
+	nc := cfg.ACC200
+	if nc == nil {
+		return errors.New("received nil ACC200BBDevConfig")
+	}
+
+	iniCfg := ini.Empty()
+	err := iniCfg.NewSections(mode, vfbundles, maxqsize, uplink4g, downlink4g, uplink5g, downlink5g, qfft)
+	if err != nil {
+		return fmt.Errorf("Unable to create sections in bbdevconfig")
+	}
+
+	setPFMode(iniCfg, nc.PFMode)
+	iniCfg.Section(vfbundles).Key(num_vf_bundles).SetValue(strconv.Itoa(nc.NumVfBundles))
+	iniCfg.Section(maxqsize).Key(max_queue_size).SetValue(strconv.Itoa(nc.MaxQueueSize))
+	setQueueGroups(iniCfg.Section(uplink4g), nc.Uplink4G)
+	setQueueGroups(iniCfg.Section(downlink4g), nc.Downlink4G)
+	setQueueGroups(iniCfg.Section(uplink5g), nc.Uplink5G)
+	setQueueGroups(iniCfg.Section(downlink5g), nc.Downlink5G)
+	setQueueGroups(iniCfg.Section(qfft), nc.QFFT)
+
+	return saveConfig(iniCfg, file)
+}
+
+func (a *acc200Configurator) Parse(file string) (sriovv1.BBDevConfig, error) {
+	iniCfg, err := loadConfig(file)
+	if err != nil {
+		return sriovv1.BBDevConfig{}, err
+	}
+
+	p := &intParser{cfg: iniCfg}
+	nc := &sriovv1.ACC200BBDevConfig{
+		PFMode:       p.get(mode, pf_mode_en) == 1,
+		NumVfBundles: p.get(vfbundles, num_vf_bundles),
+		MaxQueueSize: p.get(maxqsize, max_queue_size),
+		Uplink4G:     getQueueGroups(p, uplink4g),
+		Downlink4G:   getQueueGroups(p, downlink4g),
+		Uplink5G:     getQueueGroups(p, uplink5g),
+		Downlink5G:   getQueueGroups(p, downlink5g),
+		QFFT:         getQueueGroups(p, qfft),
+	}
+	if p.err != nil {
+		return sriovv1.BBDevConfig{}, p.err
+	}
+	return sriovv1.BBDevConfig{ACC200: nc}, nil
+}
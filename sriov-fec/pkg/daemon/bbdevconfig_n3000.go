@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2020-2021 Intel Corporation
+
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	sriovv1 "github.com/open-ness/openshift-operator/sriov-fec/api/v1"
+	"gopkg.in/ini.v1"
+)
+
+const (
+	ul           = "UL"
+	dl           = "DL"
+	flr          = "FLR"
+	bandwidth    = "bandwidth"
+	load_balance = "load_balance"
+	vfqmap       = "vfqmap"
+	flr_time_out = "flr_time_out"
+)
+
+func init() {
+	RegisterBBDevConfigurator("FPGA_LTE", &n3000Configurator{networkType: "FPGA_LTE"})
+	RegisterBBDevConfigurator("FPGA_5GNR", &n3000Configurator{networkType: "FPGA_5GNR"})
+}
+
+// n3000Configurator configures the FEC of N3000 with the LTE or 5GNR FPGA image
+type n3000Configurator struct {
+	networkType string
+}
+
+func (n *n3000Configurator) Name() string {
+	return "N3000"
+}
+
+func (n *n3000Configurator) IsSet(cfg sriovv1.BBDevConfig) bool {
+	return cfg.N3000 != nil
+}
+
+func (n *n3000Configurator) Validate(cfg sriovv1.BBDevConfig) error {
+	if cfg.N3000 == nil {
+		return errors.New("received nil N3000BBDevConfig")
+	}
+	return nil
+}
+
+func (n *n3000Configurator) Render(cfg sriovv1.BBDevConfig, file string) error {
+	nc := cfg.N3000
+	if nc == nil {
+		return errors.New("received nil N3000BBDevConfig")
+	}
+
+	iniCfg := ini.Empty()
+	if err := iniCfg.NewSections(mode, ul, dl, flr); err != nil {
+		return fmt.Errorf("Unable to create sections in bbdevconfig")
+	}
+
+	setPFMode(iniCfg, nc.PFMode)
+	iniCfg.Section(ul).Key(bandwidth).SetValue(strconv.Itoa(nc.Uplink.Bandwidth))
+	iniCfg.Section(ul).Key(load_balance).SetValue(strconv.Itoa(nc.Uplink.LoadBalance))
+	iniCfg.Section(ul).Key(vfqmap).SetValue(nc.Uplink.Queues.String())
+	iniCfg.Section(dl).Key(bandwidth).SetValue(strconv.Itoa(nc.Downlink.Bandwidth))
+	iniCfg.Section(dl).Key(load_balance).SetValue(strconv.Itoa(nc.Downlink.LoadBalance))
+	iniCfg.Section(dl).Key(vfqmap).SetValue(nc.Downlink.Queues.String())
+	iniCfg.Section(flr).Key(flr_time_out).SetValue(strconv.Itoa(nc.FLRTimeOut))
+
+	return saveConfig(iniCfg, file)
+}
+
+func (n *n3000Configurator) Parse(file string) (sriovv1.BBDevConfig, error) {
+	iniCfg, err := loadConfig(file)
+	if err != nil {
+		return sriovv1.BBDevConfig{}, err
+	}
+
+	p := &intParser{cfg: iniCfg}
+	nc := &sriovv1.N3000BBDevConfig{
+		NetworkType: n.networkType,
+		PFMode:      p.get(mode, pf_mode_en) == 1,
+		FLRTimeOut:  p.get(flr, flr_time_out),
+		Uplink: sriovv1.UplinkDownlink{
+			Bandwidth:   p.get(ul, bandwidth),
+			LoadBalance: p.get(ul, load_balance),
+		},
+		Downlink: sriovv1.UplinkDownlink{
+			Bandwidth:   p.get(dl, bandwidth),
+			LoadBalance: p.get(dl, load_balance),
+		},
+	}
+	if p.err != nil {
+		return sriovv1.BBDevConfig{}, p.err
+	}
+	if nc.Uplink.Queues, err = parseQueues(iniCfg.Section(ul).Key(vfqmap).String()); err != nil {
+		return sriovv1.BBDevConfig{}, fmt.Errorf("invalid %s.%s: %v", ul, vfqmap, err)
+	}
+	if nc.Downlink.Queues, err = parseQueues(iniCfg.Section(dl).Key(vfqmap).String()); err != nil {
+		return sriovv1.BBDevConfig{}, fmt.Errorf("invalid %s.%s: %v", dl, vfqmap, err)
+	}
+	return sriovv1.BBDevConfig{N3000: nc}, nil
+}
+
+// parseQueues parses the number of the queues of the VFs (vfqmap)
+func parseQueues(value string) (sriovv1.UplinkDownlinkQueues, error) {
+	fields := strings.Split(value, ",")
+	if len(fields) != 8 {
+		return sriovv1.UplinkDownlinkQueues{}, fmt.Errorf("expected 8 values, got %q", value)
+	}
+	queues := make([]int, len(fields))
+	for i, field := range fields {
+		q, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return sriovv1.UplinkDownlinkQueues{}, err
+		}
+		queues[i] = q
+	}
+	return sriovv1.UplinkDownlinkQueues{
+		VF0: queues[0], VF1: queues[1], VF2: queues[2], VF3: queues[3],
+		VF4: queues[4], VF5: queues[5], VF6: queues[6], VF7: queues[7],
+	}, nil
+}
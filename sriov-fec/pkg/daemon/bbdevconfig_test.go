@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 
 	. "github.com/onsi/ginkgo"
@@ -119,54 +120,208 @@ var _ = Describe("bbdevconfig", func() {
 		ACC100: &sampleBBDevConfig1,
 	}
 	sampleBBDevConfig5 := sriovv1.BBDevConfig{}
-	var _ = Context("generateBBDevConfigFile", func() {
+	sampleBBDevConfig6 := sriovv1.ACC200BBDevConfig{
+		PFMode:       false,
+		NumVfBundles: 16,
+		MaxQueueSize: 1024,
+		Uplink4G: sriovv1.QueueGroupConfig{
+			NumQueueGroups:  2,
+			NumAqsPerGroups: 16,
+			AqDepthLog2:     4,
+		},
+		Downlink4G: sriovv1.QueueGroupConfig{
+			NumQueueGroups:  2,
+			NumAqsPerGroups: 16,
+			AqDepthLog2:     4,
+		},
+		Uplink5G: sriovv1.QueueGroupConfig{
+			NumQueueGroups:  4,
+			NumAqsPerGroups: 16,
+			AqDepthLog2:     4,
+		},
+		Downlink5G: sriovv1.QueueGroupConfig{
+			NumQueueGroups:  4,
+			NumAqsPerGroups: 16,
+			AqDepthLog2:     4,
+		},
+		QFFT: sriovv1.QueueGroupConfig{
+			NumQueueGroups:  4,
+			NumAqsPerGroups: 16,
+			AqDepthLog2:     4,
+		},
+	}
+	configurator := func(deviceName string) BBDevConfigurator {
+		c, err := getBBDevConfigurator(deviceName)
+		Expect(err).ToNot(HaveOccurred())
+		return c
+	}
+	var _ = Context("BBDevConfigurators", func() {
 		var _ = It("will create valid config ", func() {
 			filename := "config.cfg"
-			err := generateN3000BBDevConfigFile(&sampleBBDevConfig0, filepath.Join(testTmpFolder, filename))
+			err := configurator("FPGA_5GNR").Render(sampleBBDevConfig3, filepath.Join(testTmpFolder, filename))
 			Expect(err).ToNot(HaveOccurred())
 			err = compareFiles(filepath.Join(testTmpFolder, filename), "testdata/bbdevconfig_test1.cfg")
 			Expect(err).ToNot(HaveOccurred())
 		})
 		var _ = It("will return error when config is nil ", func() {
 			filename := "config.cfg"
-			err := generateN3000BBDevConfigFile(nil, filepath.Join(testTmpFolder, filename))
+			err := configurator("FPGA_5GNR").Render(sampleBBDevConfig5, filepath.Join(testTmpFolder, filename))
 			Expect(err).To(HaveOccurred())
+			Expect(configurator("FPGA_5GNR").Validate(sampleBBDevConfig5)).ToNot(Succeed())
 		})
 		var _ = It("will create valid ACC100 config ", func() {
 			filename := "config.cfg"
-			err := generateACC100BBDevConfigFile(&sampleBBDevConfig1, filepath.Join(testTmpFolder, filename))
+			err := configurator("ACC100").Render(sampleBBDevConfig4, filepath.Join(testTmpFolder, filename))
 			Expect(err).ToNot(HaveOccurred())
 			err = compareFiles(filepath.Join(testTmpFolder, filename), "testdata/bbdevconfig_test2.cfg")
 			Expect(err).ToNot(HaveOccurred())
 		})
 		var _ = It("will return error when ACC100 config is nil ", func() {
 			filename := "config.cfg"
-			err := generateACC100BBDevConfigFile(nil, filepath.Join(testTmpFolder, filename))
+			err := configurator("ACC100").Render(sampleBBDevConfig5, filepath.Join(testTmpFolder, filename))
 			Expect(err).To(HaveOccurred())
 		})
 		var _ = It("will return error when total number of queue groups for ACC100 exceeds 8 ", func() {
+			err := configurator("ACC100").Validate(sriovv1.BBDevConfig{ACC100: &sampleBBDevConfig2})
+			Expect(err).To(HaveOccurred())
+		})
+		var _ = It("will create valid ACC200 config ", func() {
 			filename := "config.cfg"
-			err := generateACC100BBDevConfigFile(&sampleBBDevConfig2, filepath.Join(testTmpFolder, filename))
+			err := configurator("ACC200").Render(sriovv1.BBDevConfig{ACC200: &sampleBBDevConfig6},
+				filepath.Join(testTmpFolder, filename))
+			Expect(err).ToNot(HaveOccurred())
+			err = compareFiles(filepath.Join(testTmpFolder, filename), "testdata/bbdevconfig_test3.cfg")
+			Expect(err).ToNot(HaveOccurred())
+		})
+		var _ = It("will return error when total number of queue groups for ACC200 exceeds 16 ", func() {
+			cfg := sampleBBDevConfig6
+			cfg.QFFT.NumQueueGroups = 5
+			err := configurator("ACC200").Validate(sriovv1.BBDevConfig{ACC200: &cfg})
+			Expect(err).To(HaveOccurred())
+		})
+		var _ = It("will parse back the rendered configs ", func() {
+			n3000 := sampleBBDevConfig0
+			n3000.NetworkType = "FPGA_LTE"
+			for deviceName, cfg := range map[string]sriovv1.BBDevConfig{
+				"FPGA_LTE": {N3000: &n3000},
+				"ACC100":   sampleBBDevConfig4,
+				"ACC200":   {ACC200: &sampleBBDevConfig6},
+			} {
+				file := filepath.Join(testTmpFolder, deviceName+".cfg")
+				Expect(configurator(deviceName).Render(cfg, file)).To(Succeed())
+				parsed, err := configurator(deviceName).Parse(file)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(parsed).To(Equal(cfg))
+			}
+		})
+		var _ = It("will return error when parsed config is invalid ", func() {
+			file := filepath.Join(testTmpFolder, "config.cfg")
+			Expect(ioutil.WriteFile(file, []byte("[MODE]\npf_mode_en = 0\n\n[UL]\nvfqmap = 1,2\n"), 0644)).To(Succeed())
+			_, err := configurator("FPGA_5GNR").Parse(file)
 			Expect(err).To(HaveOccurred())
 		})
+	})
+	var _ = Context("generateBBDevConfigFile", func() {
 		var _ = It("will create valid N3000 config ", func() {
 			filename := "config.cfg"
-			err := generateBBDevConfigFile(sampleBBDevConfig3, filepath.Join(testTmpFolder, filename))
+			err := generateBBDevConfigFile(configurator("FPGA_5GNR"), sampleBBDevConfig3, filepath.Join(testTmpFolder, filename))
 			Expect(err).ToNot(HaveOccurred())
 			err = compareFiles(filepath.Join(testTmpFolder, filename), "testdata/bbdevconfig_test1.cfg")
 			Expect(err).ToNot(HaveOccurred())
 		})
 		var _ = It("will create valid ACC100 config ", func() {
 			filename := "config.cfg"
-			err := generateBBDevConfigFile(sampleBBDevConfig4, filepath.Join(testTmpFolder, filename))
+			err := generateBBDevConfigFile(configurator("ACC100"), sampleBBDevConfig4, filepath.Join(testTmpFolder, filename))
 			Expect(err).ToNot(HaveOccurred())
 			err = compareFiles(filepath.Join(testTmpFolder, filename), "testdata/bbdevconfig_test2.cfg")
 			Expect(err).ToNot(HaveOccurred())
 		})
-		var _ = It("will return an error when N3000 and ACC100 configs are nil ", func() {
+		var _ = It("will return an error when config of the device is nil ", func() {
 			filename := "config.cfg"
-			err := generateBBDevConfigFile(sampleBBDevConfig5, filepath.Join(testTmpFolder, filename))
+			err := generateBBDevConfigFile(configurator("ACC100"), sampleBBDevConfig5, filepath.Join(testTmpFolder, filename))
 			Expect(err).To(HaveOccurred())
 		})
+		var _ = It("will return an error when config of another device is set ", func() {
+			filename := "config.cfg"
+			err := generateBBDevConfigFile(configurator("ACC200"),
+				sriovv1.BBDevConfig{ACC100: &sampleBBDevConfig1, ACC200: &sampleBBDevConfig6},
+				filepath.Join(testTmpFolder, filename))
+			Expect(err).To(MatchError("Received ACC100 config for ACC200 device"))
+		})
+	})
+	var _ = Context("getBBDevConfigurator", func() {
+		var _ = It("will return error for the device without registered configurator ", func() {
+			_, err := getBBDevConfigurator("")
+			Expect(err).To(HaveOccurred())
+		})
+		var _ = It("will panic when the device is registered twice ", func() {
+			Expect(func() { RegisterBBDevConfigurator("ACC100", &acc100Configurator{}) }).To(Panic())
+		})
+	})
+	var _ = Context("getAppliedBBDevConfig", func() {
+		pf := sriovv1.PhysicalFunctionConfig{PCIAddress: "0000:14:00.1", PFDriver: "pci-pf-stub", VFAmount: 2,
+			BBDevConfig: sampleBBDevConfig4}
+		acc := sriovv1.SriovAccelerator{PCIAddress: pf.PCIAddress, Driver: "pci_pf_stub", VFs: make([]sriovv1.VF, 2)}
+		BeforeEach(func() {
+			statedir = testTmpFolder
+			bootIDFilepath = filepath.Join(testTmpFolder, "boot_id")
+			Expect(ioutil.WriteFile(bootIDFilepath, []byte("boot1\n"), 0644)).To(Succeed())
+		})
+		AfterEach(func() {
+			Expect(removeAppliedBBDevConfig(pf.PCIAddress)).To(Succeed())
+			Expect(os.RemoveAll(bootIDFilepath)).To(Succeed())
+		})
+
+		var _ = It("will return the config applied to the PF ", func() {
+			Expect(saveAppliedBBDevConfig(configurator("ACC100"), pf)).To(Succeed())
+			cfg, err := getAppliedBBDevConfig("ACC100", acc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cfg).To(Equal(&sampleBBDevConfig4))
+		})
+		var _ = It("will return nil when no config was applied to the PF ", func() {
+			cfg, err := getAppliedBBDevConfig("ACC100", acc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cfg).To(BeNil())
+		})
+		var _ = It("will return nil for the device without registered configurator ", func() {
+			Expect(saveAppliedBBDevConfig(configurator("ACC100"), pf)).To(Succeed())
+			cfg, err := getAppliedBBDevConfig("", acc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cfg).To(BeNil())
+		})
+		var _ = It("will remove the config not active after reboot ", func() {
+			Expect(saveAppliedBBDevConfig(configurator("ACC100"), pf)).To(Succeed())
+			Expect(ioutil.WriteFile(bootIDFilepath, []byte("boot2\n"), 0644)).To(Succeed())
+			cfg, err := getAppliedBBDevConfig("ACC100", acc)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cfg).To(BeNil())
+			Expect(appliedConfigFilepath(pf.PCIAddress)).ToNot(BeAnExistingFile())
+			Expect(appliedStateFilepath(pf.PCIAddress)).ToNot(BeAnExistingFile())
+		})
+		var _ = It("will remove the config not active after the VFs or the driver changed ", func() {
+			Expect(saveAppliedBBDevConfig(configurator("ACC100"), pf)).To(Succeed())
+			changed := acc
+			changed.VFs = nil
+			Expect(getAppliedBBDevConfig("ACC100", changed)).To(BeNil())
+			Expect(appliedConfigFilepath(pf.PCIAddress)).ToNot(BeAnExistingFile())
+
+			Expect(saveAppliedBBDevConfig(configurator("ACC100"), pf)).To(Succeed())
+			changed = acc
+			changed.Driver = "vfio-pci"
+			Expect(getAppliedBBDevConfig("ACC100", changed)).To(BeNil())
+			Expect(appliedConfigFilepath(pf.PCIAddress)).ToNot(BeAnExistingFile())
+		})
+		var _ = It("will remove the config saved without the state of the PF ", func() {
+			Expect(configurator("ACC100").Render(sampleBBDevConfig4, appliedConfigFilepath(pf.PCIAddress))).
+				To(Succeed())
+			Expect(getAppliedBBDevConfig("ACC100", acc)).To(BeNil())
+			Expect(appliedConfigFilepath(pf.PCIAddress)).ToNot(BeAnExistingFile())
+		})
+	})
+	var _ = Context("runPFConfig", func() {
+		var _ = It("will reject the device not supported by pf_bb_config ", func() {
+			err := runPFConfig(log, "ACC300", "config.cfg", "0000:14:00.1")
+			Expect(err).To(MatchError("incorrect deviceName for pf config: ACC300"))
+		})
 	})
 })
@@ -33,3 +33,12 @@ func execCmd(args []string, log logr.Logger) (string, error) {
 	log.V(4).Info("commands output", "output", output)
 	return output, nil
 }
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -6,10 +6,13 @@ package daemon
 import (
 	"context"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
 	dh "github.com/open-ness/openshift-operator/common/pkg/drainhelper"
+	"github.com/open-ness/openshift-operator/common/pkg/events"
+	"github.com/open-ness/openshift-operator/common/pkg/statuswriter"
 	"github.com/open-ness/openshift-operator/common/pkg/utils"
 	sriovv1 "github.com/open-ness/openshift-operator/sriov-fec/api/v1"
 	"github.com/pkg/errors"
@@ -38,6 +41,13 @@ const (
 	ConfigurationFailed       ConfigurationConditionReason = "Failed"
 	ConfigurationNotRequested ConfigurationConditionReason = "NotRequested"
 	ConfigurationSucceeded    ConfigurationConditionReason = "Succeeded"
+	ConfigurationWaiting      ConfigurationConditionReason = "WaitingForMaintenanceSlot"
+
+	// Reasons of the events recorded on the SriovFecNodeConfig and the Node, in addition to the condition reasons
+	EventKernelParamsAdded = "KernelParamsAdded"
+	EventVFsCreated        = "VFsCreated"
+	EventQueuesConfigured  = "QueuesConfigured"
+	EventPFDeconfigured    = "PFDeconfigured"
 )
 
 func (r *NodeConfigReconciler) updateCondition(nc *sriovv1.SriovFecNodeConfig, status metav1.ConditionStatus,
@@ -53,8 +63,36 @@ func (r *NodeConfigReconciler) updateCondition(nc *sriovv1.SriovFecNodeConfig, s
 	if err := r.updateStatus(nc, []metav1.Condition{c}); err != nil {
 		log.Error(err, "failed to update SriovFecNodeConfig condition")
 	}
+
+	switch reason {
+	case ConfigurationNotRequested:
+	case ConfigurationSucceeded:
+		r.recorder.Eventf(nc, corev1.EventTypeNormal, "Configuration"+string(reason), "%s", msg)
+	default:
+		r.recorder.Eventf(nc, corev1.EventTypeWarning, "Configuration"+string(reason), "%s", msg)
+	}
+}
+
+// updateProgressCondition updates the configuration condition without marking the generation as observed,
+// so the spec is handled again if the daemon is restarted before the configuration finishes
+func (r *NodeConfigReconciler) updateProgressCondition(nc *sriovv1.SriovFecNodeConfig,
+	reason ConfigurationConditionReason, msg string) error {
+	return r.updateStatus(nc, []metav1.Condition{
+		statuswriter.ProgressCondition(nc.Status.Conditions, ConfigurationCondition, string(reason), msg)})
+}
+
+// updateDrainSimulation stores the result of the drain simulation in the status
+func (r *NodeConfigReconciler) updateDrainSimulation(nc *sriovv1.SriovFecNodeConfig, sim *dh.DrainSimulation) {
+	simulation := dh.NewDrainSimulationStatus(sim)
+	err := statuswriter.NewStatusWriter(r.Client).Update(context.Background(), nc, func(obj client.Object) {
+		obj.(*sriovv1.SriovFecNodeConfig).Status.DrainSimulation = simulation
+	})
+	if err != nil {
+		r.log.WithName("updateDrainSimulation").Error(err, "failed to update SriovFecNodeConfig drain simulation")
+	}
 }
 
+// updateStatus sets the current inventory and the given conditions. Other existing conditions are preserved.
 func (r *NodeConfigReconciler) updateStatus(nc *sriovv1.SriovFecNodeConfig, c []metav1.Condition) error {
 	log := r.log.WithName("updateStatus")
 
@@ -63,14 +101,13 @@ func (r *NodeConfigReconciler) updateStatus(nc *sriovv1.SriovFecNodeConfig, c []
 		log.Error(err, "failed to obtain sriov inventory for the node")
 		return err
 	}
-	nodeStatus := sriovv1.SriovFecNodeConfigStatus{Inventory: *inv}
 
-	for _, condition := range c {
-		meta.SetStatusCondition(&nodeStatus.Conditions, condition)
-	}
-
-	nc.Status = nodeStatus
-	if err := r.Status().Update(context.Background(), nc); err != nil {
+	err = statuswriter.NewStatusWriter(r.Client).Update(context.Background(), nc, func(obj client.Object) {
+		nodeConfig := obj.(*sriovv1.SriovFecNodeConfig)
+		nodeConfig.Status.Inventory = *inv
+		statuswriter.SetConditions(&nodeConfig.Status.Conditions, c...)
+	})
+	if err != nil {
 		log.Error(err, "failed to update SriovFecNode status")
 		return err
 	}
@@ -85,6 +122,7 @@ type NodeConfigReconciler struct {
 	namespace        string
 	drainHelper      *dh.DrainHelper
 	nodeConfigurator *NodeConfigurator
+	recorder         *events.NodeRecorder
 }
 
 var (
@@ -120,6 +158,11 @@ func NewNodeConfigReconciler(c client.Client, clientSet *clientset.Clientset, lo
 }
 
 func (r *NodeConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.recorder = events.NewNodeRecorder(mgr.GetEventRecorderFor("sriov-fec-daemon"), r.nodeName)
+	if r.drainHelper != nil {
+		r.drainHelper.SetEventRecorder(r.recorder)
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&sriovv1.SriovFecNodeConfig{}).
 		WithEventFilter(predicate.Funcs{
@@ -167,7 +210,17 @@ func (r *NodeConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	}
 	skipStatusUpdate := false
 
-	if len(nodeConfig.Spec.PhysicalFunctions) == 0 {
+	// the PFs configured by the daemon are deconfigured once removed from the spec
+	owned, err := loadOwnedPFs()
+	if err != nil {
+		log.Error(err, "failed to load owned PFs")
+		r.updateCondition(nodeConfig, metav1.ConditionFalse, ConfigurationFailed, err.Error())
+		return reconcile.Result{}, err
+	}
+	removedPFs := getRemovedPFs(nodeConfig.Spec, owned)
+	configRequested := len(nodeConfig.Spec.PhysicalFunctions) != 0
+
+	if !configRequested && len(removedPFs) == 0 {
 		log.V(4).Info("Nothing to do")
 		r.updateCondition(nodeConfig, metav1.ConditionFalse, ConfigurationNotRequested, "Inventory up to date")
 		return reconcile.Result{}, nil
@@ -180,25 +233,93 @@ func (r *NodeConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return reconcile.Result{}, err
 	}
 
+	drainPolicy, err := dh.NewDrainPolicy(nodeConfig.Spec.DrainPolicy)
+	if err != nil {
+		log.Error(err, "drain policy error")
+		r.updateCondition(nodeConfig, metav1.ConditionFalse, ConfigurationFailed, err.Error())
+		return reconcile.Result{}, nil
+	}
+
 	currentCondition := meta.FindStatusCondition(nodeConfig.Status.Conditions, ConfigurationCondition)
-	if currentCondition != nil {
-		if reflect.DeepEqual(*inv, nodeConfig.Status.Inventory) && currentCondition.ObservedGeneration == nodeConfig.GetGeneration() {
-			return reconcile.Result{RequeueAfter: resyncPeriod}, nil
+	if currentCondition != nil && reflect.DeepEqual(*inv, nodeConfig.Status.Inventory) &&
+		currentCondition.ObservedGeneration == nodeConfig.GetGeneration() {
+		return reconcile.Result{RequeueAfter: resyncPeriod}, nil
+	}
+
+	// the node is not drained if all the PFs are configured already
+	changedPFs, err := getChangedPFs(nodeConfig.Spec, inv)
+	if err != nil {
+		log.Error(err, "failed to compare the PFs with the inventory")
+		r.updateCondition(nodeConfig, metav1.ConditionFalse, ConfigurationFailed, err.Error())
+		return reconcile.Result{}, err
+	}
+	missingParams, err := r.isAnyKernelParamsMissing(nodeConfig)
+	if err != nil {
+		log.Error(err, "failed to check for missing params")
+		r.updateCondition(nodeConfig, metav1.ConditionFalse, ConfigurationFailed, err.Error())
+		return reconcile.Result{}, err
+	}
+	if len(changedPFs) == 0 && len(removedPFs) == 0 && !missingParams {
+		log.V(2).Info("configuration already applied - drain skipped")
+		// the resources of the VFs are changed without draining the node, as the VFs are not recreated
+		devicePluginConfigChanged, err := r.updateDevicePluginConfig(nodeConfig)
+		if err == nil && devicePluginConfigChanged {
+			err = r.restartDevicePlugin()
+		}
+		if err != nil {
+			log.Error(err, "failed to update the device plugin config")
+			r.updateCondition(nodeConfig, metav1.ConditionFalse, ConfigurationFailed, err.Error())
+			return reconcile.Result{}, err
 		}
+		if err := r.updateInventory(nodeConfig); err != nil {
+			log.Error(err, "error during updateInventory")
+			r.updateCondition(nodeConfig, metav1.ConditionFalse, ConfigurationFailed, err.Error())
+			return reconcile.Result{}, err
+		}
+		r.updateCondition(nodeConfig, metav1.ConditionTrue, ConfigurationSucceeded, "Configured successfully")
+		return reconcile.Result{RequeueAfter: resyncPeriod}, nil
+	}
 
-		currentCondition.Status = metav1.ConditionFalse
-		currentCondition.Reason = string(ConfigurationInProgress)
-		currentCondition.Message = "Configuration started"
-		if err := r.updateStatus(nodeConfig, []metav1.Condition{*currentCondition}); err != nil {
+	if currentCondition != nil {
+		if err := r.updateProgressCondition(nodeConfig, ConfigurationInProgress, "Configuration started"); err != nil {
 			log.Error(err, "failed to update current SriovFecNode configuration condition")
 			return reconcile.Result{}, err
 		}
 	}
 
+	// waiting for the maintenance slot is abandoned if the spec is changed in the meantime
+	runCtx, cancelRun := context.WithCancel(context.Background())
+	defer cancelRun()
+	stopWatching := r.drainHelper.WatchWaiting(cancelRun, func(holders []string) {
+		msg := "Waiting for maintenance slot, held by: " + strings.Join(holders, ", ")
+		if err := r.updateProgressCondition(nodeConfig, ConfigurationWaiting, msg); err != nil {
+			log.Error(err, "failed to update SriovFecNodeConfig condition")
+		}
+		r.recorder.Eventf(nodeConfig, corev1.EventTypeNormal, "Configuration"+string(ConfigurationWaiting), "%s", msg)
+	}, func() bool {
+		return statuswriter.GenerationChanged(context.Background(), r.Client, nodeConfig)
+	})
+
+	// result of the dry-run drain is reported before the node is cordoned
+	if drainPolicy == nil {
+		drainPolicy = &dh.DrainPolicy{}
+	}
+	drainPolicy.OnSimulation = func(sim *dh.DrainSimulation) {
+		// the simulation is done once the slot is acquired, the watcher writing the same object is stopped first
+		stopWatching()
+		r.updateDrainSimulation(nodeConfig, sim)
+	}
+
 	var configurationErr, dhErr error
 
-	dhErr = r.drainHelper.Run(func(c context.Context) bool {
-		missingParams, err := r.nodeConfigurator.isAnyKernelParamsMissing()
+	dhErr = r.drainHelper.Run(runCtx, func(c context.Context) bool {
+		if stopWatching() {
+			if err := r.updateProgressCondition(nodeConfig, ConfigurationInProgress, "Configuration started"); err != nil {
+				log.Error(err, "failed to update SriovFecNodeConfig condition")
+			}
+		}
+
+		missingParams, err := r.isAnyKernelParamsMissing(nodeConfig)
 		if err != nil {
 			log.Error(err, "failed to check for missing params")
 			configurationErr = err
@@ -216,6 +337,8 @@ func (r *NodeConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			}
 
 			log.V(2).Info("added kernel params - rebooting")
+			r.recorder.Eventf(nodeConfig, corev1.EventTypeNormal, EventKernelParamsAdded,
+				"Kernel parameters added - rebooting node")
 			if err := r.nodeConfigurator.rebootNode(); err != nil {
 				log.Error(err, "failed to request a node reboot")
 				configurationErr = err
@@ -224,15 +347,32 @@ func (r *NodeConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			skipStatusUpdate = true
 			return false // leave node cordoned & keep the leadership
 		}
-		if err := r.nodeConfigurator.applyConfig(nodeConfig.Spec); err != nil {
+		configuredPFs, deconfiguredPFs, err := r.nodeConfigurator.applyConfig(nodeConfig.Spec)
+		r.recordConfigEvents(nodeConfig, configuredPFs, deconfiguredPFs)
+		if err != nil {
 			log.Error(err, "failed applying new PF/VF configuration")
 			configurationErr = err
 			return true
 		}
 
-		configurationErr = r.restartDevicePlugin()
+		devicePluginConfigChanged, err := r.updateDevicePluginConfig(nodeConfig)
+		if err != nil {
+			log.Error(err, "failed to update the device plugin config")
+			configurationErr = err
+			return true
+		}
+
+		if len(configuredPFs) != 0 || len(deconfiguredPFs) != 0 || devicePluginConfigChanged {
+			configurationErr = r.restartDevicePlugin()
+		}
 		return true
-	}, !nodeConfig.Spec.DrainSkip)
+	}, !nodeConfig.Spec.DrainSkip, drainPolicy)
+	stopWatching()
+
+	if errors.Is(dhErr, dh.ErrWaitCancelled) {
+		log.V(2).Info("SriovFecNodeConfig changed while waiting for maintenance slot - requeueing")
+		return reconcile.Result{Requeue: true}, nil
+	}
 
 	if skipStatusUpdate {
 		log.V(4).Info("status update skipped - CR will be handled again after node reboot")
@@ -257,12 +397,44 @@ func (r *NodeConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return reconcile.Result{}, err
 	}
 
+	if !configRequested {
+		r.updateCondition(nodeConfig, metav1.ConditionFalse, ConfigurationNotRequested, "PFs deconfigured")
+		log.V(2).Info("Reconciled - PFs deconfigured")
+		return reconcile.Result{}, nil
+	}
+
 	r.updateCondition(nodeConfig, metav1.ConditionTrue, ConfigurationSucceeded, "Configured successfully")
 	log.V(2).Info("Reconciled")
 
 	return reconcile.Result{RequeueAfter: resyncPeriod}, nil
 }
 
+// isAnyKernelParamsMissing checks the kernel params needed by the configured PFs, none are needed if only
+// the removed PFs are deconfigured
+func (r *NodeConfigReconciler) isAnyKernelParamsMissing(nc *sriovv1.SriovFecNodeConfig) (bool, error) {
+	if len(nc.Spec.PhysicalFunctions) == 0 {
+		return false, nil
+	}
+	return r.nodeConfigurator.isAnyKernelParamsMissing()
+}
+
+// recordConfigEvents records the events of the configured and deconfigured PFs
+func (r *NodeConfigReconciler) recordConfigEvents(nc *sriovv1.SriovFecNodeConfig, pfs []sriovv1.PhysicalFunctionConfig,
+	deconfigured []string) {
+	for _, pci := range deconfigured {
+		r.recorder.Eventf(nc, corev1.EventTypeNormal, EventPFDeconfigured, "Removed VFs of PF %s and restored its driver",
+			pci)
+	}
+	for _, pf := range pfs {
+		r.recorder.Eventf(nc, corev1.EventTypeNormal, EventVFsCreated, "Created %d VFs (%s) on PF %s (%s)",
+			pf.VFAmount, pf.VFDriver, pf.PCIAddress, pf.PFDriver)
+		if hasBBDevConfig(pf.BBDevConfig) {
+			r.recorder.Eventf(nc, corev1.EventTypeNormal, EventQueuesConfigured, "Queues of PF %s configured",
+				pf.PCIAddress)
+		}
+	}
+}
+
 func (r *NodeConfigReconciler) restartDevicePlugin() error {
 	pods := &corev1.PodList{}
 	err := r.Client.List(context.TODO(), pods,
@@ -352,10 +524,17 @@ func (r *NodeConfigReconciler) CreateEmptyNodeConfigIfNeeded(c client.Client) er
 		return createErr
 	}
 
-	updateErr := c.Status().Update(context.Background(), nodeConfig)
+	// the conditions are set by the Reconcile, only the inventory is filled in (if available)
+	inv, err := getSriovInventory(log)
+	if err != nil {
+		log.Error(err, "failed to obtain sriov inventory for the node")
+		return nil
+	}
+	updateErr := statuswriter.NewStatusWriter(c).Update(context.Background(), nodeConfig, func(obj client.Object) {
+		obj.(*sriovv1.SriovFecNodeConfig).Status.Inventory = *inv
+	})
 	if updateErr != nil {
 		log.Error(updateErr, "failed to update status")
 	}
 	return updateErr
-
 }
@@ -40,6 +40,7 @@ var _ = Describe("SriovDaemonTest", func() {
 
 		//configure node configurator
 		workdir = testTmpFolder
+		statedir = testTmpFolder
 		sysBusPciDevices = testTmpFolder
 		sysBusPciDrivers = testTmpFolder
 		Expect(createFiles(filepath.Join(sysBusPciDevices, pciAddress), "driver_override", vfNumFile)).To(Succeed())
@@ -96,6 +97,7 @@ var _ = Describe("SriovDaemonTest", func() {
 			nodeConfigs := &sriov.SriovFecNodeConfigList{}
 			Expect(k8sClient.List(context.TODO(), nodeConfigs)).To(Succeed())
 			Expect(nodeConfigs.Items).To(HaveLen(1))
+			Expect(nodeConfigs.Items[0].Status.Inventory).To(Equal(data.NodeInventory))
 		})
 
 		var _ = It("will ignore cr with wrong node name", func() {
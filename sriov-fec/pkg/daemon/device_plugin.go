@@ -0,0 +1,219 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	sriovv1 "github.com/open-ness/openshift-operator/sriov-fec/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// devicePluginConfigMap is the default config of the device plugin, deployed by the operator
+	devicePluginConfigMap = "sriovdp-config"
+	devicePluginConfigKey = "config.json"
+	// devicePluginNodeConfigMap holds the configs of the nodes with VFs advertised by their own resources,
+	// the device plugin of the node uses the key <node name>.json instead of the default config if present
+	devicePluginNodeConfigMap = "sriovdp-node-config"
+)
+
+// devicePluginConfig is the config of the SR-IOV Network Device Plugin
+type devicePluginConfig struct {
+	ResourceList []devicePluginResource `json:"resourceList"`
+}
+
+type devicePluginResource struct {
+	ResourcePrefix string                `json:"resourcePrefix,omitempty"`
+	ResourceName   string                `json:"resourceName"`
+	DeviceType     string                `json:"deviceType,omitempty"`
+	Selectors      devicePluginSelectors `json:"selectors"`
+}
+
+type devicePluginSelectors struct {
+	Vendors      []string `json:"vendors,omitempty"`
+	Devices      []string `json:"devices,omitempty"`
+	Drivers      []string `json:"drivers,omitempty"`
+	PCIAddresses []string `json:"pciAddresses,omitempty"`
+}
+
+// matches returns true if the VF of the accelerator is selected, the selectors which are not set match any VF
+func (s *devicePluginSelectors) matches(acc sriovv1.SriovAccelerator, vf sriovv1.VF) bool {
+	return (len(s.Vendors) == 0 || containsString(s.Vendors, acc.VendorID)) &&
+		(len(s.Devices) == 0 || containsString(s.Devices, vf.DeviceID)) &&
+		(len(s.Drivers) == 0 || containsString(s.Drivers, vf.Driver)) &&
+		(len(s.PCIAddresses) == 0 || containsString(s.PCIAddresses, vf.PCIAddress))
+}
+
+// getVFResourceNames returns the resource names of the VFs overridden by the per-VF configs, by PCI address
+func getVFResourceNames(nodeConfig sriovv1.SriovFecNodeConfigSpec, inv *sriovv1.NodeInventory) map[string]string {
+	resourceNames := map[string]string{}
+	for _, pf := range nodeConfig.PhysicalFunctions {
+		acc, exists := getMatchingExistingAccelerator(inv, pf.PCIAddress)
+		if !exists {
+			continue
+		}
+		vfs := sortedVFs(acc.VFs)
+		for _, vf := range pf.VFs {
+			if vf.ResourceName != "" && vf.Index < len(vfs) {
+				resourceNames[vfs[vf.Index].PCIAddress] = vf.ResourceName
+			}
+		}
+	}
+	return resourceNames
+}
+
+// renderDevicePluginConfig returns the config of the device plugin for the node, in which the VFs with
+// overridden resource names are moved from the default resources to their own ones. Returns an empty
+// string if no resource name is overridden, so the default config is used.
+func renderDevicePluginConfig(defaultConfig string, resourceNames map[string]string,
+	inv *sriovv1.NodeInventory) (string, error) {
+	if len(resourceNames) == 0 {
+		return "", nil
+	}
+
+	cfg := devicePluginConfig{}
+	if err := json.Unmarshal([]byte(defaultConfig), &cfg); err != nil {
+		return "", fmt.Errorf("failed to parse the default device plugin config: %w", err)
+	}
+
+	// the default resources select only the VFs of the node which are not overridden, a resource
+	// without such VFs is dropped (the device plugin treats the empty list as any address)
+	nodeCfg := devicePluginConfig{ResourceList: []devicePluginResource{}}
+	defaultNames := map[string]bool{}
+	for _, r := range cfg.ResourceList {
+		defaultNames[r.ResourceName] = true
+		addresses := []string{}
+		for _, acc := range inv.SriovAccelerators {
+			for _, vf := range acc.VFs {
+				if _, overridden := resourceNames[vf.PCIAddress]; !overridden && r.Selectors.matches(acc, vf) {
+					addresses = append(addresses, vf.PCIAddress)
+				}
+			}
+		}
+		if len(addresses) == 0 {
+			continue
+		}
+		sort.Strings(addresses)
+		r.Selectors.PCIAddresses = addresses
+		nodeCfg.ResourceList = append(nodeCfg.ResourceList, r)
+	}
+
+	addressesByName := map[string][]string{}
+	for pci, name := range resourceNames {
+		if defaultNames[name] {
+			return "", fmt.Errorf("resource name %s of VF %s is used by the default device plugin config", name, pci)
+		}
+		addressesByName[name] = append(addressesByName[name], pci)
+	}
+	names := []string{}
+	for name := range addressesByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		addresses := addressesByName[name]
+		sort.Strings(addresses)
+		nodeCfg.ResourceList = append(nodeCfg.ResourceList, devicePluginResource{
+			ResourceName: name,
+			DeviceType:   "accelerator",
+			Selectors:    devicePluginSelectors{PCIAddresses: addresses},
+		})
+	}
+
+	data, err := json.MarshalIndent(nodeCfg, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// updateDevicePluginConfig stores the device plugin config of the node rendered from the per-VF resource
+// names, or removes it if none is set. Returns true if the config of the node was changed.
+func (r *NodeConfigReconciler) updateDevicePluginConfig(nc *sriovv1.SriovFecNodeConfig) (bool, error) {
+	log := r.log.WithName("updateDevicePluginConfig")
+
+	inv, err := getSriovInventory(log)
+	if err != nil {
+		log.Error(err, "failed to obtain sriov inventory")
+		return false, err
+	}
+
+	nodeCfg := ""
+	defaultCM := &corev1.ConfigMap{}
+	if resourceNames := getVFResourceNames(nc.Spec, inv); len(resourceNames) != 0 {
+		err := r.Client.Get(context.TODO(), types.NamespacedName{Namespace: r.namespace, Name: devicePluginConfigMap},
+			defaultCM)
+		if err != nil {
+			log.Error(err, "failed to get the default device plugin config")
+			return false, err
+		}
+		nodeCfg, err = renderDevicePluginConfig(defaultCM.Data[devicePluginConfigKey], resourceNames, inv)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	key := r.nodeName + ".json"
+	changed := false
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		changed = false
+		cm := &corev1.ConfigMap{}
+		err := r.Client.Get(context.TODO(), types.NamespacedName{Namespace: r.namespace, Name: devicePluginNodeConfigMap}, cm)
+		if k8serrors.IsNotFound(err) {
+			if nodeCfg == "" {
+				return nil
+			}
+			// the configs of the nodes are removed with the default config
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      devicePluginNodeConfigMap,
+					Namespace: r.namespace,
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: "v1",
+						Kind:       "ConfigMap",
+						Name:       defaultCM.Name,
+						UID:        defaultCM.UID,
+					}},
+				},
+				Data: map[string]string{key: nodeCfg},
+			}
+			changed = true
+			return r.Client.Create(context.TODO(), cm)
+		} else if err != nil {
+			return err
+		}
+
+		current, exists := cm.Data[key]
+		if (nodeCfg == "" && !exists) || (nodeCfg != "" && current == nodeCfg) {
+			return nil
+		}
+		if nodeCfg == "" {
+			delete(cm.Data, key)
+		} else {
+			if cm.Data == nil {
+				cm.Data = map[string]string{}
+			}
+			cm.Data[key] = nodeCfg
+		}
+		changed = true
+		return r.Client.Update(context.TODO(), cm)
+	})
+	if err != nil {
+		log.Error(err, "failed to update the device plugin config of the node")
+		return false, err
+	}
+
+	if changed {
+		log.V(2).Info("device plugin config of the node updated", "perVFResources", nodeCfg != "")
+	}
+	return changed, nil
+}
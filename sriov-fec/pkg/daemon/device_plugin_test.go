@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package daemon
+
+import (
+	"encoding/json"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	sriovv1 "github.com/open-ness/openshift-operator/sriov-fec/api/v1"
+)
+
+var _ = Describe("DevicePlugin", func() {
+	const defaultConfig = `{
+    "resourceList": [
+        {
+            "resourceName": "intel_fec_5g",
+            "deviceType": "accelerator",
+            "selectors": {"vendors": ["8086"], "devices": ["0d90"], "drivers": ["pci-pf-stub", "vfio-pci"]}
+        },
+        {
+            "resourceName": "intel_fec_acc100",
+            "deviceType": "accelerator",
+            "selectors": {"vendors": ["8086"], "devices": ["0d5d"], "drivers": ["pci-pf-stub", "vfio-pci"]}
+        }
+    ]
+}`
+	inventory := &sriovv1.NodeInventory{SriovAccelerators: []sriovv1.SriovAccelerator{
+		{
+			VendorID:   "8086",
+			DeviceID:   "0d8f",
+			PCIAddress: "0000:1b:00.0",
+			VFs: []sriovv1.VF{
+				{PCIAddress: "0000:1c:00.1", Driver: "vfio-pci", DeviceID: "0d90"},
+				{PCIAddress: "0000:1c:00.0", Driver: "vfio-pci", DeviceID: "0d90"},
+				{PCIAddress: "0000:1c:00.2", Driver: "igb_uio", DeviceID: "0d90"},
+			},
+		},
+	}}
+	spec := func(vfs ...sriovv1.VFConfig) sriovv1.SriovFecNodeConfigSpec {
+		return sriovv1.SriovFecNodeConfigSpec{PhysicalFunctions: []sriovv1.PhysicalFunctionConfig{
+			{PCIAddress: "0000:1b:00.0", VFDriver: "vfio-pci", VFAmount: 3, VFs: vfs},
+		}}
+	}
+
+	var _ = Context("getVFResourceNames", func() {
+		var _ = It("will return the resource names by the PCI addresses of the VFs", func() {
+			Expect(getVFResourceNames(spec(
+				sriovv1.VFConfig{Index: 1, ResourceName: "intel_fec_du"},
+				sriovv1.VFConfig{Index: 2, Driver: "igb_uio"},
+			), inventory)).To(Equal(map[string]string{"0000:1c:00.1": "intel_fec_du"}))
+		})
+	})
+
+	var _ = Context("renderDevicePluginConfig", func() {
+		var _ = It("will return empty config if no resource name is overridden", func() {
+			Expect(renderDevicePluginConfig(defaultConfig, map[string]string{}, inventory)).To(BeEmpty())
+		})
+		var _ = It("will move the VFs to their own resources", func() {
+			rendered, err := renderDevicePluginConfig(defaultConfig, map[string]string{
+				"0000:1c:00.1": "intel_fec_du",
+				"0000:1c:00.2": "intel_fec_tools",
+			}, inventory)
+			Expect(err).ToNot(HaveOccurred())
+
+			cfg := devicePluginConfig{}
+			Expect(json.Unmarshal([]byte(rendered), &cfg)).To(Succeed())
+			Expect(cfg.ResourceList).To(Equal([]devicePluginResource{
+				{
+					ResourceName: "intel_fec_5g",
+					DeviceType:   "accelerator",
+					Selectors: devicePluginSelectors{
+						Vendors:      []string{"8086"},
+						Devices:      []string{"0d90"},
+						Drivers:      []string{"pci-pf-stub", "vfio-pci"},
+						PCIAddresses: []string{"0000:1c:00.0"},
+					},
+				},
+				{
+					ResourceName: "intel_fec_du",
+					DeviceType:   "accelerator",
+					Selectors:    devicePluginSelectors{PCIAddresses: []string{"0000:1c:00.1"}},
+				},
+				{
+					ResourceName: "intel_fec_tools",
+					DeviceType:   "accelerator",
+					Selectors:    devicePluginSelectors{PCIAddresses: []string{"0000:1c:00.2"}},
+				},
+			}))
+		})
+		var _ = It("will keep the VFs bound to the per-VF drivers in the default resources", func() {
+			config := strings.ReplaceAll(defaultConfig, `"drivers": ["pci-pf-stub", "vfio-pci"]`,
+				`"drivers": ["pci-pf-stub", "vfio-pci", "igb_uio", "uio_pci_generic"]`)
+			rendered, err := renderDevicePluginConfig(config, map[string]string{"0000:1c:00.1": "intel_fec_du"},
+				inventory)
+			Expect(err).ToNot(HaveOccurred())
+
+			cfg := devicePluginConfig{}
+			Expect(json.Unmarshal([]byte(rendered), &cfg)).To(Succeed())
+			Expect(cfg.ResourceList[0].ResourceName).To(Equal("intel_fec_5g"))
+			Expect(cfg.ResourceList[0].Selectors.PCIAddresses).To(Equal([]string{"0000:1c:00.0", "0000:1c:00.2"}))
+		})
+		var _ = It("will return error if the resource name is used by the default config", func() {
+			_, err := renderDevicePluginConfig(defaultConfig, map[string]string{"0000:1c:00.1": "intel_fec_acc100"},
+				inventory)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
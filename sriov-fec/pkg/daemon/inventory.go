@@ -90,6 +90,12 @@ func GetSriovInventory(log logr.Logger) (*sriovv1.NodeInventory, error) {
 			acc.VFs = append(acc.VFs, vfInfo)
 		}
 
+		queueConfig, err := getAppliedBBDevConfig(supportedAccelerators.Devices[device.Product.ID], acc)
+		if err != nil {
+			log.Error(err, "failed to read applied queue config of device", "pci", device.Address)
+		}
+		acc.QueueConfig = queueConfig
+
 		accelerators.SriovAccelerators = append(accelerators.SriovAccelerators, acc)
 	}
 
@@ -0,0 +1,32 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package daemon
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	pfBBConfigSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "sriov_fec_pf_bb_config_duration_seconds",
+		Help:    "Duration of pf_bb_config configuring the queues of the PF",
+		Buckets: prometheus.ExponentialBuckets(0.25, 2, 10),
+	}, []string{"pci"})
+	pfConfigurationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sriov_fec_pf_configurations_total",
+		Help: "Number of the configurations of the PFs by device and result",
+	}, []string{"pci", "result"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(pfBBConfigSeconds, pfConfigurationsTotal)
+}
+
+func metricsResult(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
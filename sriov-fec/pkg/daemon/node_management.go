@@ -8,7 +8,9 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -25,6 +27,7 @@ var (
 	runExecCmd       = execCmd
 	getVFconfigured  = utils.GetVFconfigured
 	getVFList        = utils.GetVFList
+	pciStubRegex     = regexp.MustCompile("pci[-_]pf[-_]stub")
 )
 
 type NodeConfigurator struct {
@@ -195,87 +198,213 @@ func (n *NodeConfigurator) changeAmountOfVFs(pfPCIAddress string, vfsAmount int)
 	return nil
 }
 
-func (n *NodeConfigurator) applyConfig(nodeConfig sriovv1.SriovFecNodeConfigSpec) error {
+// driverMatches returns true if the device is bound to the requested driver, the names of the pci-pf-stub
+// module and driver differ
+func driverMatches(current, requested string) bool {
+	return current == requested || (pciStubRegex.MatchString(current) && pciStubRegex.MatchString(requested))
+}
+
+// sortedVFs returns the VFs of the PF in the order of their PCI addresses, which is the order of their indexes
+func sortedVFs(vfs []sriovv1.VF) []sriovv1.VF {
+	sorted := append([]sriovv1.VF{}, vfs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].PCIAddress < sorted[j].PCIAddress })
+	return sorted
+}
+
+// pfUpToDate returns true if the PF from the inventory is configured according to the PF config: the PF and
+// its VFs are bound to the requested drivers, the amount of VFs and the applied queue config match
+func pfUpToDate(pf sriovv1.PhysicalFunctionConfig, acc sriovv1.SriovAccelerator) bool {
+	if !driverMatches(acc.Driver, pf.PFDriver) || len(acc.VFs) != pf.VFAmount {
+		return false
+	}
+	for i, vf := range sortedVFs(acc.VFs) {
+		if !driverMatches(vf.Driver, pf.GetVFDriver(i)) {
+			return false
+		}
+	}
+
+	if !hasBBDevConfig(pf.BBDevConfig) {
+		return true
+	}
+	if acc.QueueConfig == nil {
+		return false
+	}
+	requested := pf.GetBBDevConfig()
+	// network type of N3000 is given by the FPGA image, not by the applied config
+	if requested.N3000 != nil && acc.QueueConfig.N3000 != nil {
+		requested.N3000.NetworkType = acc.QueueConfig.N3000.NetworkType
+	}
+	return reflect.DeepEqual(requested, *acc.QueueConfig)
+}
+
+// getChangedPFs returns the PF configs, which are not applied to the PFs from the inventory
+func getChangedPFs(nodeConfig sriovv1.SriovFecNodeConfigSpec, inv *sriovv1.NodeInventory) ([]sriovv1.PhysicalFunctionConfig, error) {
+	changed := []sriovv1.PhysicalFunctionConfig{}
+	for _, pf := range nodeConfig.PhysicalFunctions {
+		acc, exists := getMatchingExistingAccelerator(inv, pf.PCIAddress)
+		if !exists {
+			return nil, fmt.Errorf("unknown (%s not present in inventory) PciAddress", pf.PCIAddress)
+		}
+		if !pfUpToDate(pf, acc) {
+			changed = append(changed, pf)
+		}
+	}
+	return changed, nil
+}
+
+// applyConfig deconfigures the PFs removed from the spec and configures the PFs whose config is not applied
+// yet, the other PFs (and the workloads using their VFs) are not touched. Returns the configured PFs and
+// the PCI addresses of the deconfigured PFs.
+func (n *NodeConfigurator) applyConfig(nodeConfig sriovv1.SriovFecNodeConfigSpec) ([]sriovv1.PhysicalFunctionConfig, []string, error) {
 	log := n.Log.WithName("applyConfig")
 
 	inv, err := getSriovInventory(log)
 	if err != nil {
 		log.Error(err, "failed to obtain current sriov inventory")
-		return err
+		return nil, nil, err
 	}
 
 	log.V(4).Info("current node status", "inventory", inv)
-	pciStubRegex := regexp.MustCompile("pci[-_]pf[-_]stub")
-	for _, pf := range nodeConfig.PhysicalFunctions {
-		acc, exists := getMatchingExistingAccelerator(inv, pf.PCIAddress)
-		if !exists {
-			log.Info("received unknown (not present in inventory) PciAddress", "pci", pf.PCIAddress)
-			return fmt.Errorf("unknown (%s not present in inventory) PciAddress", pf.PCIAddress)
+	changed, err := getChangedPFs(nodeConfig, inv)
+	if err != nil {
+		log.Info("received unknown (not present in inventory) PciAddress", "reason", err.Error())
+		return nil, nil, err
+	}
+
+	owned, err := loadOwnedPFs()
+	if err != nil {
+		log.Error(err, "failed to load owned PFs")
+		return nil, nil, err
+	}
+
+	deconfigured := []string{}
+	for _, pci := range getRemovedPFs(nodeConfig, owned) {
+		if err := n.deconfigurePF(pci, owned[pci], inv); err != nil {
+			return nil, deconfigured, err
 		}
+		delete(owned, pci)
+		if err := saveOwnedPFs(owned); err != nil {
+			return nil, deconfigured, err
+		}
+		deconfigured = append(deconfigured, pci)
+	}
 
-		log.V(4).Info("configuring PF", "requestedConfig", pf)
+	// the original drivers are stored before the PFs are touched, so they can be restored once the PFs are
+	// removed from the spec. The unchanged PFs are owned too, so they are deconfigured once removed.
+	if seedOwnedPFs(nodeConfig, owned, inv) {
+		if err := saveOwnedPFs(owned); err != nil {
+			return nil, deconfigured, err
+		}
+	}
 
-		if err := n.loadModule(pf.PFDriver); err != nil {
-			log.Info("failed to load module for PF driver", "driver", pf.PFDriver)
-			return err
+	configured := []sriovv1.PhysicalFunctionConfig{}
+	for _, pf := range changed {
+		acc, _ := getMatchingExistingAccelerator(inv, pf.PCIAddress)
+		err := n.configurePF(pf, acc)
+		pfConfigurationsTotal.WithLabelValues(pf.PCIAddress, metricsResult(err)).Inc()
+		if err != nil {
+			return configured, deconfigured, err
 		}
+		configured = append(configured, pf)
+	}
+	log.V(2).Info("PFs configured", "configured", len(configured), "deconfigured", len(deconfigured),
+		"unchanged", len(nodeConfig.PhysicalFunctions)-len(configured))
+
+	return configured, deconfigured, nil
+}
+
+// configurePF binds the PF, creates its VFs and configures its queues
+func (n *NodeConfigurator) configurePF(pf sriovv1.PhysicalFunctionConfig, acc sriovv1.SriovAccelerator) error {
+	log := n.Log.WithName("configurePF").WithValues("pci", pf.PCIAddress)
+
+	log.V(4).Info("configuring PF", "requestedConfig", pf)
 
-		if err := n.loadModule(pf.VFDriver); err != nil {
-			log.Info("failed to load module for VF driver", "driver", pf.VFDriver)
+	if err := n.loadModule(pf.PFDriver); err != nil {
+		log.Info("failed to load module for PF driver", "driver", pf.PFDriver)
+		return err
+	}
+
+	vfDrivers := []string{pf.VFDriver}
+	for _, vf := range pf.VFs {
+		if vf.Driver != "" && !containsString(vfDrivers, vf.Driver) {
+			vfDrivers = append(vfDrivers, vf.Driver)
+		}
+	}
+	for _, driver := range vfDrivers {
+		if err := n.loadModule(driver); err != nil {
+			log.Info("failed to load module for VF driver", "driver", driver)
 			return err
 		}
+	}
 
-		if len(acc.VFs) > 0 {
-			if err := n.changeAmountOfVFs(pf.PCIAddress, 0); err != nil {
-				return err
-			}
-		}
+	// the queues are reset with the VFs, so the config applied before is not active anymore
+	if err := removeAppliedBBDevConfig(pf.PCIAddress); err != nil {
+		log.Error(err, "failed to remove applied bbdev config file")
+	}
 
-		if err := n.bindDeviceToDriver(pf.PCIAddress, pf.PFDriver); err != nil {
+	if len(acc.VFs) > 0 {
+		if err := n.changeAmountOfVFs(pf.PCIAddress, 0); err != nil {
 			return err
 		}
+	}
+
+	if err := n.bindDeviceToDriver(pf.PCIAddress, pf.PFDriver); err != nil {
+		return err
+	}
 
-		if err := n.changeAmountOfVFs(pf.PCIAddress, pf.VFAmount); err != nil {
+	if err := n.changeAmountOfVFs(pf.PCIAddress, pf.VFAmount); err != nil {
+		return err
+	}
+
+	createdVfs, err := getVFList(pf.PCIAddress)
+	if err != nil {
+		log.Error(err, "failed to get list of newly created VFs")
+		return err
+	}
+
+	// the VFs are indexed in the order of their PCI addresses
+	sort.Strings(createdVfs)
+	for i, vf := range createdVfs {
+		if err := n.bindDeviceToDriver(vf, pf.GetVFDriver(i)); err != nil {
 			return err
 		}
+	}
 
-		createdVfs, err := getVFList(pf.PCIAddress)
+	if hasBBDevConfig(pf.BBDevConfig) {
+		deviceName := supportedAccelerators.Devices[acc.DeviceID]
+		configurator, err := getBBDevConfigurator(deviceName)
 		if err != nil {
-			log.Error(err, "failed to get list of newly created VFs")
+			log.Error(err, "device's queues can't be configured", "deviceID", acc.DeviceID)
 			return err
 		}
 
-		for _, vf := range createdVfs {
-			if err := n.bindDeviceToDriver(vf, pf.VFDriver); err != nil {
-				return err
+		bbdevConfigFilepath := filepath.Join(workdir, fmt.Sprintf("%s.ini", pf.PCIAddress))
+		if err := generateBBDevConfigFile(configurator, pf.GetBBDevConfig(), bbdevConfigFilepath); err != nil {
+			log.Error(err, "failed to create bbdev config file", "pci", pf.PCIAddress)
+			return err
+		}
+		defer func() {
+			if err := os.Remove(bbdevConfigFilepath); err != nil && !os.IsNotExist(err) {
+				log.Error(err, "failed to remove old bbdev config file", "path", bbdevConfigFilepath)
 			}
+		}()
+
+		if err := runPFConfig(log, deviceName, bbdevConfigFilepath, pf.PCIAddress); err != nil {
+			log.Error(err, "failed to configure device's queues", "pci", pf.PCIAddress)
+			return err
 		}
 
-		if pf.BBDevConfig.N3000 != nil || pf.BBDevConfig.ACC100 != nil {
-			bbdevConfigFilepath := filepath.Join(workdir, fmt.Sprintf("%s.ini", pf.PCIAddress))
-			if err := generateBBDevConfigFile(pf.BBDevConfig, bbdevConfigFilepath); err != nil {
-				log.Error(err, "failed to create bbdev config file", "pci", pf.PCIAddress)
-				return err
-			}
-			defer func() {
-				if err := os.Remove(bbdevConfigFilepath); err != nil {
-					log.Error(err, "failed to remove old bbdev config file", "path", bbdevConfigFilepath)
-				}
-			}()
-
-			deviceName := supportedAccelerators.Devices[acc.DeviceID]
-			if err := runPFConfig(log, deviceName, bbdevConfigFilepath, pf.PCIAddress); err != nil {
-				log.Error(err, "failed to configure device's queues", "pci", pf.PCIAddress)
-				return err
-			}
-		} else {
-			log.V(4).Info("N3000 and ACC100 BBDevConfig are nil - queues will not be (re)configured")
+		// the applied config is reported in the inventory, the statedir is on another volume than the workdir
+		if err := saveAppliedBBDevConfig(configurator, pf); err != nil {
+			log.Error(err, "failed to save applied bbdev config file", "pci", pf.PCIAddress)
 		}
+	} else {
+		log.V(4).Info("BBDevConfig is empty - queues will not be (re)configured")
+	}
 
-		if pciStubRegex.MatchString(pf.PFDriver) {
-			if err := n.enableMasterBus(pf.PCIAddress); err != nil {
-				return err
-			}
+	if pciStubRegex.MatchString(pf.PFDriver) {
+		if err := n.enableMasterBus(pf.PCIAddress); err != nil {
+			return err
 		}
 	}
 
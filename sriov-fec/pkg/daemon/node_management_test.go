@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package daemon
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	sriovv1 "github.com/open-ness/openshift-operator/sriov-fec/api/v1"
+)
+
+var _ = Describe("NodeManagement", func() {
+	acc100Config := func() sriovv1.BBDevConfig {
+		return sriovv1.BBDevConfig{ACC100: &sriovv1.ACC100BBDevConfig{
+			NumVfBundles: 2,
+			MaxQueueSize: 1024,
+			Uplink5G:     sriovv1.QueueGroupConfig{NumQueueGroups: 4, NumAqsPerGroups: 16, AqDepthLog2: 4},
+			Downlink5G:   sriovv1.QueueGroupConfig{NumQueueGroups: 4, NumAqsPerGroups: 16, AqDepthLog2: 4},
+		}}
+	}
+	pfConfig := func() sriovv1.PhysicalFunctionConfig {
+		return sriovv1.PhysicalFunctionConfig{
+			PCIAddress:  "0000:14:00.1",
+			PFDriver:    "pci-pf-stub",
+			VFDriver:    "vfio-pci",
+			VFAmount:    2,
+			BBDevConfig: acc100Config(),
+		}
+	}
+	accelerator := func() sriovv1.SriovAccelerator {
+		queueConfig := acc100Config()
+		return sriovv1.SriovAccelerator{
+			DeviceID:   "0d5c",
+			PCIAddress: "0000:14:00.1",
+			Driver:     "pci_pf_stub",
+			VFs: []sriovv1.VF{
+				{PCIAddress: "0000:15:00.0", Driver: "vfio-pci"},
+				{PCIAddress: "0000:15:00.1", Driver: "vfio-pci"},
+			},
+			QueueConfig: &queueConfig,
+		}
+	}
+
+	var _ = Context("pfUpToDate", func() {
+		var _ = It("will return true for the applied config", func() {
+			Expect(pfUpToDate(pfConfig(), accelerator())).To(BeTrue())
+		})
+		var _ = It("will return false for different PF driver", func() {
+			pf := pfConfig()
+			pf.PFDriver = "igb_uio"
+			Expect(pfUpToDate(pf, accelerator())).To(BeFalse())
+		})
+		var _ = It("will return false for different amount of VFs", func() {
+			pf := pfConfig()
+			pf.VFAmount = 1
+			Expect(pfUpToDate(pf, accelerator())).To(BeFalse())
+		})
+		var _ = It("will return false for different VF driver", func() {
+			acc := accelerator()
+			acc.VFs[1].Driver = "igb_uio"
+			Expect(pfUpToDate(pfConfig(), acc)).To(BeFalse())
+		})
+		var _ = It("will compare the drivers of the VFs with the per-VF drivers", func() {
+			pf := pfConfig()
+			pf.VFs = []sriovv1.VFConfig{{Index: 0, Driver: "igb_uio"}}
+			acc := accelerator()
+			// VFs are indexed in the order of their PCI addresses
+			acc.VFs = []sriovv1.VF{
+				{PCIAddress: "0000:15:00.1", Driver: "vfio-pci"},
+				{PCIAddress: "0000:15:00.0", Driver: "igb_uio"},
+			}
+			Expect(pfUpToDate(pf, acc)).To(BeTrue())
+
+			pf.VFs[0].Index = 1
+			Expect(pfUpToDate(pf, acc)).To(BeFalse())
+		})
+		var _ = It("will return false for different queue config", func() {
+			pf := pfConfig()
+			pf.BBDevConfig.ACC100.NumVfBundles = 1
+			Expect(pfUpToDate(pf, accelerator())).To(BeFalse())
+		})
+		var _ = It("will return false if the queues were not configured", func() {
+			acc := accelerator()
+			acc.QueueConfig = nil
+			Expect(pfUpToDate(pfConfig(), acc)).To(BeFalse())
+		})
+		var _ = It("will compare the queues of N3000 with the per-VF queues applied", func() {
+			n3000 := &sriovv1.N3000BBDevConfig{
+				NetworkType: "FPGA_5GNR",
+				Uplink:      sriovv1.UplinkDownlink{Queues: sriovv1.UplinkDownlinkQueues{VF0: 16, VF1: 16}},
+				Downlink:    sriovv1.UplinkDownlink{Queues: sriovv1.UplinkDownlinkQueues{VF0: 16, VF1: 16}},
+			}
+			pf := pfConfig()
+			pf.BBDevConfig = sriovv1.BBDevConfig{N3000: n3000}
+			pf.VFs = []sriovv1.VFConfig{{Index: 1, Queues: &sriovv1.VFQueues{Uplink: 8, Downlink: 4}}}
+			acc := accelerator()
+			acc.QueueConfig = &sriovv1.BBDevConfig{N3000: n3000.DeepCopy()}
+			Expect(pfUpToDate(pf, acc)).To(BeFalse())
+
+			acc.QueueConfig.N3000.Uplink.Queues.VF1 = 8
+			acc.QueueConfig.N3000.Downlink.Queues.VF1 = 4
+			Expect(pfUpToDate(pf, acc)).To(BeTrue())
+		})
+		var _ = It("will ignore the network type of N3000", func() {
+			n3000 := &sriovv1.N3000BBDevConfig{
+				NetworkType: "FPGA_5GNR",
+				FLRTimeOut:  610,
+				Uplink:      sriovv1.UplinkDownlink{Bandwidth: 8, LoadBalance: 128},
+				Downlink:    sriovv1.UplinkDownlink{Bandwidth: 8, LoadBalance: 128},
+			}
+			pf := pfConfig()
+			pf.BBDevConfig = sriovv1.BBDevConfig{N3000: n3000}
+			acc := accelerator()
+			acc.QueueConfig = &sriovv1.BBDevConfig{N3000: n3000.DeepCopy()}
+			acc.QueueConfig.N3000.NetworkType = "FPGA_LTE"
+			Expect(pfUpToDate(pf, acc)).To(BeTrue())
+		})
+	})
+
+	var _ = Context("getChangedPFs", func() {
+		var _ = It("will return only the PFs with changed config", func() {
+			changed := pfConfig()
+			changed.PCIAddress = "0000:16:00.1"
+			changed.VFAmount = 1
+			other := accelerator()
+			other.PCIAddress = "0000:16:00.1"
+
+			pfs, err := getChangedPFs(sriovv1.SriovFecNodeConfigSpec{
+				PhysicalFunctions: []sriovv1.PhysicalFunctionConfig{pfConfig(), changed},
+			}, &sriovv1.NodeInventory{SriovAccelerators: []sriovv1.SriovAccelerator{accelerator(), other}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pfs).To(Equal([]sriovv1.PhysicalFunctionConfig{changed}))
+		})
+		var _ = It("will return error for the PF not present in inventory", func() {
+			_, err := getChangedPFs(sriovv1.SriovFecNodeConfigSpec{
+				PhysicalFunctions: []sriovv1.PhysicalFunctionConfig{pfConfig()},
+			}, &sriovv1.NodeInventory{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("0000:14:00.1 not present in inventory"))
+		})
+	})
+})
@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	sriovv1 "github.com/open-ness/openshift-operator/sriov-fec/api/v1"
+)
+
+var (
+	// statedir is persisted on the host, so the PFs configured by the daemon are known after its restart
+	statedir     = "/sriov_state"
+	ownedPFsFile = "owned_pfs.json"
+)
+
+// ownedPF is a PF configured by the daemon
+type ownedPF struct {
+	// OriginalDriver is the driver the PF was bound to before the daemon configured it, empty if it was not bound
+	OriginalDriver string `json:"originalDriver"`
+}
+
+// loadOwnedPFs returns the PFs configured by the daemon, by PCI address
+func loadOwnedPFs() (map[string]ownedPF, error) {
+	owned := map[string]ownedPF{}
+	data, err := ioutil.ReadFile(filepath.Join(statedir, ownedPFsFile))
+	if os.IsNotExist(err) {
+		return owned, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read owned PFs: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &owned); err != nil {
+		return nil, fmt.Errorf("failed to parse owned PFs: %w", err)
+	}
+	return owned, nil
+}
+
+// saveOwnedPFs stores the PFs configured by the daemon
+func saveOwnedPFs(owned map[string]ownedPF) error {
+	data, err := json.Marshal(owned)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(statedir, ownedPFsFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to write owned PFs: %w", err)
+	}
+	return nil
+}
+
+// getRemovedPFs returns the PCI addresses of the owned PFs, which are not in the spec anymore
+func getRemovedPFs(nodeConfig sriovv1.SriovFecNodeConfigSpec, owned map[string]ownedPF) []string {
+	removed := []string{}
+	for pci := range owned {
+		inSpec := false
+		for _, pf := range nodeConfig.PhysicalFunctions {
+			if pf.PCIAddress == pci {
+				inSpec = true
+				break
+			}
+		}
+		if !inSpec {
+			removed = append(removed, pci)
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+// seedOwnedPFs adds the PFs of the spec present in the inventory to the owned PFs, the PFs owned already keep
+// their original driver. The original driver of a PF bound to the requested PF driver already is not known
+// (the PF was configured before the tracking was introduced or outside of the operator), so such PF is unbound
+// once removed from the spec. Returns true if any PF was added.
+func seedOwnedPFs(nodeConfig sriovv1.SriovFecNodeConfigSpec, owned map[string]ownedPF,
+	inv *sriovv1.NodeInventory) bool {
+	added := false
+	for _, pf := range nodeConfig.PhysicalFunctions {
+		if _, ok := owned[pf.PCIAddress]; ok {
+			continue
+		}
+		acc, exists := getMatchingExistingAccelerator(inv, pf.PCIAddress)
+		if !exists {
+			continue
+		}
+
+		originalDriver := acc.Driver
+		if driverMatches(acc.Driver, pf.PFDriver) {
+			originalDriver = ""
+		}
+		owned[pf.PCIAddress] = ownedPF{OriginalDriver: originalDriver}
+		added = true
+	}
+	return added
+}
+
+// deconfigurePF removes the VFs of the PF configured by the daemon and restores its original driver
+func (n *NodeConfigurator) deconfigurePF(pciAddress string, owned ownedPF, inv *sriovv1.NodeInventory) error {
+	log := n.Log.WithName("deconfigurePF").WithValues("pci", pciAddress)
+
+	acc, exists := getMatchingExistingAccelerator(inv, pciAddress)
+	if !exists {
+		log.Info("PF not present in inventory - nothing to deconfigure")
+		return nil
+	}
+
+	if err := removeAppliedBBDevConfig(pciAddress); err != nil {
+		log.Error(err, "failed to remove applied bbdev config file")
+	}
+
+	if len(acc.VFs) > 0 {
+		if err := n.changeAmountOfVFs(pciAddress, 0); err != nil {
+			return err
+		}
+	}
+
+	if owned.OriginalDriver == "" {
+		if acc.Driver != "" {
+			if err := n.unbindDeviceFromDriver(pciAddress); err != nil {
+				return err
+			}
+		}
+	} else if !driverMatches(acc.Driver, owned.OriginalDriver) {
+		if err := n.bindDeviceToDriver(pciAddress, owned.OriginalDriver); err != nil {
+			return err
+		}
+	}
+
+	// the driver is not forced for the PF anymore
+	driverOverridePath := filepath.Join(sysBusPciDevices, pciAddress, "driver_override")
+	if err := ioutil.WriteFile(driverOverridePath, []byte("\n"), os.ModeAppend); err != nil {
+		log.Error(err, "failed to clear driver override", "path", driverOverridePath)
+		return err
+	}
+
+	log.V(2).Info("PF deconfigured", "driver", owned.OriginalDriver)
+	return nil
+}
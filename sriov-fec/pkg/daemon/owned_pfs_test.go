@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2021 Intel Corporation
+
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	sriovv1 "github.com/open-ness/openshift-operator/sriov-fec/api/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var _ = Describe("OwnedPFs", func() {
+	const pfPCIAddress = "0000:14:00.1"
+	var tmpDir string
+	configurator := &NodeConfigurator{Log: ctrl.Log.WithName("OwnedPFs-test")}
+
+	var _ = BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir(testTmpFolder, "owned_pfs")
+		Expect(err).ToNot(HaveOccurred())
+		statedir = tmpDir
+		workdir = tmpDir
+		sysBusPciDevices = filepath.Join(tmpDir, "devices")
+		sysBusPciDrivers = filepath.Join(tmpDir, "drivers")
+	})
+
+	var _ = AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	var _ = Context("loadOwnedPFs", func() {
+		var _ = It("will return no PFs if none were saved", func() {
+			owned, err := loadOwnedPFs()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(owned).To(BeEmpty())
+		})
+		var _ = It("will return the saved PFs", func() {
+			saved := map[string]ownedPF{pfPCIAddress: {OriginalDriver: "igb_uio"}, "0000:16:00.1": {}}
+			Expect(saveOwnedPFs(saved)).To(Succeed())
+			Expect(loadOwnedPFs()).To(Equal(saved))
+		})
+		var _ = It("will return error for invalid file", func() {
+			Expect(ioutil.WriteFile(filepath.Join(statedir, ownedPFsFile), []byte("{"), 0644)).To(Succeed())
+			_, err := loadOwnedPFs()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	var _ = Context("getRemovedPFs", func() {
+		var _ = It("will return the owned PFs not present in the spec", func() {
+			owned := map[string]ownedPF{"0000:18:00.1": {}, pfPCIAddress: {}, "0000:16:00.1": {}}
+			spec := sriovv1.SriovFecNodeConfigSpec{
+				PhysicalFunctions: []sriovv1.PhysicalFunctionConfig{{PCIAddress: pfPCIAddress}},
+			}
+			Expect(getRemovedPFs(spec, owned)).To(Equal([]string{"0000:16:00.1", "0000:18:00.1"}))
+		})
+		var _ = It("will return all owned PFs for empty spec", func() {
+			owned := map[string]ownedPF{pfPCIAddress: {}}
+			Expect(getRemovedPFs(sriovv1.SriovFecNodeConfigSpec{}, owned)).To(Equal([]string{pfPCIAddress}))
+		})
+	})
+
+	var _ = Context("seedOwnedPFs", func() {
+		inventory := &sriovv1.NodeInventory{SriovAccelerators: []sriovv1.SriovAccelerator{
+			{PCIAddress: pfPCIAddress, Driver: "igb_uio"},
+			{PCIAddress: "0000:16:00.1", Driver: "pci-pf-stub"},
+			{PCIAddress: "0000:18:00.1", Driver: "pci-pf-stub"},
+		}}
+
+		var _ = It("will own the PFs of the spec and keep the known original drivers", func() {
+			owned := map[string]ownedPF{"0000:18:00.1": {OriginalDriver: "igb_uio"}}
+			spec := sriovv1.SriovFecNodeConfigSpec{PhysicalFunctions: []sriovv1.PhysicalFunctionConfig{
+				{PCIAddress: pfPCIAddress, PFDriver: "pci-pf-stub"},
+				{PCIAddress: "0000:16:00.1", PFDriver: "pci-pf-stub"},
+				{PCIAddress: "0000:18:00.1", PFDriver: "pci-pf-stub"},
+				{PCIAddress: "0000:1a:00.1", PFDriver: "pci-pf-stub"},
+			}}
+			Expect(seedOwnedPFs(spec, owned, inventory)).To(BeTrue())
+			Expect(owned).To(Equal(map[string]ownedPF{
+				pfPCIAddress:   {OriginalDriver: "igb_uio"},
+				"0000:16:00.1": {},
+				"0000:18:00.1": {OriginalDriver: "igb_uio"},
+			}))
+			Expect(seedOwnedPFs(spec, owned, inventory)).To(BeFalse())
+		})
+	})
+
+	var _ = Context("deconfigurePF", func() {
+		pfDevice := func() string { return filepath.Join(sysBusPciDevices, pfPCIAddress) }
+		inventory := func(driver string, vfs int) *sriovv1.NodeInventory {
+			acc := sriovv1.SriovAccelerator{PCIAddress: pfPCIAddress, Driver: driver, VFs: make([]sriovv1.VF, vfs)}
+			return &sriovv1.NodeInventory{SriovAccelerators: []sriovv1.SriovAccelerator{acc}}
+		}
+
+		var _ = BeforeEach(func() {
+			Expect(createFiles(pfDevice(), "driver_override", vfNumFile)).To(Succeed())
+			Expect(createFiles(filepath.Join(sysBusPciDrivers, "pci-pf-stub"), "unbind")).To(Succeed())
+			Expect(createFiles(filepath.Join(sysBusPciDrivers, "igb_uio"), "bind")).To(Succeed())
+			Expect(os.Symlink(filepath.Join(sysBusPciDrivers, "pci-pf-stub"), filepath.Join(pfDevice(), "driver"))).
+				To(Succeed())
+			getVFconfigured = func(string) int {
+				return 2
+			}
+		})
+
+		var _ = It("will remove the VFs and restore the original driver", func() {
+			Expect(configurator.deconfigurePF(pfPCIAddress, ownedPF{OriginalDriver: "igb_uio"},
+				inventory("pci-pf-stub", 2))).To(Succeed())
+
+			Expect(ioutil.ReadFile(filepath.Join(pfDevice(), vfNumFile))).To(BeEquivalentTo("0"))
+			Expect(ioutil.ReadFile(filepath.Join(sysBusPciDrivers, "pci-pf-stub", "unbind"))).
+				To(BeEquivalentTo(pfPCIAddress))
+			Expect(ioutil.ReadFile(filepath.Join(sysBusPciDrivers, "igb_uio", "bind"))).To(BeEquivalentTo(pfPCIAddress))
+			Expect(ioutil.ReadFile(filepath.Join(pfDevice(), "driver_override"))).To(BeEquivalentTo("\n"))
+		})
+		var _ = It("will unbind the PF which was not bound", func() {
+			Expect(configurator.deconfigurePF(pfPCIAddress, ownedPF{}, inventory("pci-pf-stub", 0))).To(Succeed())
+
+			Expect(ioutil.ReadFile(filepath.Join(pfDevice(), vfNumFile))).To(BeEmpty())
+			Expect(ioutil.ReadFile(filepath.Join(sysBusPciDrivers, "pci-pf-stub", "unbind"))).
+				To(BeEquivalentTo(pfPCIAddress))
+			Expect(ioutil.ReadFile(filepath.Join(sysBusPciDrivers, "igb_uio", "bind"))).To(BeEmpty())
+		})
+		var _ = It("will skip the PF not present in inventory", func() {
+			Expect(configurator.deconfigurePF(pfPCIAddress, ownedPF{OriginalDriver: "igb_uio"},
+				&sriovv1.NodeInventory{})).To(Succeed())
+			Expect(ioutil.ReadFile(filepath.Join(pfDevice(), "driver_override"))).To(BeEmpty())
+		})
+	})
+})